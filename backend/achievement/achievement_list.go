@@ -1,165 +1,169 @@
 package achievement
 
 import (
+	"backend/models"
+	"errors"
 	"fmt"
-	"strconv"
-)
-
-// AchievementCheckFunc 定义成就检查函数的类型
-type AchievementCheckFunc func() bool
-
-// GetAchievementChecks 返回物品ID到成就检查函数的映射
-func (c *Check) GetAchievementChecks() map[uint]AchievementCheckFunc {
-	// 创建物品名称到ID的映射，方便查找
-	nameToID := make(map[string]uint)
-	for _, item := range c.Items {
-		nameToID[item.Name] = item.ID
-	}
-
-	// 创建ID到检查函数的映射
-	checks := make(map[uint]AchievementCheckFunc)
-
-	// 为每个成就物品添加对应的检查函数
-
-	// 饭团猫的礼物
-	if id, ok := nameToID["饭团猫的礼物"]; ok {
-		checks[id] = func() bool {
-			return c.CheckFirstFoodRecord()
-		}
-	}
-
-	// 第七天的饭勺
-	if id, ok := nameToID["第七天的饭勺"]; ok {
-		checks[id] = func() bool {
-			return c.CheckSevenDaysCheckin()
-		}
-	}
 
-	// 超人披风
-	if id, ok := nameToID["超人披风"]; ok {
-		checks[id] = func() bool {
-			return c.CheckTwentyOneDaysCheckin()
-		}
-	}
-
-	// 不倒翁餐盘
-	if id, ok := nameToID["不倒翁餐盘"]; ok {
-		checks[id] = func() bool {
-			return c.CheckReboundCheckin()
-		}
-	}
-
-	// 太阳花
-	if id, ok := nameToID["太阳花"]; ok {
-		checks[id] = func() bool {
-			return c.CheckEarlyBreakfast()
-		}
-	}
-
-	// 奇怪的鸡蛋
-	if id, ok := nameToID["奇怪的鸡蛋"]; ok {
-		checks[id] = func() bool {
-			return c.CheckHighProtein()
-		}
-	}
+	"gorm.io/gorm"
+)
 
-	// 大小适中的碗
-	if id, ok := nameToID["大小适中的碗"]; ok {
-		checks[id] = func() bool {
-			return c.CheckBalancedMeal()
-		}
-	}
+// DefaultRule 内置成就规则
+type DefaultRule struct {
+	ItemKey     string // 成就物品的标识，物品由 seed 包创建
+	Description string
+	Definition  models.RuleDefinition
+}
 
-	// 几个糖分子
-	if id, ok := nameToID["几个糖分子"]; ok {
-		checks[id] = func() bool {
-			return c.CheckLowCarb()
-		}
-	}
+// 三大营养素比例均衡：蛋白质10-35%，脂肪20-35%，碳水45-65%
+var balancedMacroFilters = []models.RuleFilter{
+	{Field: "protein_ratio", Op: "between", Value: []interface{}{0.1, 0.35}},
+	{Field: "fat_ratio", Op: "between", Value: []interface{}{0.2, 0.35}},
+	{Field: "carb_ratio", Op: "between", Value: []interface{}{0.45, 0.65}},
+}
 
-	// 美食记录本
-	if id, ok := nameToID["美食记录本"]; ok {
-		checks[id] = func() bool {
-			return c.CheckConsistentRecording()
-		}
-	}
+// DefaultRules 内置成就规则，对应 doc/成就.md
+var DefaultRules = []DefaultRule{
+	{
+		ItemKey:     "rice-ball-cat-gift",
+		Description: "记录第一次食物",
+		Definition:  models.RuleDefinition{Type: models.RuleTypeCount, Source: models.RuleSourceFoodRecord, Target: 1},
+	},
+	{
+		ItemKey:     "seventh-day-spoon",
+		Description: "累计打卡7天",
+		Definition:  models.RuleDefinition{Type: models.RuleTypeDistinctDays, Source: models.RuleSourceCheckIn, Target: 7},
+	},
+	{
+		ItemKey:     "superhero-cape",
+		Description: "累计打卡21天",
+		Definition:  models.RuleDefinition{Type: models.RuleTypeDistinctDays, Source: models.RuleSourceCheckIn, Target: 21},
+	},
+	{
+		ItemKey:     "tumbler-plate",
+		Description: "在打卡中断后重新打卡3天",
+		Definition:  models.RuleDefinition{Type: models.RuleTypeReboundStreak, Source: models.RuleSourceCheckIn, Target: 3},
+	},
+	{
+		ItemKey:     "sunflower",
+		Description: "记录一次早餐，且时间在上午9点前",
+		Definition: models.RuleDefinition{
+			Type:   models.RuleTypeCount,
+			Source: models.RuleSourceFoodRecord,
+			Filters: []models.RuleFilter{
+				{Field: "meal_type", Op: "==", Value: "早餐"},
+				{Field: "hour", Op: "<", Value: 9.0},
+			},
+			Target: 1,
+		},
+	},
+	{
+		ItemKey:     "strange-egg",
+		Description: "记录一次高蛋白（超过20克）的餐食",
+		Definition: models.RuleDefinition{
+			Type:    models.RuleTypeCount,
+			Source:  models.RuleSourceFoodRecord,
+			Filters: []models.RuleFilter{{Field: "protein", Op: ">=", Value: 20.0}},
+			Target:  1,
+		},
+	},
+	{
+		ItemKey:     "just-right-bowl",
+		Description: "记录一次热量适中（400-600卡）且三大营养素比例均衡的正餐",
+		Definition: models.RuleDefinition{
+			Type:   models.RuleTypeCount,
+			Source: models.RuleSourceFoodRecord,
+			Filters: append([]models.RuleFilter{
+				{Field: "calories", Op: "between", Value: []interface{}{400.0, 600.0}},
+			}, balancedMacroFilters...),
+			Target: 1,
+		},
+	},
+	{
+		ItemKey:     "sugar-molecules",
+		Description: "记录一次低糖（碳水化合物少于30克）的餐食",
+		Definition: models.RuleDefinition{
+			Type:    models.RuleTypeCount,
+			Source:  models.RuleSourceFoodRecord,
+			Filters: []models.RuleFilter{{Field: "carbohydrates", Op: "<", Value: 30.0}},
+			Target:  1,
+		},
+	},
+	{
+		ItemKey:     "food-journal",
+		Description: "连续3天每天记录2餐以上",
+		Definition:  models.RuleDefinition{Type: models.RuleTypeStreak, Source: models.RuleSourceFoodRecord, MinPerDay: 2, Target: 3},
+	},
+	{
+		ItemKey:     "balanced-scale",
+		Description: "一周内有3次餐食的三大营养素比例符合推荐标准",
+		Definition: models.RuleDefinition{
+			Type:       models.RuleTypeCount,
+			Source:     models.RuleSourceFoodRecord,
+			Filters:    balancedMacroFilters,
+			WindowDays: 7,
+			Target:     3,
+		},
+	},
+	{
+		ItemKey:     "woodpecker-alarm-clock",
+		Description: "一周内记录至少4次早餐",
+		Definition: models.RuleDefinition{
+			Type:       models.RuleTypeCount,
+			Source:     models.RuleSourceFoodRecord,
+			Filters:    []models.RuleFilter{{Field: "meal_type", Op: "==", Value: "早餐"}},
+			WindowDays: 7,
+			Target:     4,
+		},
+	},
+	{
+		ItemKey:     "sleepy-night-light",
+		Description: "连续7天没有记录晚上9点后的进食",
+		Definition: models.RuleDefinition{
+			Type:    models.RuleTypeAbsenceStreak,
+			Source:  models.RuleSourceFoodRecord,
+			Filters: []models.RuleFilter{{Field: "hour", Op: ">=", Value: 21.0}},
+			Target:  7,
+		},
+	},
+	{
+		ItemKey:     "food-explorer-backpack",
+		Description: "记录20种从未记录过的新食物",
+		Definition:  models.RuleDefinition{Type: models.RuleTypeDistinctValues, Source: models.RuleSourceFoodRecord, Field: "food_name", Target: 20},
+	},
+}
 
-	// 正常的天秤
-	if id, ok := nameToID["正常的天秤"]; ok {
-		checks[id] = func() bool {
-			return c.CheckBalancedWeeklyMeals()
+// EnsureDefaultRules 为已存在的成就物品创建内置规则（仅在该物品还没有任何规则时创建）
+// 规则创建后通过物品ID关联，之后修改物品名称不会影响成就
+func EnsureDefaultRules() error {
+	for _, def := range DefaultRules {
+		item, err := models.GetItemByKey(def.ItemKey)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
 		}
-	}
-
-	// 啄木鸟闹钟
-	if id, ok := nameToID["啄木鸟闹钟"]; ok {
-		checks[id] = func() bool {
-			return c.CheckWeeklyBreakfast()
+		if err != nil {
+			return fmt.Errorf("查找物品 %s 失败: %v", def.ItemKey, err)
 		}
-	}
 
-	// 安眠小夜灯
-	if id, ok := nameToID["安眠小夜灯"]; ok {
-		checks[id] = func() bool {
-			return c.CheckNoLateNightEating()
+		count, err := models.CountAchievementRulesByItem(item.ID)
+		if err != nil {
+			return fmt.Errorf("查询物品 %s 的成就规则失败: %v", item.Name, err)
 		}
-	}
-
-	// 美食探险背包
-	if id, ok := nameToID["美食探险背包"]; ok {
-		checks[id] = func() bool {
-			return c.CheckDiverseFoods()
+		if count > 0 {
+			continue
 		}
-	}
-
-	return checks
-}
-
-// CheckAchievements 检查用户的所有成就并返回已达成但未获得的成就ID列表
-func (c *Check) CheckAchievements() []uint {
-	achievementChecks := c.GetAchievementChecks()
 
-	// 将用户已有的物品ID存入map，方便查找
-	userItemIDs := make(map[uint]bool)
-	for _, userItem := range c.UserItems {
-		// 根据SQL查询，物品ID应该是"id"字段
-		if itemID, ok := userItem["id"].(uint); ok {
-			userItemIDs[itemID] = true
-		} else if itemIDFloat, ok := userItem["id"].(float64); ok {
-			// 处理JSON解析可能将uint转为float64的情况
-			userItemIDs[uint(itemIDFloat)] = true
-		} else if itemIDInt, ok := userItem["id"].(int); ok {
-			// 处理可能是int的情况
-			userItemIDs[uint(itemIDInt)] = true
-		} else if itemIDInt64, ok := userItem["id"].(int64); ok {
-			// 处理int64类型
-			userItemIDs[uint(itemIDInt64)] = true
-		} else if itemIDStr, ok := userItem["id"].(string); ok {
-			// 处理可能是字符串的情况
-			if id, err := strconv.ParseUint(itemIDStr, 10, 32); err == nil {
-				userItemIDs[uint(id)] = true
-			}
+		rule := &models.AchievementRule{
+			ItemID:      item.ID,
+			Name:        item.Name,
+			Description: def.Description,
+			Definition:  def.Definition,
+			Enabled:     true,
 		}
-	}
-
-	// 存储符合条件但用户尚未获得的成就ID，使用map确保唯一性
-	uniqueAchievedItemIDs := make(map[uint]bool)
-
-	// 遍历所有成就检查函数
-	for itemID, checkFunc := range achievementChecks {
-		// 如果用户还没有这个成就物品，且满足获取条件
-		if !userItemIDs[itemID] && checkFunc() {
-			// 使用map确保唯一性
-			uniqueAchievedItemIDs[itemID] = true
-			fmt.Printf("用户达成了成就，物品ID: %d\n", itemID)
+		if err := models.CreateAchievementRule(rule); err != nil {
+			return fmt.Errorf("创建成就规则 %s 失败: %v", item.Name, err)
 		}
+		fmt.Printf("已为物品 %s 创建内置成就规则\n", item.Name)
 	}
-
-	// 转换回切片
-	var achievedItemIDs []uint
-	for itemID := range uniqueAchievedItemIDs {
-		achievedItemIDs = append(achievedItemIDs, itemID)
-	}
-
-	return achievedItemIDs
+	return nil
 }
@@ -0,0 +1,259 @@
+package achievement
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 回溯任务每批处理的默认和最大用户数
+const (
+	DefaultBackfillBatchSize = 100
+	MaxBackfillBatchSize     = 1000
+)
+
+var (
+	ErrBackfillRunning   = errors.New("回溯任务正在执行")
+	ErrBackfillCompleted = errors.New("回溯任务已完成")
+	ErrBackfillCancelled = errors.New("回溯任务已中断")
+)
+
+// runningBackfills 当前进程中正在执行的回溯任务，避免同一任务被重复执行
+var runningBackfills sync.Map
+
+// BackfillProgressFunc 每处理完一批用户后的进度回调
+type BackfillProgressFunc func(job *models.BackfillJob)
+
+// NewBackfillJob 创建回溯任务，ruleIDs 为空时回溯所有启用的规则
+func NewBackfillJob(ruleIDs []uint, dryRun bool, batchSize int, createdBy *uint) (*models.BackfillJob, error) {
+	if len(ruleIDs) == 0 {
+		rules, err := models.GetEnabledAchievementRules()
+		if err != nil {
+			return nil, fmt.Errorf("获取成就规则失败: %v", err)
+		}
+		for _, rule := range rules {
+			ruleIDs = append(ruleIDs, rule.ID)
+		}
+	}
+	if len(ruleIDs) == 0 {
+		return nil, fmt.Errorf("没有需要回溯的规则")
+	}
+
+	rules, err := models.GetAchievementRulesByIDs(ruleIDs)
+	if err != nil {
+		return nil, fmt.Errorf("获取成就规则失败: %v", err)
+	}
+	if len(rules) != len(ruleIDs) {
+		return nil, fmt.Errorf("部分规则不存在")
+	}
+
+	if batchSize <= 0 {
+		batchSize = DefaultBackfillBatchSize
+	}
+	if batchSize > MaxBackfillBatchSize {
+		batchSize = MaxBackfillBatchSize
+	}
+
+	job := &models.BackfillJob{
+		RuleIDs:   ruleIDs,
+		DryRun:    dryRun,
+		BatchSize: batchSize,
+		Status:    models.BackfillStatusPending,
+		CreatedBy: createdBy,
+	}
+	if err := models.CreateBackfillJob(job); err != nil {
+		return nil, fmt.Errorf("创建回溯任务失败: %v", err)
+	}
+	return job, nil
+}
+
+// RunBackfill 执行回溯任务，从上次处理到的用户之后继续，直到完成或 ctx 被取消
+func RunBackfill(ctx context.Context, jobID uint, progress BackfillProgressFunc) (*models.BackfillJob, error) {
+	job, err := claimBackfill(jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer runningBackfills.Delete(jobID)
+
+	return job, runBackfill(ctx, job, progress)
+}
+
+// StartBackfill 在后台执行回溯任务
+func StartBackfill(jobID uint) error {
+	job, err := claimBackfill(jobID)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer runningBackfills.Delete(jobID)
+		if err := runBackfill(context.Background(), job, nil); err != nil {
+			fmt.Printf("回溯任务 %d 执行失败: %v\n", jobID, err)
+		}
+	}()
+	return nil
+}
+
+// claimBackfill 加载任务并标记为在当前进程中执行
+func claimBackfill(jobID uint) (*models.BackfillJob, error) {
+	job, err := models.GetBackfillJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == models.BackfillStatusCompleted {
+		return nil, ErrBackfillCompleted
+	}
+	if _, loaded := runningBackfills.LoadOrStore(jobID, true); loaded {
+		return nil, ErrBackfillRunning
+	}
+	return job, nil
+}
+
+func runBackfill(ctx context.Context, job *models.BackfillJob, progress BackfillProgressFunc) error {
+	rules, err := models.GetAchievementRulesByIDs(job.RuleIDs)
+	if err != nil {
+		return failBackfill(job, fmt.Errorf("获取成就规则失败: %v", err))
+	}
+
+	remaining, err := models.CountUsersAfter(job.LastUserID)
+	if err != nil {
+		return failBackfill(job, fmt.Errorf("统计用户数失败: %v", err))
+	}
+
+	now := time.Now()
+	job.Status = models.BackfillStatusRunning
+	job.Error = ""
+	job.TotalUsers = job.ProcessedUsers + remaining
+	if job.StartedAt == nil {
+		job.StartedAt = &now
+	}
+	if err := models.SaveBackfillJob(job); err != nil {
+		return err
+	}
+
+	for {
+		if ctx.Err() != nil {
+			return failBackfill(job, ErrBackfillCancelled)
+		}
+
+		userIDs, err := models.GetUserIDsAfter(job.LastUserID, job.BatchSize)
+		if err != nil {
+			return failBackfill(job, fmt.Errorf("获取用户失败: %v", err))
+		}
+		if len(userIDs) == 0 {
+			break
+		}
+
+		// 结果保存失败时恢复到本批次开始前的进度，避免跳过这些用户
+		lastUserID, processedUsers := job.LastUserID, job.ProcessedUsers
+		var matches []models.BackfillMatch
+		var batchErr error
+		for _, userID := range userIDs {
+			userMatches, err := backfillUser(userID, rules, job)
+			if err != nil {
+				batchErr = fmt.Errorf("处理用户 %d 失败: %v", userID, err)
+				break
+			}
+			matches = append(matches, userMatches...)
+			job.LastUserID = userID
+			job.ProcessedUsers++
+		}
+
+		// 先保存结果再保存进度，中断后从 LastUserID 继续时不会遗漏
+		if err := models.CreateBackfillMatches(matches); err != nil {
+			job.LastUserID, job.ProcessedUsers = lastUserID, processedUsers
+			return failBackfill(job, fmt.Errorf("保存回溯结果失败: %v", err))
+		}
+		job.Matched += int64(len(matches))
+		if batchErr != nil {
+			return failBackfill(job, batchErr)
+		}
+		if err := models.SaveBackfillJob(job); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(job)
+		}
+	}
+
+	finished := time.Now()
+	job.Status = models.BackfillStatusCompleted
+	job.FinishedAt = &finished
+	return models.SaveBackfillJob(job)
+}
+
+// failBackfill 记录任务失败原因，任务可以稍后从 LastUserID 继续执行
+func failBackfill(job *models.BackfillJob, err error) error {
+	job.Status = models.BackfillStatusFailed
+	job.Error = err.Error()
+	if saveErr := models.SaveBackfillJob(job); saveErr != nil {
+		fmt.Printf("保存回溯任务 %d 失败: %v\n", job.ID, saveErr)
+	}
+	return err
+}
+
+// backfillUser 在一个用户上重新计算规则，非试运行时保存进度并发放达成的成就
+func backfillUser(userID uint, rules []models.AchievementRule, job *models.BackfillJob) ([]models.BackfillMatch, error) {
+	c := newCheck(userID)
+	if err := c.GatherData(); err != nil {
+		return nil, err
+	}
+
+	progress, err := models.GetUserAchievementProgress(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取成就进度失败: %v", err)
+	}
+
+	var matches []models.BackfillMatch
+	var achieved []*models.AchievementRule
+	achievedItems := make(map[uint]bool)
+	for i := range rules {
+		rule := &rules[i]
+		if c.OwnedItemIDs[rule.ItemID] {
+			continue
+		}
+
+		p, err := c.updateProgress(rule, progress[rule.ID], nil, true)
+		if err != nil {
+			return nil, err
+		}
+		if !job.DryRun {
+			if err := models.SaveAchievementProgress(p); err != nil {
+				return nil, fmt.Errorf("保存成就进度失败: %v", err)
+			}
+		}
+		if !p.Achieved {
+			continue
+		}
+
+		matches = append(matches, models.BackfillMatch{
+			JobID:   job.ID,
+			UserID:  userID,
+			RuleID:  rule.ID,
+			ItemID:  rule.ItemID,
+			Current: p.Current,
+			Target:  p.Target,
+		})
+		if !achievedItems[rule.ItemID] {
+			achievedItems[rule.ItemID] = true
+			achieved = append(achieved, rule)
+		}
+	}
+
+	if job.DryRun || len(achieved) == 0 {
+		return matches, nil
+	}
+
+	unlocked, err := c.grantAchievements(achieved)
+	granted := make(map[uint]bool)
+	for _, status := range unlocked {
+		granted[status.RuleID] = true
+	}
+	for i := range matches {
+		matches[i].Granted = granted[matches[i].RuleID]
+	}
+	return matches, err
+}
@@ -3,85 +3,150 @@ package achievement
 import (
 	"backend/models"
 	"fmt"
-	"math"
+	"time"
 )
 
+// Check 一次成就检查所需的用户数据
 type Check struct {
-	ID          int
-	UserID      uint
-	UserItems   []map[string]interface{}
-	FoodRecords []models.FoodRecord
-	CheckIns    []models.CheckIn
-	Items       []models.Item
+	UserID       uint
+	Now          time.Time
+	Location     *time.Location
+	OwnedItemIDs map[uint]bool
+	Rules        []models.AchievementRule
+
+	// records 按数据来源缓存的记录，时间已转换到用户时区，按需从数据库加载
+	records map[string][]ruleRecord
+	// recordCounts 缓存的记录数，键为 "<来源>:<最大ID>"
+	recordCounts map[string]int64
 }
 
+// CheckAchievements 检查用户的所有成就，并发放新达成的成就物品
 func CheckAchievements(UserID uint) {
-	c := GenCheck(UserID)
-	achievedItemIDs := c.CheckAchievements()
-	_, err := c.GiveItemsToUser(achievedItemIDs)
+	c, err := GenCheck(UserID)
 	if err != nil {
+		fmt.Printf("获取用户 %d 的成就数据失败: %v\n", UserID, err)
+		return
+	}
+	if _, err := c.grantAchievements(c.CheckAchievements()); err != nil {
 		fmt.Printf("给用户 %d 添加成就物品失败: %v\n", UserID, err)
 	}
 }
 
-func GenCheck(UserID uint) *Check {
-	c := &Check{}
-	c.UserID = UserID
-	c.UserItems = make([]map[string]interface{}, 0)
-	c.FoodRecords = make([]models.FoodRecord, 0)
-	c.CheckIns = make([]models.CheckIn, 0)
-	c.Items = make([]models.Item, 0)
-	c.GatherData()
-	c.CheckAchievements()
-	return c
+// GenCheck 创建并加载用户的成就检查数据
+func GenCheck(UserID uint) (*Check, error) {
+	c := newCheck(UserID)
+	if err := c.GatherData(); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
-func (c *Check) GatherData() error {
-	// 获取用户的打卡记录
-	checkIns, err := models.GetUserCheckIns(c.UserID)
-	if err != nil {
-		return fmt.Errorf("获取用户打卡记录失败: %v", err)
+func newCheck(userID uint) *Check {
+	return &Check{
+		UserID:       userID,
+		Now:          time.Now(),
+		OwnedItemIDs: make(map[uint]bool),
+		records:      make(map[string][]ruleRecord),
+		recordCounts: make(map[string]int64),
 	}
-	c.CheckIns = checkIns
-	fmt.Printf("用户 %d 的打卡记录已获取，共 %d 条记录\n", c.UserID, len(checkIns))
+}
 
-	// 获取用户的饮食记录
-	foodRecords, err := models.GetAllUserFoodRecords(c.UserID)
+// GatherData 加载用户的时区、已有物品和所有启用的成就规则
+// 打卡、饮食等记录在规则计算时按来源延迟加载
+func (c *Check) GatherData() error {
+	profile, err := models.GetUserProfile(c.UserID)
 	if err != nil {
-		return fmt.Errorf("获取用户饮食记录失败: %v", err)
+		return fmt.Errorf("获取用户资料失败: %v", err)
 	}
-	c.FoodRecords = foodRecords
-	fmt.Printf("用户 %d 的饮食记录已获取，共 %d 条记录\n", c.UserID, len(foodRecords))
+	c.Location = profile.Location()
+	c.Now = c.Now.In(c.Location)
 
-	// 获取用户的物品
-	// 注意：这里假设有一个 GetUserItems 函数来获取用户的物品
-	userItems, err := models.GetUserItems(c.UserID)
+	// 获取用户已拥有的物品
+	itemIDs, err := models.GetUserItemIDs(c.UserID)
 	if err != nil {
 		return fmt.Errorf("获取用户物品失败: %v", err)
 	}
-	c.UserItems = userItems
-	fmt.Printf("用户 %d 的物品已获取，共 %d 个物品\n", c.UserID, len(userItems))
+	for _, itemID := range itemIDs {
+		c.OwnedItemIDs[itemID] = true
+	}
 
-	// 获取所有物品
-	items, total, err := models.GetAllItems(1, 100) // 假设总物品数不超过100
+	// 获取所有启用的成就规则
+	rules, err := models.GetEnabledAchievementRules()
 	if err != nil {
-		return fmt.Errorf("获取所有物品失败: %v", err)
+		return fmt.Errorf("获取成就规则失败: %v", err)
 	}
+	c.Rules = rules
 
-	// 如果物品数量超过了一页的限制，继续获取剩余物品
-	if total > 100 {
-		pageCount := int(math.Ceil(float64(total) / 100))
-		for page := 2; page <= pageCount; page++ {
-			moreItems, _, err := models.GetAllItems(page, 100)
-			if err != nil {
-				return fmt.Errorf("获取第 %d 页物品失败: %v", page, err)
-			}
-			items = append(items, moreItems...)
+	return nil
+}
+
+// sourceRecords 返回指定数据来源的统一记录视图
+func (c *Check) sourceRecords(source string) ([]ruleRecord, error) {
+	if records, ok := c.records[source]; ok {
+		return records, nil
+	}
+
+	var records []ruleRecord
+	switch source {
+	case models.RuleSourceFoodRecord:
+		foodRecords, err := models.GetAllUserFoodRecords(c.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("获取用户饮食记录失败: %v", err)
+		}
+		for i := range foodRecords {
+			records = append(records, c.foodRecord(&foodRecords[i]))
+		}
+	case models.RuleSourceCheckIn:
+		checkIns, err := models.GetUserCheckIns(c.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("获取用户打卡记录失败: %v", err)
+		}
+		for i := range checkIns {
+			records = append(records, c.checkInRecord(&checkIns[i]))
 		}
+	case models.RuleSourceHealthState:
+		states, err := models.GetAllUserHealthStates(c.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("获取用户健康状态记录失败: %v", err)
+		}
+		for i := range states {
+			records = append(records, c.healthRecord(&states[i]))
+		}
+	default:
+		return nil, fmt.Errorf("不支持的数据来源: %s", source)
 	}
 
-	c.Items = items
-	fmt.Printf("所有物品已获取，共 %d 个物品\n", len(items))
+	c.records[source] = records
+	return records, nil
+}
 
-	return nil
+func (c *Check) foodRecord(r *models.FoodRecord) ruleRecord {
+	return ruleRecord{ID: r.ID, Time: r.RecordTime.In(c.Location), Food: r}
+}
+
+func (c *Check) checkInRecord(r *models.CheckIn) ruleRecord {
+	return ruleRecord{ID: r.ID, Time: r.CheckInAt.In(c.Location), CheckIn: r}
+}
+
+func (c *Check) healthRecord(r *models.UserHealthState) ruleRecord {
+	return ruleRecord{ID: r.ID, Time: r.RecordTime.In(c.Location), Health: r}
+}
+
+// Evaluate 从历史数据计算规则在当前用户上的结果
+func (c *Check) Evaluate(def *models.RuleDefinition) (RuleResult, error) {
+	state, _, _, err := c.rebuild(def)
+	if err != nil {
+		return RuleResult{Target: def.Target}, err
+	}
+	return stateResult(&state, def, c.Now), nil
+}
+
+// rebuild 从历史数据重新计算规则状态，返回状态、最大记录ID和记录数
+func (c *Check) rebuild(def *models.RuleDefinition) (models.RuleState, uint, int64, error) {
+	records, err := c.sourceRecords(def.Source)
+	if err != nil {
+		return models.RuleState{}, 0, 0, err
+	}
+	state, lastID := replay(records, def, c.Now)
+	return state, lastID, int64(len(records)), nil
 }
@@ -1,14 +1,15 @@
 package achievement
 
 import (
+	"backend/coin"
 	"backend/models"
 	"fmt"
-	"time"
 )
 
-// GiveItemsToUser 给用户添加指定的成就物品
-func (c *Check) GiveItemsToUser(achievedItemIDs []uint) ([]models.UserItem, error) {
-	if len(achievedItemIDs) == 0 {
+// GiveItemsToUser 给用户发放达成规则对应的成就物品，返回本次实际新解锁的成就
+// 每条规则对每个用户只会发放一次，并发或重复调用不会重复发放
+func (c *Check) GiveItemsToUser(rules []*models.AchievementRule) ([]Status, error) {
+	if len(rules) == 0 {
 		fmt.Println("没有新成就物品需要添加")
 		return nil, nil
 	}
@@ -18,37 +19,32 @@ func (c *Check) GiveItemsToUser(achievedItemIDs []uint) ([]models.UserItem, erro
 		return nil, fmt.Errorf("未设置用户ID")
 	}
 
-	// 为用户添加新达成的成就物品
-	var newUserItems []models.UserItem
-
-	for _, itemID := range achievedItemIDs {
-		// 创建新的用户物品记录
-		userItem := models.UserItem{
-			UserID:       c.UserID,
-			ItemID:       itemID,
-			Quantity:     1,
-			ObtainedAt:   time.Now(),
-			ObtainedFrom: "achievement", // 标记来源为成就系统
-		}
-
-		// 保存到数据库
-		err := models.CreateUserItem(&userItem)
+	var unlocked []Status
+	for _, rule := range rules {
+		userItem, granted, err := models.GrantItem(models.GrantRequest{
+			UserID:    c.UserID,
+			ItemID:    rule.ItemID,
+			Quantity:  1,
+			Source:    "achievement", // 标记来源为成就系统
+			SourceRef: fmt.Sprintf("rule:%d", rule.ID),
+			Reason:    fmt.Sprintf("达成成就：%s", rule.Name),
+		})
 		if err != nil {
-			return newUserItems, fmt.Errorf("添加物品(ID: %d)失败: %v", itemID, err)
+			return unlocked, fmt.Errorf("添加物品(ID: %d)失败: %v", rule.ItemID, err)
 		}
 
-		// 找到对应的物品信息
-		var itemName string
-		for _, item := range c.Items {
-			if item.ID == itemID {
-				itemName = item.Name
-				break
-			}
+		c.OwnedItemIDs[rule.ItemID] = true
+		if !granted {
+			fmt.Printf("用户 %d 已通过规则 %d 获得过物品 (ID: %d)，跳过\n", c.UserID, rule.ID, rule.ItemID)
+			continue
 		}
 
-		fmt.Printf("成功给用户 %d 添加成就物品: %s (ID: %d)\n", c.UserID, itemName, itemID)
-		newUserItems = append(newUserItems, userItem)
+		fmt.Printf("成功给用户 %d 添加成就物品 (ID: %d)\n", c.UserID, rule.ItemID)
+		if _, err := coin.RewardAchievement(c.UserID, rule); err != nil {
+			fmt.Printf("发放用户 %d 的成就 %d 金币奖励失败: %v\n", c.UserID, rule.ID, err)
+		}
+		unlocked = append(unlocked, unlockedStatus(rule, userItem.ObtainedAt))
 	}
 
-	return newUserItems, nil
+	return unlocked, nil
 }
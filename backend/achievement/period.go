@@ -0,0 +1,41 @@
+package achievement
+
+import (
+	"backend/models"
+	"fmt"
+	"time"
+)
+
+// EvaluatePeriod 只使用 [start, end) 内的记录计算规则在用户上的结果，用于挑战等限时活动
+// end 晚于当前时间时只统计到当前时间；规则不能设置 window_days
+func EvaluatePeriod(userID uint, def *models.RuleDefinition, start, end time.Time) (RuleResult, error) {
+	if def.WindowDays != 0 || def.Type == models.RuleTypeAbsenceStreak {
+		return RuleResult{Target: def.Target}, fmt.Errorf("限时统计不支持 window_days 和 absence_streak")
+	}
+
+	c := newCheck(userID)
+	profile, err := models.GetUserProfile(userID)
+	if err != nil {
+		return RuleResult{Target: def.Target}, fmt.Errorf("获取用户资料失败: %v", err)
+	}
+	c.Location = profile.Location()
+	if end.Before(c.Now) {
+		// applyRecord 只跳过晚于 now 的记录，结束时间本身不在统计范围内
+		c.Now = end.Add(-time.Nanosecond)
+	}
+	c.Now = c.Now.In(c.Location)
+
+	records, err := c.sourceRecords(def.Source)
+	if err != nil {
+		return RuleResult{Target: def.Target}, err
+	}
+	inPeriod := make([]ruleRecord, 0, len(records))
+	for _, r := range records {
+		if !r.Time.Before(start) {
+			inPeriod = append(inPeriod, r)
+		}
+	}
+
+	state, _ := replay(inPeriod, def, c.Now)
+	return stateResult(&state, def, c.Now), nil
+}
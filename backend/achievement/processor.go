@@ -0,0 +1,184 @@
+package achievement
+
+import (
+	"backend/events"
+	"backend/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// RegisterEventHandlers 订阅饮食、打卡和健康状态事件，在后台增量更新成就进度
+func RegisterEventHandlers() {
+	events.Subscribe(events.FoodRecordCreated, handleRecordCreated)
+	events.Subscribe(events.CheckInCreated, handleRecordCreated)
+	events.Subscribe(events.HealthStateRecorded, handleRecordCreated)
+
+	// 修改或删除历史记录后，增量状态不再可靠，需要重新计算该来源的规则
+	events.Subscribe(events.FoodRecordUpdated, handleRecordChanged(models.RuleSourceFoodRecord))
+	events.Subscribe(events.FoodRecordDeleted, handleRecordChanged(models.RuleSourceFoodRecord))
+	events.Subscribe(events.HealthStateUpdated, handleRecordChanged(models.RuleSourceHealthState))
+	events.Subscribe(events.HealthStateDeleted, handleRecordChanged(models.RuleSourceHealthState))
+}
+
+func handleRecordCreated(e events.Event) {
+	unlocked, err := processEvent(e.UserID, e.Payload)
+	if err != nil {
+		fmt.Printf("处理用户 %d 的事件 %s 失败: %v\n", e.UserID, e.Type, err)
+	}
+	if len(unlocked) > 0 {
+		e.Reply(unlocked)
+	}
+}
+
+func handleRecordChanged(source string) events.Handler {
+	return func(e events.Event) {
+		if _, err := processSource(e.UserID, source, nil, true); err != nil {
+			fmt.Printf("处理用户 %d 的事件 %s 失败: %v\n", e.UserID, e.Type, err)
+		}
+	}
+}
+
+// processEvent 根据新增记录的类型更新对应来源的规则
+func processEvent(userID uint, payload interface{}) ([]Status, error) {
+	switch p := payload.(type) {
+	case *models.FoodRecord:
+		return processSource(userID, models.RuleSourceFoodRecord, p, false)
+	case *models.CheckIn:
+		return processSource(userID, models.RuleSourceCheckIn, p, false)
+	case *models.UserHealthState:
+		return processSource(userID, models.RuleSourceHealthState, p, false)
+	}
+	return nil, fmt.Errorf("不支持的事件数据: %T", payload)
+}
+
+// processSource 更新用户在某个数据来源上所有规则的进度，发放新达成的成就物品并返回新解锁的成就
+// payload 为新增的记录；为 nil 或 rebuild 为 true 时从历史数据重新计算
+func processSource(userID uint, source string, payload interface{}, rebuild bool) ([]Status, error) {
+	c := newCheck(userID)
+	if err := c.GatherData(); err != nil {
+		return nil, err
+	}
+
+	var record *ruleRecord
+	switch p := payload.(type) {
+	case *models.FoodRecord:
+		r := c.foodRecord(p)
+		record = &r
+	case *models.CheckIn:
+		r := c.checkInRecord(p)
+		record = &r
+	case *models.UserHealthState:
+		r := c.healthRecord(p)
+		record = &r
+	}
+
+	progress, err := models.GetUserAchievementProgress(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取成就进度失败: %v", err)
+	}
+
+	var achievedRules []*models.AchievementRule
+	achieved := make(map[uint]bool)
+	for i := range c.Rules {
+		rule := &c.Rules[i]
+		if rule.Definition.Source != source || c.OwnedItemIDs[rule.ItemID] || achieved[rule.ItemID] {
+			continue
+		}
+
+		p, err := c.updateProgress(rule, progress[rule.ID], record, rebuild)
+		if err != nil {
+			return nil, err
+		}
+		if err := models.SaveAchievementProgress(p); err != nil {
+			return nil, fmt.Errorf("保存成就进度失败: %v", err)
+		}
+
+		if p.Achieved {
+			fmt.Printf("用户 %d 达成了成就 %s，物品ID: %d\n", userID, rule.Name, rule.ItemID)
+			achievedRules = append(achievedRules, rule)
+			achieved[rule.ItemID] = true
+		}
+	}
+
+	return c.grantAchievements(achievedRules)
+}
+
+// updateProgress 将新增记录计入规则状态，状态缺失、规则已修改、记录乱序或有记录遗漏时从历史数据重新计算
+func (c *Check) updateProgress(rule *models.AchievementRule, p *models.AchievementProgress, record *ruleRecord, rebuild bool) (*models.AchievementProgress, error) {
+	def := &rule.Definition
+	hash := definitionHash(def)
+
+	if p == nil {
+		p = &models.AchievementProgress{UserID: c.UserID, RuleID: rule.ID}
+		rebuild = true
+	}
+	if p.DefinitionHash != hash || record == nil {
+		rebuild = true
+	}
+
+	if !rebuild {
+		// 事件可能因队列已满被丢弃或在重启时丢失，ID 更小的记录也可能晚于新记录提交，
+		// 数据库中的记录数与已计入的记录数对不上时说明有遗漏
+		maxID, expected := record.ID, p.RecordCount+1
+		if record.ID <= p.LastRecordID {
+			maxID, expected = p.LastRecordID, p.RecordCount
+		}
+		count, err := c.countRecords(def.Source, maxID)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case count != expected:
+			rebuild = true
+		case record.ID > p.LastRecordID:
+			state := p.State
+			if applyRecord(&state, def, record, c.Now) {
+				p.State = state
+				p.LastRecordID = record.ID
+				p.RecordCount = count
+			} else {
+				rebuild = true
+			}
+		}
+	}
+
+	if rebuild {
+		state, lastID, count, err := c.rebuild(def)
+		if err != nil {
+			return nil, err
+		}
+		p.State = state
+		p.LastRecordID = lastID
+		p.RecordCount = count
+		p.DefinitionHash = hash
+	}
+
+	result := stateResult(&p.State, def, c.Now)
+	p.Current = result.Current
+	p.Target = result.Target
+	p.Achieved = result.Achieved
+	return p, nil
+}
+
+// countRecords 统计数据来源中ID不大于 maxID 的记录数，同一次检查中相同的查询只执行一次
+func (c *Check) countRecords(source string, maxID uint) (int64, error) {
+	key := fmt.Sprintf("%s:%d", source, maxID)
+	if count, ok := c.recordCounts[key]; ok {
+		return count, nil
+	}
+	count, err := models.CountUserSourceRecords(c.UserID, source, maxID)
+	if err != nil {
+		return 0, fmt.Errorf("统计记录数失败: %v", err)
+	}
+	c.recordCounts[key] = count
+	return count, nil
+}
+
+// definitionHash 计算规则定义的哈希，用于判断缓存的状态是否仍然有效
+func definitionHash(def *models.RuleDefinition) string {
+	data, _ := json.Marshal(def)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
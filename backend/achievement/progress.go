@@ -0,0 +1,104 @@
+package achievement
+
+import (
+	"backend/models"
+	"fmt"
+	"time"
+)
+
+// Status 用户在某个成就上的解锁状态和进度
+type Status struct {
+	RuleID      uint       `json:"rule_id"`
+	ItemID      uint       `json:"item_id"`
+	Name        string     `json:"name"`                  // 成就名称
+	Condition   string     `json:"condition"`             // 获取条件
+	Description string     `json:"description"`           // 成就描述
+	IconURL     string     `json:"icon_url"`              // 图标路径
+	ImageURL    string     `json:"image_url"`             // 图片路径
+	Unlocked    bool       `json:"unlocked"`              // 是否已解锁
+	UnlockedAt  *time.Time `json:"unlocked_at,omitempty"` // 解锁时间
+	Current     int        `json:"current"`               // 当前进度
+	Target      int        `json:"target"`                // 目标
+}
+
+// UserAchievements 列出所有启用的成就及用户在每个成就上的状态
+// 未解锁的成就优先使用后台计算的进度，缺失或规则已修改时从历史数据重新计算
+func UserAchievements(userID uint) ([]Status, error) {
+	c, err := GenCheck(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	obtainedAt, err := models.GetUserItemObtainedTimes(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户物品失败: %v", err)
+	}
+
+	progress, err := models.GetUserAchievementProgress(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取成就进度失败: %v", err)
+	}
+
+	statuses := make([]Status, 0, len(c.Rules))
+	for i := range c.Rules {
+		rule := &c.Rules[i]
+		if t, ok := obtainedAt[rule.ItemID]; ok {
+			statuses = append(statuses, unlockedStatus(rule, t))
+			continue
+		}
+
+		status := ruleStatus(rule)
+		result, err := c.progressResult(rule, progress[rule.ID])
+		if err != nil {
+			return nil, err
+		}
+		status.Current = result.Current
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// ruleStatus 根据规则和关联物品生成成就的基本信息
+func ruleStatus(rule *models.AchievementRule) Status {
+	status := Status{
+		RuleID:    rule.ID,
+		ItemID:    rule.ItemID,
+		Name:      rule.Name,
+		Condition: rule.Description,
+		Target:    rule.Definition.Target,
+	}
+	if rule.Item != nil {
+		status.Name = rule.Item.Name
+		status.Description = rule.Item.Description
+		status.IconURL = rule.Item.IconURL
+		status.ImageURL = rule.Item.ImageURL
+	}
+	return status
+}
+
+// unlockedStatus 生成已解锁成就的状态
+func unlockedStatus(rule *models.AchievementRule, unlockedAt time.Time) Status {
+	status := ruleStatus(rule)
+	status.Unlocked = true
+	status.UnlockedAt = &unlockedAt
+	status.Current = status.Target
+	return status
+}
+
+// progressResult 计算未解锁规则的当前进度，必要时重新计算并保存状态
+func (c *Check) progressResult(rule *models.AchievementRule, p *models.AchievementProgress) (RuleResult, error) {
+	def := &rule.Definition
+	if p != nil && p.DefinitionHash == definitionHash(def) {
+		return stateResult(&p.State, def, c.Now), nil
+	}
+
+	p, err := c.updateProgress(rule, p, nil, true)
+	if err != nil {
+		return RuleResult{Target: def.Target}, err
+	}
+	if err := models.SaveAchievementProgress(p); err != nil {
+		return RuleResult{Target: def.Target}, fmt.Errorf("保存成就进度失败: %v", err)
+	}
+	return RuleResult{Current: p.Current, Target: p.Target, Achieved: p.Achieved}, nil
+}
@@ -0,0 +1,286 @@
+package achievement
+
+import (
+	"backend/models"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RuleResult 规则在某个用户上的计算结果
+type RuleResult struct {
+	Current  int  `json:"current"`  // 当前进度
+	Target   int  `json:"target"`   // 目标
+	Achieved bool `json:"achieved"` // 是否达成
+}
+
+// ruleRecord 统一的记录视图，便于对不同数据来源应用同一套规则
+type ruleRecord struct {
+	ID      uint
+	Time    time.Time
+	Food    *models.FoodRecord
+	CheckIn *models.CheckIn
+	Health  *models.UserHealthState
+}
+
+// fieldSpec 规则可引用的字段，数值字段和文本字段二选一
+type fieldSpec struct {
+	numeric func(r *ruleRecord) float64
+	text    func(r *ruleRecord) string
+}
+
+func foodNumber(fn func(f *models.FoodRecord) float64) fieldSpec {
+	return fieldSpec{numeric: func(r *ruleRecord) float64 { return fn(r.Food) }}
+}
+
+func checkInNumber(fn func(c *models.CheckIn) float64) fieldSpec {
+	return fieldSpec{numeric: func(r *ruleRecord) float64 { return fn(r.CheckIn) }}
+}
+
+func healthNumber(fn func(h *models.UserHealthState) float64) fieldSpec {
+	return fieldSpec{numeric: func(r *ruleRecord) float64 { return fn(r.Health) }}
+}
+
+// timeFields 所有数据来源都支持的时间字段（按用户时区计算）
+var timeFields = map[string]fieldSpec{
+	"hour":    {numeric: func(r *ruleRecord) float64 { return float64(r.Time.Hour()) }},
+	"weekday": {numeric: func(r *ruleRecord) float64 { return float64(r.Time.Weekday()) }},
+}
+
+// sourceFields 各数据来源可用于过滤的字段
+var sourceFields = map[string]map[string]fieldSpec{
+	models.RuleSourceFoodRecord: {
+		"weight":          foodNumber(func(f *models.FoodRecord) float64 { return f.Weight }),
+		"calories":        foodNumber(func(f *models.FoodRecord) float64 { return f.Calories }),
+		"protein":         foodNumber(func(f *models.FoodRecord) float64 { return f.Protein }),
+		"total_fat":       foodNumber(func(f *models.FoodRecord) float64 { return f.TotalFat }),
+		"saturated_fat":   foodNumber(func(f *models.FoodRecord) float64 { return f.SaturatedFat }),
+		"trans_fat":       foodNumber(func(f *models.FoodRecord) float64 { return f.TransFat }),
+		"unsaturated_fat": foodNumber(func(f *models.FoodRecord) float64 { return f.UnsaturatedFat }),
+		"carbohydrates":   foodNumber(func(f *models.FoodRecord) float64 { return f.Carbohydrates }),
+		"sugar":           foodNumber(func(f *models.FoodRecord) float64 { return f.Sugar }),
+		"fiber":           foodNumber(func(f *models.FoodRecord) float64 { return f.Fiber }),
+		"vitamin_a":       foodNumber(func(f *models.FoodRecord) float64 { return f.VitaminA }),
+		"vitamin_c":       foodNumber(func(f *models.FoodRecord) float64 { return f.VitaminC }),
+		"vitamin_d":       foodNumber(func(f *models.FoodRecord) float64 { return f.VitaminD }),
+		"vitamin_b1":      foodNumber(func(f *models.FoodRecord) float64 { return f.VitaminB1 }),
+		"vitamin_b2":      foodNumber(func(f *models.FoodRecord) float64 { return f.VitaminB2 }),
+		"calcium":         foodNumber(func(f *models.FoodRecord) float64 { return f.Calcium }),
+		"iron":            foodNumber(func(f *models.FoodRecord) float64 { return f.Iron }),
+		"sodium":          foodNumber(func(f *models.FoodRecord) float64 { return f.Sodium }),
+		"potassium":       foodNumber(func(f *models.FoodRecord) float64 { return f.Potassium }),
+		// 三大营养素占比（按克数计算）
+		"protein_ratio": foodNumber(func(f *models.FoodRecord) float64 { return macroRatio(f, f.Protein) }),
+		"fat_ratio":     foodNumber(func(f *models.FoodRecord) float64 { return macroRatio(f, f.TotalFat) }),
+		"carb_ratio":    foodNumber(func(f *models.FoodRecord) float64 { return macroRatio(f, f.Carbohydrates) }),
+		"meal_type":     {text: func(r *ruleRecord) string { return r.Food.MealType }},
+		"food_name":     {text: func(r *ruleRecord) string { return strings.TrimSpace(r.Food.FoodName) }},
+	},
+	models.RuleSourceCheckIn: {
+		// 评分为 0 表示未填写
+		"mood":   checkInNumber(func(c *models.CheckIn) float64 { return float64(c.Mood) }),
+		"energy": checkInNumber(func(c *models.CheckIn) float64 { return float64(c.Energy) }),
+		"hunger": checkInNumber(func(c *models.CheckIn) float64 { return float64(c.Hunger) }),
+	},
+	models.RuleSourceHealthState: {
+		"weight":               healthNumber(func(h *models.UserHealthState) float64 { return h.Weight }),
+		"bmi":                  healthNumber(func(h *models.UserHealthState) float64 { return h.BMI }),
+		"body_fat_percentage":  healthNumber(func(h *models.UserHealthState) float64 { return h.BodyFatPercentage }),
+		"heart_rate":           healthNumber(func(h *models.UserHealthState) float64 { return float64(h.HeartRate) }),
+		"fasting_glucose":      healthNumber(func(h *models.UserHealthState) float64 { return h.FastingGlucose }),
+		"postprandial_glucose": healthNumber(func(h *models.UserHealthState) float64 { return h.PostprandialGlucose }),
+		"total_cholesterol":    healthNumber(func(h *models.UserHealthState) float64 { return h.TotalCholesterol }),
+	},
+}
+
+var validOps = map[string]bool{
+	"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true, "in": true, "between": true,
+}
+
+func macroRatio(f *models.FoodRecord, value float64) float64 {
+	total := f.Protein + f.TotalFat + f.Carbohydrates
+	if total == 0 {
+		return 0
+	}
+	return value / total
+}
+
+// lookupField 查找数据来源下的字段定义
+func lookupField(source, field string) (fieldSpec, bool) {
+	if spec, ok := timeFields[field]; ok {
+		return spec, true
+	}
+	spec, ok := sourceFields[source][field]
+	return spec, ok
+}
+
+// ValidateDefinition 校验规则定义是否合法
+func ValidateDefinition(def *models.RuleDefinition) error {
+	switch def.Type {
+	case models.RuleTypeCount, models.RuleTypeDistinctDays, models.RuleTypeStreak,
+		models.RuleTypeReboundStreak, models.RuleTypeDistinctValues, models.RuleTypeAbsenceStreak:
+	default:
+		return fmt.Errorf("不支持的规则类型: %s", def.Type)
+	}
+
+	if _, ok := sourceFields[def.Source]; !ok {
+		return fmt.Errorf("不支持的数据来源: %s", def.Source)
+	}
+	if def.Target <= 0 {
+		return fmt.Errorf("目标必须大于0")
+	}
+	if def.WindowDays < 0 || def.MinPerDay < 0 {
+		return fmt.Errorf("window_days 和 min_per_day 不能为负数")
+	}
+	if def.WindowDays > 0 && def.Type != models.RuleTypeCount && def.Type != models.RuleTypeDistinctDays {
+		return fmt.Errorf("只有 count 和 distinct_days 规则支持 window_days")
+	}
+
+	if def.Type == models.RuleTypeDistinctValues {
+		spec, ok := lookupField(def.Source, def.Field)
+		if !ok || spec.text == nil {
+			return fmt.Errorf("不支持统计的字段: %s", def.Field)
+		}
+	}
+
+	for _, f := range def.Filters {
+		if !validOps[f.Op] {
+			return fmt.Errorf("不支持的比较运算符: %s", f.Op)
+		}
+		spec, ok := lookupField(def.Source, f.Field)
+		if !ok {
+			return fmt.Errorf("数据来源 %s 不支持字段: %s", def.Source, f.Field)
+		}
+		if err := validateFilterValue(f, spec.text != nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateFilterValue(f models.RuleFilter, isText bool) error {
+	switch f.Op {
+	case "in":
+		values, ok := f.Value.([]interface{})
+		if !ok || len(values) == 0 {
+			return fmt.Errorf("字段 %s 的 in 条件需要非空数组", f.Field)
+		}
+	case "between":
+		values, ok := f.Value.([]interface{})
+		if !ok || len(values) != 2 {
+			return fmt.Errorf("字段 %s 的 between 条件需要 [最小值, 最大值]", f.Field)
+		}
+		for _, v := range values {
+			if _, ok := toFloat(v); !ok {
+				return fmt.Errorf("字段 %s 的 between 条件需要数值", f.Field)
+			}
+		}
+	default:
+		if isText {
+			if f.Op != "==" && f.Op != "!=" {
+				return fmt.Errorf("文本字段 %s 只支持 ==、!=、in", f.Field)
+			}
+			if _, ok := f.Value.(string); !ok {
+				return fmt.Errorf("字段 %s 需要文本值", f.Field)
+			}
+		} else if _, ok := toFloat(f.Value); !ok {
+			return fmt.Errorf("字段 %s 需要数值", f.Field)
+		}
+	}
+	return nil
+}
+
+// matchFilters 判断记录是否满足所有过滤条件
+func matchFilters(source string, r *ruleRecord, filters []models.RuleFilter) bool {
+	for _, f := range filters {
+		spec, ok := lookupField(source, f.Field)
+		if !ok {
+			return false
+		}
+		if spec.text != nil {
+			if !compareString(spec.text(r), f) {
+				return false
+			}
+		} else if !compareNumber(spec.numeric(r), f) {
+			return false
+		}
+	}
+	return true
+}
+
+func compareNumber(v float64, f models.RuleFilter) bool {
+	switch f.Op {
+	case "in":
+		values, _ := f.Value.([]interface{})
+		for _, item := range values {
+			if n, ok := toFloat(item); ok && n == v {
+				return true
+			}
+		}
+		return false
+	case "between":
+		values, _ := f.Value.([]interface{})
+		if len(values) != 2 {
+			return false
+		}
+		lo, ok1 := toFloat(values[0])
+		hi, ok2 := toFloat(values[1])
+		return ok1 && ok2 && v >= lo && v <= hi
+	}
+
+	target, ok := toFloat(f.Value)
+	if !ok {
+		return false
+	}
+	switch f.Op {
+	case "==":
+		return v == target
+	case "!=":
+		return v != target
+	case "<":
+		return v < target
+	case "<=":
+		return v <= target
+	case ">":
+		return v > target
+	case ">=":
+		return v >= target
+	}
+	return false
+}
+
+func compareString(v string, f models.RuleFilter) bool {
+	switch f.Op {
+	case "==":
+		s, _ := f.Value.(string)
+		return v == s
+	case "!=":
+		s, _ := f.Value.(string)
+		return v != s
+	case "in":
+		values, _ := f.Value.([]interface{})
+		for _, item := range values {
+			if s, ok := item.(string); ok && s == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,92 @@
+package achievement
+
+import (
+	"backend/models"
+	"testing"
+	"time"
+)
+
+func TestValidateDefinition(t *testing.T) {
+	tests := []struct {
+		name    string
+		def     models.RuleDefinition
+		wantErr bool
+	}{
+		{"最简单的计数规则", models.RuleDefinition{Type: models.RuleTypeCount, Source: models.RuleSourceFoodRecord, Target: 1}, false},
+		{"不支持的规则类型", models.RuleDefinition{Type: "sum", Source: models.RuleSourceFoodRecord, Target: 1}, true},
+		{"不支持的数据来源", models.RuleDefinition{Type: models.RuleTypeCount, Source: "water", Target: 1}, true},
+		{"目标为0", models.RuleDefinition{Type: models.RuleTypeCount, Source: models.RuleSourceFoodRecord}, true},
+		{"负数窗口", models.RuleDefinition{Type: models.RuleTypeCount, Source: models.RuleSourceFoodRecord, WindowDays: -1, Target: 1}, true},
+		{"负数每日最低记录数", models.RuleDefinition{Type: models.RuleTypeStreak, Source: models.RuleSourceFoodRecord, MinPerDay: -1, Target: 1}, true},
+		{"distinct_days 支持窗口", models.RuleDefinition{Type: models.RuleTypeDistinctDays, Source: models.RuleSourceCheckIn, WindowDays: 7, Target: 5}, false},
+		{"streak 不支持窗口", models.RuleDefinition{Type: models.RuleTypeStreak, Source: models.RuleSourceFoodRecord, WindowDays: 7, Target: 3}, true},
+		{"distinct_values 统计文本字段", models.RuleDefinition{Type: models.RuleTypeDistinctValues, Source: models.RuleSourceFoodRecord, Field: "food_name", Target: 10}, false},
+		{"distinct_values 不能统计数值字段", models.RuleDefinition{Type: models.RuleTypeDistinctValues, Source: models.RuleSourceFoodRecord, Field: "calories", Target: 10}, true},
+		{"distinct_values 缺少字段", models.RuleDefinition{Type: models.RuleTypeDistinctValues, Source: models.RuleSourceFoodRecord, Target: 10}, true},
+		{"所有来源都支持时间字段", models.RuleDefinition{Type: models.RuleTypeCount, Source: models.RuleSourceCheckIn, Filters: []models.RuleFilter{{Field: "hour", Op: "<", Value: float64(9)}}, Target: 1}, false},
+		{"来源不支持的字段", models.RuleDefinition{Type: models.RuleTypeCount, Source: models.RuleSourceFoodRecord, Filters: []models.RuleFilter{{Field: "mood", Op: ">=", Value: float64(4)}}, Target: 1}, true},
+		{"不支持的运算符", models.RuleDefinition{Type: models.RuleTypeCount, Source: models.RuleSourceFoodRecord, Filters: []models.RuleFilter{{Field: "calories", Op: "~", Value: float64(100)}}, Target: 1}, true},
+		{"in 需要非空数组", models.RuleDefinition{Type: models.RuleTypeCount, Source: models.RuleSourceFoodRecord, Filters: []models.RuleFilter{{Field: "meal_type", Op: "in", Value: []interface{}{}}}, Target: 1}, true},
+		{"between 需要两个值", models.RuleDefinition{Type: models.RuleTypeCount, Source: models.RuleSourceFoodRecord, Filters: []models.RuleFilter{{Field: "hour", Op: "between", Value: []interface{}{float64(6)}}}, Target: 1}, true},
+		{"between 需要数值", models.RuleDefinition{Type: models.RuleTypeCount, Source: models.RuleSourceFoodRecord, Filters: []models.RuleFilter{{Field: "hour", Op: "between", Value: []interface{}{"6", "9"}}}, Target: 1}, true},
+		{"between 数值范围", models.RuleDefinition{Type: models.RuleTypeCount, Source: models.RuleSourceFoodRecord, Filters: []models.RuleFilter{{Field: "hour", Op: "between", Value: []interface{}{float64(6), float64(9)}}}, Target: 1}, false},
+		{"文本字段不支持大小比较", models.RuleDefinition{Type: models.RuleTypeCount, Source: models.RuleSourceFoodRecord, Filters: []models.RuleFilter{{Field: "meal_type", Op: "<", Value: "早餐"}}, Target: 1}, true},
+		{"文本字段需要文本值", models.RuleDefinition{Type: models.RuleTypeCount, Source: models.RuleSourceFoodRecord, Filters: []models.RuleFilter{{Field: "meal_type", Op: "==", Value: float64(1)}}, Target: 1}, true},
+		{"数值字段需要数值", models.RuleDefinition{Type: models.RuleTypeCount, Source: models.RuleSourceFoodRecord, Filters: []models.RuleFilter{{Field: "sodium", Op: "<", Value: "2000"}}, Target: 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDefinition(&tt.def)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDefinition() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatchFilters(t *testing.T) {
+	// 2026-03-10 是星期二
+	at := time.Date(2026, 3, 10, 7, 30, 0, 0, testLoc)
+	breakfast := &ruleRecord{ID: 1, Time: at, Food: &models.FoodRecord{
+		FoodName: " 燕麦粥 ", MealType: "早餐", Calories: 350, Protein: 20, TotalFat: 10, Carbohydrates: 70,
+	}}
+	checkIn := &ruleRecord{ID: 2, Time: at, CheckIn: &models.CheckIn{Mood: 4}}
+
+	tests := []struct {
+		name    string
+		source  string
+		record  *ruleRecord
+		filters []models.RuleFilter
+		want    bool
+	}{
+		{"没有条件", models.RuleSourceFoodRecord, breakfast, nil, true},
+		{"小时在范围内", models.RuleSourceFoodRecord, breakfast, []models.RuleFilter{{Field: "hour", Op: "between", Value: []interface{}{float64(5), float64(9)}}}, true},
+		{"小时不在范围内", models.RuleSourceFoodRecord, breakfast, []models.RuleFilter{{Field: "hour", Op: "between", Value: []interface{}{float64(10), float64(14)}}}, false},
+		{"小时边界包含", models.RuleSourceFoodRecord, breakfast, []models.RuleFilter{{Field: "hour", Op: ">=", Value: float64(7)}}, true},
+		{"小时边界不包含", models.RuleSourceFoodRecord, breakfast, []models.RuleFilter{{Field: "hour", Op: "<", Value: float64(7)}}, false},
+		{"星期", models.RuleSourceFoodRecord, breakfast, []models.RuleFilter{{Field: "weekday", Op: "==", Value: float64(2)}}, true},
+		{"餐食类型相等", models.RuleSourceFoodRecord, breakfast, []models.RuleFilter{{Field: "meal_type", Op: "==", Value: "早餐"}}, true},
+		{"餐食类型不等", models.RuleSourceFoodRecord, breakfast, []models.RuleFilter{{Field: "meal_type", Op: "!=", Value: "早餐"}}, false},
+		{"餐食类型在列表中", models.RuleSourceFoodRecord, breakfast, []models.RuleFilter{{Field: "meal_type", Op: "in", Value: []interface{}{"午餐", "早餐"}}}, true},
+		{"餐食类型不在列表中", models.RuleSourceFoodRecord, breakfast, []models.RuleFilter{{Field: "meal_type", Op: "in", Value: []interface{}{"午餐", "晚餐"}}}, false},
+		{"食物名称去掉首尾空白", models.RuleSourceFoodRecord, breakfast, []models.RuleFilter{{Field: "food_name", Op: "==", Value: "燕麦粥"}}, true},
+		{"数值在列表中", models.RuleSourceFoodRecord, breakfast, []models.RuleFilter{{Field: "calories", Op: "in", Value: []interface{}{float64(300), float64(350)}}}, true},
+		{"营养素占比", models.RuleSourceFoodRecord, breakfast, []models.RuleFilter{{Field: "protein_ratio", Op: "<=", Value: 0.2}}, true},
+		{"多个条件需要同时满足", models.RuleSourceFoodRecord, breakfast, []models.RuleFilter{
+			{Field: "hour", Op: ">=", Value: float64(7)},
+			{Field: "meal_type", Op: "==", Value: "晚餐"},
+		}, false},
+		{"来源不支持的字段不匹配", models.RuleSourceFoodRecord, breakfast, []models.RuleFilter{{Field: "mood", Op: ">=", Value: float64(1)}}, false},
+		{"打卡评分", models.RuleSourceCheckIn, checkIn, []models.RuleFilter{{Field: "mood", Op: ">=", Value: float64(4)}}, true},
+		{"打卡评分未达到", models.RuleSourceCheckIn, checkIn, []models.RuleFilter{{Field: "mood", Op: ">", Value: float64(4)}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchFilters(tt.source, tt.record, tt.filters); got != tt.want {
+				t.Errorf("matchFilters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
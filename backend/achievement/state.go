@@ -0,0 +1,207 @@
+package achievement
+
+import (
+	"backend/calendar"
+	"backend/models"
+	"sort"
+	"time"
+)
+
+// applyRecord 将一条记录计入规则状态，记录时间需已转换到用户时区
+// 依赖日期顺序的规则遇到比已有状态更早的记录时返回 false，调用方需要从历史数据重新计算
+func applyRecord(state *models.RuleState, def *models.RuleDefinition, r *ruleRecord, now time.Time) bool {
+	if r.Time.After(now) {
+		return true
+	}
+	day := calendar.DateKey(r.Time, now.Location())
+
+	// absence_streak 需要知道用户从哪天开始产生该来源的记录
+	if def.Type == models.RuleTypeAbsenceStreak && (state.FirstDay == "" || day < state.FirstDay) {
+		state.FirstDay = day
+	}
+
+	if !matchFilters(def.Source, r, def.Filters) {
+		return true
+	}
+
+	windowStart := windowStartDay(def.WindowDays, now)
+	if windowStart != "" && day < windowStart {
+		return true
+	}
+
+	switch def.Type {
+	case models.RuleTypeCount:
+		if windowStart == "" {
+			state.Count++
+			return true
+		}
+		addDayCount(state, day, windowStart)
+	case models.RuleTypeDistinctDays:
+		if windowStart != "" {
+			addDayCount(state, day, windowStart)
+			return true
+		}
+		return applyQualifyingDay(state, def, day)
+	case models.RuleTypeStreak, models.RuleTypeReboundStreak:
+		return applyQualifyingDay(state, def, day)
+	case models.RuleTypeDistinctValues:
+		spec, _ := lookupField(def.Source, def.Field)
+		if spec.text == nil {
+			return true
+		}
+		if v := spec.text(r); v != "" {
+			if state.Values == nil {
+				state.Values = make(map[string]bool)
+			}
+			state.Values[v] = true
+		}
+	case models.RuleTypeAbsenceStreak:
+		if day > state.LastViolationDay {
+			state.LastViolationDay = day
+		}
+	}
+	return true
+}
+
+// addDayCount 累加某天的记录数，并丢弃统计窗口之外的日期
+func addDayCount(state *models.RuleState, day, windowStart string) {
+	if state.DayCounts == nil {
+		state.DayCounts = make(map[string]int)
+	}
+	state.DayCounts[day]++
+	for d := range state.DayCounts {
+		if d < windowStart {
+			delete(state.DayCounts, d)
+		}
+	}
+}
+
+// applyQualifyingDay 按日期顺序累计达标天数和连续天数
+func applyQualifyingDay(state *models.RuleState, def *models.RuleDefinition, day string) bool {
+	switch {
+	case day == state.LastDay:
+		state.LastDayCount++
+	case day > state.LastDay:
+		state.LastDay = day
+		state.LastDayCount = 1
+	default:
+		return false
+	}
+
+	// 当天记录数恰好达到最低要求时才算作新的达标日
+	if state.LastDayCount != minPerDay(def) {
+		return true
+	}
+
+	state.QualifiedDays++
+	if state.LastQualifiedDay != "" && calendar.DaysBetween(state.LastQualifiedDay, day) == 1 {
+		state.CurrentRun++
+	} else {
+		if state.LastQualifiedDay != "" {
+			state.HadBreak = true
+		}
+		state.CurrentRun = 1
+	}
+	state.LastQualifiedDay = day
+
+	// rebound_streak 只统计发生过中断之后的连续段
+	if def.Type != models.RuleTypeReboundStreak || state.HadBreak {
+		if state.CurrentRun > state.LongestRun {
+			state.LongestRun = state.CurrentRun
+		}
+	}
+	return true
+}
+
+// stateResult 根据规则状态计算当前进度
+func stateResult(state *models.RuleState, def *models.RuleDefinition, now time.Time) RuleResult {
+	result := RuleResult{Target: def.Target}
+	windowStart := windowStartDay(def.WindowDays, now)
+
+	switch def.Type {
+	case models.RuleTypeCount:
+		if windowStart == "" {
+			result.Current = state.Count
+			break
+		}
+		for day, count := range state.DayCounts {
+			if day >= windowStart {
+				result.Current += count
+			}
+		}
+	case models.RuleTypeDistinctDays:
+		if windowStart == "" {
+			result.Current = state.QualifiedDays
+			break
+		}
+		for day, count := range state.DayCounts {
+			if day >= windowStart && count >= minPerDay(def) {
+				result.Current++
+			}
+		}
+	case models.RuleTypeStreak, models.RuleTypeReboundStreak:
+		result.Current = state.LongestRun
+	case models.RuleTypeDistinctValues:
+		result.Current = len(state.Values)
+	case models.RuleTypeAbsenceStreak:
+		result.Current = absenceDays(state, now)
+	}
+
+	result.Achieved = result.Current >= result.Target
+	return result
+}
+
+// absenceDays 计算截至昨天连续多少个完整的日子没有出现匹配记录
+// 从用户第一次产生该来源记录的那天开始计算
+func absenceDays(state *models.RuleState, now time.Time) int {
+	if state.FirstDay == "" {
+		return 0
+	}
+	today := calendar.DateKey(now, now.Location())
+	days := calendar.DaysBetween(state.FirstDay, today)
+	if state.LastViolationDay != "" {
+		days = calendar.DaysBetween(state.LastViolationDay, today) - 1
+	}
+	if days < 0 {
+		return 0
+	}
+	return days
+}
+
+// replay 按时间顺序重放所有记录，返回规则状态和已计入的最大记录ID
+func replay(records []ruleRecord, def *models.RuleDefinition, now time.Time) (models.RuleState, uint) {
+	sorted := make([]ruleRecord, len(records))
+	copy(sorted, records)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Time.Equal(sorted[j].Time) {
+			return sorted[i].ID < sorted[j].ID
+		}
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	var state models.RuleState
+	var lastID uint
+	for i := range sorted {
+		applyRecord(&state, def, &sorted[i], now)
+		if sorted[i].ID > lastID {
+			lastID = sorted[i].ID
+		}
+	}
+	return state, lastID
+}
+
+func minPerDay(def *models.RuleDefinition) int {
+	if def.MinPerDay <= 0 {
+		return 1
+	}
+	return def.MinPerDay
+}
+
+// windowStartDay 返回统计窗口的起始日期（包含今天在内的最近N天），不限时返回空字符串
+func windowStartDay(days int, now time.Time) string {
+	if days <= 0 {
+		return ""
+	}
+	start, _ := calendar.LastNDays(now, days, now.Location())
+	return calendar.DateKey(start, now.Location())
+}
@@ -0,0 +1,194 @@
+package achievement
+
+import (
+	"backend/models"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testLoc 测试使用的用户时区，testNow 为计算进度的时间（2026-03-10 20:00）
+var (
+	testLoc = time.FixedZone("CST", 8*3600)
+	testNow = time.Date(2026, 3, 10, 20, 0, 0, 0, testLoc)
+)
+
+// food 构造一条饮食记录，daysAgo 为距 testNow 所在日期的天数
+func food(id uint, daysAgo, hour int, f models.FoodRecord) ruleRecord {
+	return ruleRecord{ID: id, Time: time.Date(2026, 3, 10-daysAgo, hour, 0, 0, 0, testLoc), Food: &f}
+}
+
+// meal 构造一条只有餐食类型和食物名称的饮食记录
+func meal(id uint, daysAgo, hour int, mealType, name string) ruleRecord {
+	return food(id, daysAgo, hour, models.FoodRecord{MealType: mealType, FoodName: name})
+}
+
+// onDays 在每个指定的日期构造一条早餐记录
+func onDays(daysAgo ...int) []ruleRecord {
+	records := make([]ruleRecord, len(daysAgo))
+	for i, d := range daysAgo {
+		records[i] = meal(uint(i+1), d, 8, "早餐", "燕麦粥")
+	}
+	return records
+}
+
+func TestStateResult(t *testing.T) {
+	breakfast := []models.RuleFilter{{Field: "meal_type", Op: "==", Value: "早餐"}}
+	highSugar := []models.RuleFilter{{Field: "sugar", Op: ">", Value: float64(25)}}
+
+	tests := []struct {
+		name    string
+		def     models.RuleDefinition
+		records []ruleRecord
+		want    int
+	}{
+		{"计数", models.RuleDefinition{Type: models.RuleTypeCount, Target: 3}, onDays(2, 1, 1), 3},
+		{"计数-按餐食类型过滤", models.RuleDefinition{Type: models.RuleTypeCount, Filters: breakfast, Target: 3}, []ruleRecord{
+			meal(1, 2, 8, "早餐", "包子"), meal(2, 2, 12, "午餐", "米饭"), meal(3, 1, 8, "早餐", "油条"),
+		}, 2},
+		{"计数-忽略晚于当前时间的记录", models.RuleDefinition{Type: models.RuleTypeCount, Target: 1}, []ruleRecord{meal(1, 0, 21, "加餐", "水果")}, 0},
+		{"不同天数", models.RuleDefinition{Type: models.RuleTypeDistinctDays, Target: 3}, onDays(5, 5, 2, 0), 3},
+		{"不同天数-每天至少两条", models.RuleDefinition{Type: models.RuleTypeDistinctDays, MinPerDay: 2, Target: 3}, onDays(3, 3, 1, 0, 0), 2},
+		{"最长连续天数", models.RuleDefinition{Type: models.RuleTypeStreak, Target: 3}, onDays(6, 5, 4, 1, 0), 3},
+		{"最长连续天数-当天未达到最低记录数则中断", models.RuleDefinition{Type: models.RuleTypeStreak, MinPerDay: 2, Target: 3}, onDays(3, 3, 2, 1, 1, 0, 0), 2},
+		{"中断后的连续天数", models.RuleDefinition{Type: models.RuleTypeReboundStreak, Target: 3}, onDays(9, 8, 7, 6, 3, 2), 2},
+		{"中断后的连续天数-没有中断", models.RuleDefinition{Type: models.RuleTypeReboundStreak, Target: 3}, onDays(2, 1, 0), 0},
+		{"不同取值", models.RuleDefinition{Type: models.RuleTypeDistinctValues, Field: "food_name", Target: 3}, []ruleRecord{
+			meal(1, 3, 12, "午餐", "米饭"), meal(2, 2, 12, "午餐", "面条"), meal(3, 1, 12, "午餐", "米饭"), meal(4, 0, 12, "午餐", ""),
+		}, 2},
+		{"连续未出现-从第一条记录开始计算", models.RuleDefinition{Type: models.RuleTypeAbsenceStreak, Filters: highSugar, Target: 3}, []ruleRecord{
+			food(1, 3, 8, models.FoodRecord{Sugar: 5}),
+		}, 3},
+		{"连续未出现-从最近一次出现的次日开始计算", models.RuleDefinition{Type: models.RuleTypeAbsenceStreak, Filters: highSugar, Target: 3}, []ruleRecord{
+			food(1, 9, 8, models.FoodRecord{Sugar: 10}), food(2, 5, 8, models.FoodRecord{Sugar: 30}), food(3, 2, 8, models.FoodRecord{Sugar: 5}),
+		}, 4},
+		{"连续未出现-今天出现", models.RuleDefinition{Type: models.RuleTypeAbsenceStreak, Filters: highSugar, Target: 3}, []ruleRecord{
+			food(1, 9, 8, models.FoodRecord{Sugar: 10}), food(2, 0, 8, models.FoodRecord{Sugar: 30}),
+		}, 0},
+		{"连续未出现-没有记录", models.RuleDefinition{Type: models.RuleTypeAbsenceStreak, Filters: highSugar, Target: 3}, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.def.Source = models.RuleSourceFoodRecord
+			if err := ValidateDefinition(&tt.def); err != nil {
+				t.Fatalf("规则定义无效: %v", err)
+			}
+			state, _ := replay(tt.records, &tt.def, testNow)
+			result := stateResult(&state, &tt.def, testNow)
+			if result.Current != tt.want {
+				t.Errorf("current = %d, want %d", result.Current, tt.want)
+			}
+			if result.Achieved != (tt.want >= tt.def.Target) {
+				t.Errorf("achieved = %v, want %v", result.Achieved, tt.want >= tt.def.Target)
+			}
+		})
+	}
+}
+
+func TestWindowBoundaries(t *testing.T) {
+	tests := []struct {
+		name    string
+		def     models.RuleDefinition
+		records []ruleRecord
+		now     time.Time
+		want    int
+	}{
+		{"窗口包含最早一天的零点", models.RuleDefinition{Type: models.RuleTypeCount, WindowDays: 7}, []ruleRecord{
+			meal(1, 6, 0, "早餐", "a"), meal(2, 7, 23, "晚餐", "b"), meal(3, 0, 8, "早餐", "c"),
+		}, testNow, 2},
+		{"窗口包含今天", models.RuleDefinition{Type: models.RuleTypeCount, WindowDays: 1}, []ruleRecord{
+			meal(1, 1, 23, "晚餐", "a"), meal(2, 0, 0, "早餐", "b"),
+		}, testNow, 1},
+		{"时间推移后旧记录移出窗口", models.RuleDefinition{Type: models.RuleTypeCount, WindowDays: 3}, onDays(2, 1, 0), testNow.AddDate(0, 0, 1), 2},
+		{"不同天数窗口", models.RuleDefinition{Type: models.RuleTypeDistinctDays, WindowDays: 3}, onDays(3, 2, 2, 0), testNow, 2},
+		{"不同天数窗口-每天至少两条", models.RuleDefinition{Type: models.RuleTypeDistinctDays, WindowDays: 3, MinPerDay: 2}, onDays(3, 3, 2, 2, 0), testNow, 1},
+		{"时间推移后达标日移出窗口", models.RuleDefinition{Type: models.RuleTypeDistinctDays, WindowDays: 3, MinPerDay: 2}, onDays(2, 2, 1), testNow.AddDate(0, 0, 1), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.def.Source = models.RuleSourceFoodRecord
+			tt.def.Target = 1
+			// 状态在 testNow 时计算，结果在 tt.now 时读取，模拟规则状态保存后隔天再查看进度
+			state, _ := replay(tt.records, &tt.def, testNow)
+			if got := stateResult(&state, &tt.def, tt.now).Current; got != tt.want {
+				t.Errorf("current = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestIncrementalMatchesReplay 按记录到达顺序逐条计入状态，与处理器一样在 applyRecord 返回 false 时重新计算，
+// 结果应与一次性重放所有记录相同
+func TestIncrementalMatchesReplay(t *testing.T) {
+	records := []ruleRecord{
+		food(1, 9, 8, models.FoodRecord{MealType: "早餐", FoodName: "燕麦粥", Sugar: 5}),
+		food(2, 8, 8, models.FoodRecord{MealType: "早餐", FoodName: "包子", Sugar: 3}),
+		food(3, 8, 19, models.FoodRecord{MealType: "晚餐", FoodName: "米饭", Sugar: 30}),
+		food(4, 6, 8, models.FoodRecord{MealType: "早餐", FoodName: "燕麦粥", Sugar: 6}),
+		food(5, 5, 12, models.FoodRecord{MealType: "午餐", FoodName: "面条", Sugar: 8}),
+		food(6, 5, 8, models.FoodRecord{MealType: "早餐", FoodName: "油条", Sugar: 2}),
+		food(7, 4, 8, models.FoodRecord{MealType: "早餐", FoodName: "包子", Sugar: 4}),
+		food(8, 3, 21, models.FoodRecord{MealType: "加餐", FoodName: "蛋糕", Sugar: 40}),
+		food(9, 2, 8, models.FoodRecord{MealType: "早餐", FoodName: "燕麦粥", Sugar: 5}),
+		food(10, 1, 8, models.FoodRecord{MealType: "早餐", FoodName: "豆浆", Sugar: 9}),
+		food(11, 1, 12, models.FoodRecord{MealType: "午餐", FoodName: "米饭", Sugar: 1}),
+		food(12, 0, 8, models.FoodRecord{MealType: "早餐", FoodName: "包子", Sugar: 3}),
+	}
+	breakfast := []models.RuleFilter{{Field: "meal_type", Op: "==", Value: "早餐"}}
+
+	defs := []models.RuleDefinition{
+		{Type: models.RuleTypeCount, Filters: breakfast},
+		{Type: models.RuleTypeCount, WindowDays: 5},
+		{Type: models.RuleTypeDistinctDays},
+		{Type: models.RuleTypeDistinctDays, MinPerDay: 2},
+		{Type: models.RuleTypeDistinctDays, WindowDays: 7, MinPerDay: 2},
+		{Type: models.RuleTypeStreak, Filters: breakfast},
+		{Type: models.RuleTypeStreak, MinPerDay: 2},
+		{Type: models.RuleTypeReboundStreak, Filters: breakfast},
+		{Type: models.RuleTypeDistinctValues, Field: "food_name"},
+		{Type: models.RuleTypeAbsenceStreak, Filters: []models.RuleFilter{{Field: "sugar", Op: ">", Value: float64(25)}}},
+	}
+
+	// 记录的到达顺序，值为 records 的下标
+	orders := []struct {
+		name  string
+		order []int
+	}{
+		{"按时间顺序", []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+		{"同一天内先到较晚的记录", []int{0, 2, 1, 3, 4, 5, 6, 7, 8, 10, 9, 11}},
+		{"补录前几天的记录", []int{0, 1, 2, 3, 6, 7, 8, 4, 5, 9, 10, 11}},
+		{"倒序到达", []int{11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0}},
+	}
+
+	for i := range defs {
+		def := &defs[i]
+		def.Source = models.RuleSourceFoodRecord
+		def.Target = 1
+		for _, o := range orders {
+			t.Run(fmt.Sprintf("%s-%d/%s", def.Type, i, o.name), func(t *testing.T) {
+				var state models.RuleState
+				var arrived []ruleRecord
+				for _, idx := range o.order {
+					// 到达顺序即 ID 顺序，重新计算时按时间排序
+					r := records[idx]
+					arrived = append(arrived, r)
+					if !applyRecord(&state, def, &r, testNow) {
+						state, _ = replay(arrived, def, testNow)
+					}
+				}
+
+				full, _ := replay(records, def, testNow)
+				if !reflect.DeepEqual(state, full) {
+					t.Errorf("state = %+v, want %+v", state, full)
+				}
+				got, want := stateResult(&state, def, testNow), stateResult(&full, def, testNow)
+				if got != want {
+					t.Errorf("result = %+v, want %+v", got, want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Package calendar 按用户时区计算自然日、周、月的边界和连续天数
+//
+// 所有函数都以日历日期而不是固定的24小时计算，夏令时切换当天（23或25小时）也能得到正确的结果。
+package calendar
+
+import (
+	"sort"
+	"time"
+
+	// 内置时区数据库，容器镜像中缺少 zoneinfo 时也能加载用户配置的时区
+	_ "time/tzdata"
+)
+
+// DateLayout 日期格式，也用作按天统计时的键
+const DateLayout = "2006-01-02"
+
+// Midnight 返回 loc 时区中指定日期的第一个时刻，日期会按 time.Date 的规则规范化
+// 部分时区（如 America/Santiago）的夏令时在午夜开始，当天不存在 00:00，此时返回切换后的时刻
+func Midnight(year int, month time.Month, day int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	wy, wm, wd := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Date()
+	if y, m, d := t.Date(); y != wy || m != wm || d != wd {
+		// time.Date 对不存在的时刻使用切换前的时差，结果落在前一天，切换时刻才是当天的开始
+		_, t = t.ZoneBounds()
+	}
+	return t
+}
+
+// StartOfDay 返回 t 在 loc 时区所在自然日的开始时间
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return Midnight(y, m, d, loc)
+}
+
+// DayRange 返回 t 在 loc 时区所在自然日的时间范围 [start, end)
+func DayRange(t time.Time, loc *time.Location) (start, end time.Time) {
+	y, m, d := t.In(loc).Date()
+	return Midnight(y, m, d, loc), Midnight(y, m, d+1, loc)
+}
+
+// LastNDays 返回包含 t 所在自然日在内的最近 n 天的时间范围 [start, end)
+func LastNDays(t time.Time, n int, loc *time.Location) (start, end time.Time) {
+	if n < 1 {
+		n = 1
+	}
+	y, m, d := t.In(loc).Date()
+	return Midnight(y, m, d-(n-1), loc), Midnight(y, m, d+1, loc)
+}
+
+// WeekRange 返回 t 在 loc 时区所在自然周（周一开始）的时间范围 [start, end)
+func WeekRange(t time.Time, loc *time.Location) (start, end time.Time) {
+	local := t.In(loc)
+	// 周一为0，周日为6
+	offset := (int(local.Weekday()) + 6) % 7
+	y, m, d := local.Date()
+	return Midnight(y, m, d-offset, loc), Midnight(y, m, d-offset+7, loc)
+}
+
+// MonthRange 返回 loc 时区中指定月份的时间范围 [start, end)
+func MonthRange(year int, month time.Month, loc *time.Location) (start, end time.Time) {
+	return Midnight(year, month, 1, loc), Midnight(year, month+1, 1, loc)
+}
+
+// DateKey 返回 t 在 loc 时区的日期，格式为 YYYY-MM-DD
+func DateKey(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(DateLayout)
+}
+
+// ParseDate 将 YYYY-MM-DD 解析为 loc 时区当天的开始时间
+func ParseDate(s string, loc *time.Location) (time.Time, error) {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return Midnight(t.Year(), t.Month(), t.Day(), loc), nil
+}
+
+// AddDays 返回日期 key 之后第 n 天的日期，key 无效时原样返回
+func AddDays(key string, n int) string {
+	t, err := time.Parse(DateLayout, key)
+	if err != nil {
+		return key
+	}
+	return t.AddDate(0, 0, n).Format(DateLayout)
+}
+
+// DaysBetween 返回两个日期之间相差的自然日数（to - from），任一日期无效时返回0
+func DaysBetween(from, to string) int {
+	a, err1 := time.Parse(DateLayout, from)
+	b, err2 := time.Parse(DateLayout, to)
+	if err1 != nil || err2 != nil {
+		return 0
+	}
+	// 两个日期都按UTC解析，不受夏令时影响
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// UniqueDays 将一组时间转换为 loc 时区下去重、升序的日期列表
+func UniqueDays(times []time.Time, loc *time.Location) []string {
+	seen := make(map[string]bool, len(times))
+	days := make([]string, 0, len(times))
+	for _, t := range times {
+		key := DateKey(t, loc)
+		if !seen[key] {
+			seen[key] = true
+			days = append(days, key)
+		}
+	}
+	sort.Strings(days)
+	return days
+}
+
+// LongestStreak 返回升序日期列表中最长的连续天数
+func LongestStreak(days []string) int {
+	longest, current := 0, 0
+	for i, day := range days {
+		if i > 0 && DaysBetween(days[i-1], day) == 1 {
+			current++
+		} else if i == 0 || days[i-1] != day {
+			current = 1
+		}
+		if current > longest {
+			longest = current
+		}
+	}
+	return longest
+}
+
+// CurrentStreak 返回截至 today 仍在延续的连续天数
+// 今天还没有记录时，从昨天开始往前计算，避免一早醒来连续天数就被清零
+func CurrentStreak(days []string, today string) int {
+	if len(days) == 0 {
+		return 0
+	}
+
+	last := days[len(days)-1]
+	gap := DaysBetween(last, today)
+	if gap > 1 {
+		return 0
+	}
+
+	streak := 1
+	for i := len(days) - 1; i > 0; i-- {
+		diff := DaysBetween(days[i-1], days[i])
+		if diff == 0 {
+			continue
+		}
+		if diff != 1 {
+			break
+		}
+		streak++
+	}
+	return streak
+}
@@ -0,0 +1,239 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("加载时区 %s 失败: %v", name, err)
+	}
+	return loc
+}
+
+func TestDayRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		zone      string
+		instant   time.Time
+		wantStart string
+		wantHours float64
+	}{
+		{"上海普通日", "Asia/Shanghai", time.Date(2026, 3, 8, 17, 30, 0, 0, time.UTC), "2026-03-09T00:00:00+08:00", 24},
+		{"纽约夏令时开始", "America/New_York", time.Date(2026, 3, 8, 12, 0, 0, 0, time.UTC), "2026-03-08T00:00:00-05:00", 23},
+		{"纽约夏令时结束", "America/New_York", time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC), "2026-11-01T00:00:00-04:00", 25},
+		{"伦敦夏令时开始", "Europe/London", time.Date(2026, 3, 29, 0, 30, 0, 0, time.UTC), "2026-03-29T00:00:00Z", 23},
+		{"悉尼夏令时结束", "Australia/Sydney", time.Date(2026, 4, 5, 1, 0, 0, 0, time.UTC), "2026-04-05T00:00:00+11:00", 25},
+		{"圣地亚哥午夜跳过", "America/Santiago", time.Date(2026, 9, 6, 15, 0, 0, 0, time.UTC), "2026-09-06T01:00:00-03:00", 23},
+		{"UTC午夜前属于前一天", "America/Los_Angeles", time.Date(2026, 6, 1, 6, 59, 0, 0, time.UTC), "2026-05-31T00:00:00-07:00", 24},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := mustLoad(t, tt.zone)
+			start, end := DayRange(tt.instant, loc)
+			if got := start.Format(time.RFC3339); got != tt.wantStart {
+				t.Errorf("start = %s, want %s", got, tt.wantStart)
+			}
+			if got := end.Sub(start).Hours(); got != tt.wantHours {
+				t.Errorf("day length = %v hours, want %v", got, tt.wantHours)
+			}
+			if tt.instant.Before(start) || !tt.instant.Before(end) {
+				t.Errorf("instant %s not in [%s, %s)", tt.instant, start, end)
+			}
+			if !StartOfDay(tt.instant, loc).Equal(start) {
+				t.Errorf("StartOfDay = %s, want %s", StartOfDay(tt.instant, loc), start)
+			}
+		})
+	}
+}
+
+func TestDateKey(t *testing.T) {
+	tests := []struct {
+		zone    string
+		instant time.Time
+		want    string
+	}{
+		{"Asia/Shanghai", time.Date(2026, 1, 1, 16, 0, 0, 0, time.UTC), "2026-01-02"},
+		{"Asia/Shanghai", time.Date(2026, 1, 1, 15, 59, 59, 0, time.UTC), "2026-01-01"},
+		{"America/New_York", time.Date(2026, 11, 1, 4, 30, 0, 0, time.UTC), "2026-11-01"},
+		{"America/New_York", time.Date(2026, 11, 2, 4, 59, 0, 0, time.UTC), "2026-11-01"},
+		{"Pacific/Auckland", time.Date(2026, 9, 26, 14, 0, 0, 0, time.UTC), "2026-09-27"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.zone+"/"+tt.want, func(t *testing.T) {
+			if got := DateKey(tt.instant, mustLoad(t, tt.zone)); got != tt.want {
+				t.Errorf("DateKey = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeekRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		zone      string
+		instant   time.Time
+		wantStart string
+		wantEnd   string
+	}{
+		{"周日属于上一周", "Asia/Shanghai", time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC), "2026-03-09", "2026-03-16"},
+		{"周一", "Asia/Shanghai", time.Date(2026, 3, 16, 1, 0, 0, 0, time.UTC), "2026-03-16", "2026-03-23"},
+		{"跨夏令时的一周", "America/New_York", time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC), "2026-03-09", "2026-03-16"},
+		{"包含夏令时切换日", "Europe/Berlin", time.Date(2026, 3, 29, 12, 0, 0, 0, time.UTC), "2026-03-23", "2026-03-30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := mustLoad(t, tt.zone)
+			start, end := WeekRange(tt.instant, loc)
+			if got := DateKey(start, loc); got != tt.wantStart {
+				t.Errorf("start = %s, want %s", got, tt.wantStart)
+			}
+			if got := DateKey(end, loc); got != tt.wantEnd {
+				t.Errorf("end = %s, want %s", got, tt.wantEnd)
+			}
+			if start.In(loc).Hour() != 0 || end.In(loc).Hour() != 0 {
+				t.Errorf("week boundaries not at midnight: %s, %s", start, end)
+			}
+		})
+	}
+}
+
+func TestLastNDays(t *testing.T) {
+	tests := []struct {
+		name      string
+		zone      string
+		instant   time.Time
+		n         int
+		wantStart string
+		wantHours float64
+	}{
+		{"最近7天", "Asia/Shanghai", time.Date(2026, 3, 20, 4, 0, 0, 0, time.UTC), 7, "2026-03-14", 7 * 24},
+		{"跨夏令时开始", "America/New_York", time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC), 7, "2026-03-04", 7*24 - 1},
+		{"跨夏令时结束", "Australia/Sydney", time.Date(2026, 4, 7, 0, 0, 0, 0, time.UTC), 3, "2026-04-05", 3*24 + 1},
+		{"n小于1按1天计算", "Europe/London", time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC), 0, "2026-07-01", 24},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := mustLoad(t, tt.zone)
+			start, end := LastNDays(tt.instant, tt.n, loc)
+			if got := DateKey(start, loc); got != tt.wantStart {
+				t.Errorf("start = %s, want %s", got, tt.wantStart)
+			}
+			if got := end.Sub(start).Hours(); got != tt.wantHours {
+				t.Errorf("window = %v hours, want %v", got, tt.wantHours)
+			}
+		})
+	}
+}
+
+func TestMonthRange(t *testing.T) {
+	loc := mustLoad(t, "America/New_York")
+	start, end := MonthRange(2026, time.March, loc)
+	if got := start.Format(time.RFC3339); got != "2026-03-01T00:00:00-05:00" {
+		t.Errorf("start = %s", got)
+	}
+	if got := end.Format(time.RFC3339); got != "2026-04-01T00:00:00-04:00" {
+		t.Errorf("end = %s", got)
+	}
+
+	start, end = MonthRange(2026, time.December, loc)
+	if DateKey(start, loc) != "2026-12-01" || DateKey(end, loc) != "2027-01-01" {
+		t.Errorf("December range = %s - %s", start, end)
+	}
+}
+
+func TestDaysBetween(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     int
+	}{
+		{"2026-03-07", "2026-03-09", 2}, // 纽约夏令时开始前后
+		{"2026-10-31", "2026-11-02", 2}, // 纽约夏令时结束前后
+		{"2026-03-09", "2026-03-07", -2},
+		{"2024-02-28", "2024-03-01", 2},
+		{"2026-12-31", "2027-01-01", 1},
+		{"bad", "2026-01-01", 0},
+	}
+
+	for _, tt := range tests {
+		if got := DaysBetween(tt.from, tt.to); got != tt.want {
+			t.Errorf("DaysBetween(%s, %s) = %d, want %d", tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	if got := AddDays("2026-03-08", 1); got != "2026-03-09" {
+		t.Errorf("AddDays = %s", got)
+	}
+}
+
+func TestUniqueDays(t *testing.T) {
+	loc := mustLoad(t, "America/New_York")
+	times := []time.Time{
+		// 夏令时结束当天凌晨重复出现的 1:30，属于同一天
+		time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC),
+		time.Date(2026, 11, 1, 6, 30, 0, 0, time.UTC),
+		time.Date(2026, 10, 31, 3, 0, 0, 0, time.UTC), // 纽约时间 10月30日
+		time.Date(2026, 11, 2, 4, 59, 0, 0, time.UTC), // 纽约时间 11月1日 23:59
+	}
+
+	got := UniqueDays(times, loc)
+	want := []string{"2026-10-30", "2026-11-01"}
+	if len(got) != len(want) {
+		t.Fatalf("UniqueDays = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("UniqueDays = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestStreaks(t *testing.T) {
+	tests := []struct {
+		name        string
+		days        []string
+		today       string
+		wantLongest int
+		wantCurrent int
+	}{
+		{"空", nil, "2026-03-10", 0, 0},
+		{"延续到今天", []string{"2026-03-07", "2026-03-08", "2026-03-09", "2026-03-10"}, "2026-03-10", 4, 4},
+		{"今天还没打卡", []string{"2026-03-07", "2026-03-08", "2026-03-09"}, "2026-03-10", 3, 3},
+		{"已经中断", []string{"2026-03-05", "2026-03-06", "2026-03-07"}, "2026-03-10", 3, 0},
+		{"中断后重新开始", []string{"2026-03-01", "2026-03-02", "2026-03-03", "2026-03-09", "2026-03-10"}, "2026-03-10", 3, 2},
+		{"重复日期", []string{"2026-03-09", "2026-03-09", "2026-03-10"}, "2026-03-10", 2, 2},
+		{"跨月份和夏令时", []string{"2026-02-28", "2026-03-01", "2026-03-07", "2026-03-08", "2026-03-09"}, "2026-03-09", 3, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LongestStreak(tt.days); got != tt.wantLongest {
+				t.Errorf("LongestStreak = %d, want %d", got, tt.wantLongest)
+			}
+			if got := CurrentStreak(tt.days, tt.today); got != tt.wantCurrent {
+				t.Errorf("CurrentStreak = %d, want %d", got, tt.wantCurrent)
+			}
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	loc := mustLoad(t, "Europe/London")
+	got, err := ParseDate("2026-03-29", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Format(time.RFC3339) != "2026-03-29T00:00:00Z" {
+		t.Errorf("ParseDate = %s", got.Format(time.RFC3339))
+	}
+	if _, err := ParseDate("2026/03/29", loc); err == nil {
+		t.Error("expected error for invalid date")
+	}
+}
@@ -0,0 +1,98 @@
+package catalog
+
+import (
+	"archive/zip"
+	"backend/models"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// 导出格式
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// Export 将所有物品导出为与导入相同格式的 ZIP 包，本地静态文件会一并打包
+func Export(w io.Writer, format string) error {
+	if format != FormatJSON && format != FormatCSV {
+		return fmt.Errorf("不支持的导出格式: %s", format)
+	}
+
+	items, err := models.GetAllItemsForExport()
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	manifest := &Manifest{Version: ManifestVersion, Items: make([]Entry, 0, len(items))}
+	for i := range items {
+		entry := entryFromItem(&items[i])
+		if entry.Icon, err = exportAsset(zw, items[i].IconURL, "icons", entry.Key); err != nil {
+			return err
+		}
+		if entry.Image, err = exportAsset(zw, items[i].ImageURL, "images", entry.Key); err != nil {
+			return err
+		}
+		manifest.Items = append(manifest.Items, entry)
+	}
+
+	if format == FormatCSV {
+		mw, err := zw.Create(ManifestCSV)
+		if err != nil {
+			return err
+		}
+		err = writeCSVManifest(mw, manifest)
+		if err != nil {
+			return err
+		}
+	} else {
+		mw, err := zw.Create(ManifestJSON)
+		if err != nil {
+			return err
+		}
+		if err := writeJSONManifest(mw, manifest); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// exportAsset 将本地静态文件写入导出包并返回包内路径，外部地址或文件不存在时原样返回地址
+func exportAsset(zw *zip.Writer, url, dir, key string) (string, error) {
+	local, ok := localStaticPath(url)
+	if !ok {
+		return url, nil
+	}
+	f, err := os.Open(local)
+	if err != nil {
+		return url, nil
+	}
+	defer f.Close()
+
+	name := path.Join(dir, key+strings.ToLower(filepath.Ext(local)))
+	fw, err := zw.Create(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(fw, f); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// localStaticPath 将 /static/ 开头的地址转换为本地文件路径
+func localStaticPath(url string) (string, bool) {
+	if !strings.HasPrefix(url, "/static/") {
+		return "", false
+	}
+	cleaned := filepath.Clean(strings.TrimPrefix(url, "/"))
+	if !strings.HasPrefix(cleaned, "static"+string(filepath.Separator)) {
+		return "", false
+	}
+	return cleaned, true
+}
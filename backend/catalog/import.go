@@ -0,0 +1,307 @@
+package catalog
+
+import (
+	"archive/zip"
+	"backend/effect"
+	"backend/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// 导入包的限制
+const (
+	MaxAssetSize    = 2 << 20 // 单个图标或图片的最大大小，与图片上传一致
+	MaxManifestSize = 5 << 20 // 清单文件的最大大小
+	MaxItems        = 1000    // 单次导入的最大物品数量
+)
+
+// AssetDir 导入的图标和图片保存目录
+const AssetDir = "static/items"
+
+// ErrInvalidPackage 导入包校验未通过，具体问题见 ImportResult.Problems
+var ErrInvalidPackage = errors.New("导入包校验未通过")
+
+var allowedAssetExts = map[string]bool{
+	".png":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".gif":  true,
+}
+
+// Problem 导入包中的一个问题，Row 为清单中的物品序号（从 1 开始），0 表示整个导入包
+type Problem struct {
+	Row     int    `json:"row"`
+	Key     string `json:"key,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportResult 导入结果
+type ImportResult struct {
+	DryRun   bool          `json:"dry_run"`
+	Created  int           `json:"created"`
+	Updated  int           `json:"updated"`
+	Items    []models.Item `json:"items"`
+	Problems []Problem     `json:"problems"`
+}
+
+// importPackage 解压后的导入包
+type importPackage struct {
+	files map[string]*zip.File // 以包内路径为键
+	base  string               // 清单所在目录，资源路径相对于该目录
+}
+
+// pendingAsset 待写入的资源文件
+type pendingAsset struct {
+	file   *zip.File
+	target *string // 写入后更新的物品字段
+	key    string
+	kind   string
+}
+
+// Import 校验 ZIP 导入包并按标识创建或更新物品
+// 包内存在问题时不写入任何数据，返回 ErrInvalidPackage；dryRun 为 true 时只校验不写入
+func Import(r io.ReaderAt, size int64, dryRun bool) (*ImportResult, error) {
+	result := &ImportResult{DryRun: dryRun, Problems: []Problem{}}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		result.Problems = append(result.Problems, Problem{Message: "无法读取 ZIP 文件"})
+		return result, ErrInvalidPackage
+	}
+	pkg := newImportPackage(zr)
+
+	manifest, err := pkg.readManifest()
+	if err != nil {
+		result.Problems = append(result.Problems, Problem{Message: err.Error()})
+		return result, ErrInvalidPackage
+	}
+	if len(manifest.Items) == 0 {
+		result.Problems = append(result.Problems, Problem{Message: "清单中没有物品"})
+		return result, ErrInvalidPackage
+	}
+	if len(manifest.Items) > MaxItems {
+		result.Problems = append(result.Problems, Problem{Message: fmt.Sprintf("单次最多导入 %d 个物品", MaxItems)})
+		return result, ErrInvalidPackage
+	}
+
+	items := make([]models.Item, len(manifest.Items))
+	var assets []pendingAsset
+	seenKeys := make(map[string]int)
+	seenNames := make(map[string]int)
+	for i := range manifest.Items {
+		entry := &manifest.Items[i]
+		row := i + 1
+		addProblem := func(format string, args ...interface{}) {
+			result.Problems = append(result.Problems, Problem{Row: row, Key: entry.Key, Message: fmt.Sprintf(format, args...)})
+		}
+
+		entry.Key = strings.TrimSpace(entry.Key)
+		entry.Name = strings.TrimSpace(entry.Name)
+		if entry.Key == "" {
+			addProblem("缺少物品标识")
+		} else if prev, ok := seenKeys[entry.Key]; ok {
+			addProblem("物品标识与第%d个物品重复", prev)
+		} else {
+			seenKeys[entry.Key] = row
+		}
+		if entry.Name == "" {
+			addProblem("缺少物品名称")
+		} else if prev, ok := seenNames[entry.Name]; ok {
+			addProblem("物品名称与第%d个物品重复", prev)
+		} else {
+			seenNames[entry.Name] = row
+		}
+
+		items[i] = entry.toItem()
+		item := &items[i]
+		if entry.Key != "" {
+			if err := models.ValidateItem(item); err != nil {
+				addProblem("%s", err.Error())
+			}
+		}
+		if item.Effect != "" && !effect.Registered(item.Effect) {
+			addProblem("未知的物品效果: %s", item.Effect)
+		}
+
+		for _, ref := range []struct {
+			kind  string
+			value string
+			dest  *string
+		}{
+			{"icon", entry.Icon, &item.IconURL},
+			{"image", entry.Image, &item.ImageURL},
+		} {
+			if ref.value == "" || isExternalURL(ref.value) {
+				*ref.dest = ref.value
+				continue
+			}
+			file, err := pkg.asset(ref.value)
+			if err != nil {
+				addProblem("%s: %v", ref.kind, err)
+				continue
+			}
+			assets = append(assets, pendingAsset{file: file, target: ref.dest, key: entry.Key, kind: ref.kind})
+		}
+	}
+
+	if len(result.Problems) > 0 {
+		return result, ErrInvalidPackage
+	}
+	if dryRun {
+		result.Items = items
+		return result, nil
+	}
+
+	written, err := saveAssets(assets)
+	if err != nil {
+		removeFiles(written)
+		return nil, err
+	}
+
+	result.Created, result.Updated, err = models.SaveItemsByKey(items)
+	if err != nil {
+		removeFiles(written)
+		return nil, err
+	}
+	result.Items = items
+	return result, nil
+}
+
+func newImportPackage(zr *zip.Reader) *importPackage {
+	pkg := &importPackage{files: make(map[string]*zip.File)}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		pkg.files[path.Clean(f.Name)] = f
+	}
+
+	// 清单不在根目录时，允许整个导入包被打包在一个顶层目录中
+	if !pkg.has(ManifestJSON) && !pkg.has(ManifestCSV) {
+		for name := range pkg.files {
+			dir, file := path.Split(name)
+			if (file == ManifestJSON || file == ManifestCSV) && strings.Count(dir, "/") == 1 {
+				pkg.base = strings.TrimSuffix(dir, "/")
+				break
+			}
+		}
+	}
+	return pkg
+}
+
+func (p *importPackage) has(name string) bool {
+	_, ok := p.files[path.Join(p.base, name)]
+	return ok
+}
+
+func (p *importPackage) readManifest() (*Manifest, error) {
+	name := ManifestJSON
+	parse := parseJSONManifest
+	if !p.has(ManifestJSON) {
+		if !p.has(ManifestCSV) {
+			return nil, fmt.Errorf("导入包中缺少 %s 或 %s", ManifestJSON, ManifestCSV)
+		}
+		name = ManifestCSV
+		parse = parseCSVManifest
+	}
+
+	f := p.files[path.Join(p.base, name)]
+	if f.UncompressedSize64 > MaxManifestSize {
+		return nil, fmt.Errorf("%s 超过 %dMB", name, MaxManifestSize>>20)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败", name)
+	}
+	defer rc.Close()
+	return parse(io.LimitReader(rc, MaxManifestSize))
+}
+
+// asset 按清单中的相对路径查找资源文件并校验类型和大小
+func (p *importPackage) asset(ref string) (*zip.File, error) {
+	cleaned := path.Clean(strings.TrimPrefix(ref, "./"))
+	if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return nil, fmt.Errorf("无效的文件路径 %s", ref)
+	}
+	f, ok := p.files[path.Join(p.base, cleaned)]
+	if !ok {
+		return nil, fmt.Errorf("导入包中不存在文件 %s", ref)
+	}
+	if !allowedAssetExts[strings.ToLower(path.Ext(cleaned))] {
+		return nil, fmt.Errorf("不支持的图片格式 %s", ref)
+	}
+	if f.UncompressedSize64 > MaxAssetSize {
+		return nil, fmt.Errorf("文件 %s 超过 %dMB", ref, MaxAssetSize>>20)
+	}
+	return f, nil
+}
+
+// saveAssets 将资源文件写入静态目录并更新物品的地址，返回本次新写入的文件
+// 文件名包含内容摘要，内容相同的文件不会重复写入
+func saveAssets(assets []pendingAsset) ([]string, error) {
+	var written []string
+	if len(assets) == 0 {
+		return written, nil
+	}
+	if err := os.MkdirAll(AssetDir, 0755); err != nil {
+		return written, fmt.Errorf("创建目录失败: %v", err)
+	}
+
+	for _, asset := range assets {
+		data, err := readZipFile(asset.file, MaxAssetSize)
+		if err != nil {
+			return written, err
+		}
+		sum := sha256.Sum256(data)
+		ext := strings.ToLower(path.Ext(asset.file.Name))
+		filename := fmt.Sprintf("%s-%s-%s%s", asset.key, asset.kind, hex.EncodeToString(sum[:])[:12], ext)
+		target := filepath.Join(AssetDir, filename)
+
+		if _, err := os.Stat(target); os.IsNotExist(err) {
+			if err := os.WriteFile(target, data, 0644); err != nil {
+				return written, fmt.Errorf("保存文件失败: %v", err)
+			}
+			written = append(written, target)
+		}
+		*asset.target = "/" + filepath.ToSlash(target)
+	}
+	return written, nil
+}
+
+func readZipFile(f *zip.File, limit int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("读取文件 %s 失败", f.Name)
+	}
+	defer rc.Close()
+
+	// 不信任 ZIP 头中声明的大小，读取时再次限制
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取文件 %s 失败", f.Name)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("文件 %s 超过大小限制", f.Name)
+	}
+	return data, nil
+}
+
+func removeFiles(files []string) {
+	for _, f := range files {
+		os.Remove(f)
+	}
+}
+
+// isExternalURL 判断清单中的地址是否引用已有的静态文件或外部地址，这类地址原样保存
+func isExternalURL(ref string) bool {
+	return strings.HasPrefix(ref, "/static/") ||
+		strings.HasPrefix(ref, "http://") ||
+		strings.HasPrefix(ref, "https://")
+}
@@ -0,0 +1,194 @@
+// Package catalog 处理物品目录的批量导入导出
+// 导入包为 ZIP：根目录下的 manifest.json 或 manifest.csv 描述物品，图标和图片放在包内并在清单中以相对路径引用
+package catalog
+
+import (
+	"backend/models"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// 清单文件名和版本
+const (
+	ManifestJSON    = "manifest.json"
+	ManifestCSV     = "manifest.csv"
+	ManifestVersion = 1
+)
+
+// Entry 清单中的一个物品
+type Entry struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Source      string `json:"source"`
+	Rarity      string `json:"rarity"`
+	Category    string `json:"category"`
+	MaxStack    int    `json:"max_stack"`
+	Effect      string `json:"effect"`
+	EffectValue int    `json:"effect_value"`
+	Tradable    bool   `json:"tradable"`
+	Icon        string `json:"icon"`  // 包内相对路径，或 /static/ 开头的已有地址
+	Image       string `json:"image"` // 同上
+}
+
+// Manifest 清单
+type Manifest struct {
+	Version int     `json:"version"`
+	Items   []Entry `json:"items"`
+}
+
+// csvColumns CSV 清单的列，顺序即导出时的列顺序
+var csvColumns = []string{
+	"key", "name", "description", "source", "rarity", "category",
+	"max_stack", "effect", "effect_value", "tradable", "icon", "image",
+}
+
+// toItem 将清单条目转换为物品，图标和图片地址由调用方填写
+func (e *Entry) toItem() models.Item {
+	key := e.Key
+	return models.Item{
+		Key:         &key,
+		Name:        e.Name,
+		Description: e.Description,
+		Source:      e.Source,
+		Rarity:      e.Rarity,
+		Category:    e.Category,
+		MaxStack:    e.MaxStack,
+		Effect:      e.Effect,
+		EffectValue: e.EffectValue,
+		Tradable:    e.Tradable,
+	}
+}
+
+// entryFromItem 将物品转换为清单条目，图标和图片地址由调用方填写
+func entryFromItem(item *models.Item) Entry {
+	return Entry{
+		Key:         itemKey(item),
+		Name:        item.Name,
+		Description: item.Description,
+		Source:      item.Source,
+		Rarity:      item.Rarity,
+		Category:    item.Category,
+		MaxStack:    item.MaxStack,
+		Effect:      item.Effect,
+		EffectValue: item.EffectValue,
+		Tradable:    item.Tradable,
+	}
+}
+
+func itemKey(item *models.Item) string {
+	if item.Key != nil && *item.Key != "" {
+		return *item.Key
+	}
+	return fmt.Sprintf("item-%d", item.ID)
+}
+
+func parseJSONManifest(r io.Reader) (*Manifest, error) {
+	var manifest Manifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %v", ManifestJSON, err)
+	}
+	if manifest.Version == 0 {
+		manifest.Version = ManifestVersion
+	}
+	if manifest.Version != ManifestVersion {
+		return nil, fmt.Errorf("不支持的清单版本: %d", manifest.Version)
+	}
+	return &manifest, nil
+}
+
+func parseCSVManifest(r io.Reader) (*Manifest, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %v", ManifestCSV, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s 为空", ManifestCSV)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	if _, ok := columns["key"]; !ok {
+		return nil, fmt.Errorf("%s 缺少 key 列", ManifestCSV)
+	}
+
+	manifest := &Manifest{Version: ManifestVersion}
+	for line, row := range rows[1:] {
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		entry := Entry{
+			Key:         get("key"),
+			Name:        get("name"),
+			Description: get("description"),
+			Source:      get("source"),
+			Rarity:      get("rarity"),
+			Category:    get("category"),
+			Effect:      get("effect"),
+			Icon:        get("icon"),
+			Image:       get("image"),
+		}
+		if entry.MaxStack, err = parseInt(get("max_stack")); err != nil {
+			return nil, fmt.Errorf("第%d行 max_stack 无效", line+2)
+		}
+		if entry.EffectValue, err = parseInt(get("effect_value")); err != nil {
+			return nil, fmt.Errorf("第%d行 effect_value 无效", line+2)
+		}
+		if entry.Tradable, err = parseBool(get("tradable")); err != nil {
+			return nil, fmt.Errorf("第%d行 tradable 无效", line+2)
+		}
+		manifest.Items = append(manifest.Items, entry)
+	}
+	return manifest, nil
+}
+
+func writeJSONManifest(w io.Writer, manifest *Manifest) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(manifest)
+}
+
+func writeCSVManifest(w io.Writer, manifest *Manifest) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvColumns); err != nil {
+		return err
+	}
+	for _, e := range manifest.Items {
+		row := []string{
+			e.Key, e.Name, e.Description, e.Source, e.Rarity, e.Category,
+			strconv.Itoa(e.MaxStack), e.Effect, strconv.Itoa(e.EffectValue),
+			strconv.FormatBool(e.Tradable), e.Icon, e.Image,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func parseInt(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
+
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "", "0", "false", "否":
+		return false, nil
+	case "1", "true", "是":
+		return true, nil
+	}
+	return false, fmt.Errorf("无效的布尔值: %s", s)
+}
@@ -0,0 +1,211 @@
+// Package challenge 处理限时挑战的目标校验、进度计算、排行和完成奖励
+package challenge
+
+import (
+	"backend/achievement"
+	"backend/models"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// MaxTarget 挑战目标的上限
+	MaxTarget = 1000
+	// MaxRewardQuantity 完成奖励的物品数量上限
+	MaxRewardQuantity = 99
+	// MaxDuration 挑战的最长持续时间
+	MaxDuration = 366 * 24 * time.Hour
+)
+
+// ValidateGoal 校验挑战目标，count、distinct_days、streak 复用成就规则的校验
+func ValidateGoal(goal *models.ChallengeGoal) error {
+	if goal.Target <= 0 || goal.Target > MaxTarget {
+		return fmt.Errorf("目标应在1到%d之间", MaxTarget)
+	}
+
+	switch goal.Type {
+	case models.ChallengeGoalCount, models.ChallengeGoalDistinctDays, models.ChallengeGoalStreak:
+		if goal.Source != models.RuleSourceFoodRecord && goal.Source != models.RuleSourceCheckIn {
+			return fmt.Errorf("挑战只支持饮食记录和打卡数据: %s", goal.Source)
+		}
+		if goal.Nutrient != "" || goal.Direction != "" {
+			return fmt.Errorf("%s 目标不支持 nutrient 和 direction", goal.Type)
+		}
+		return achievement.ValidateDefinition(ruleDefinition(goal))
+	case models.ChallengeGoalTargetDays, models.ChallengeGoalTargetStreak:
+		if goal.Source == "" {
+			goal.Source = models.RuleSourceFoodRecord
+		}
+		if goal.Source != models.RuleSourceFoodRecord {
+			return fmt.Errorf("%s 目标只支持饮食记录", goal.Type)
+		}
+		if len(goal.Filters) > 0 || goal.MinPerDay != 0 {
+			return fmt.Errorf("%s 目标不支持 filters 和 min_per_day", goal.Type)
+		}
+		if _, ok := targetNutrients[goal.Nutrient]; !ok {
+			return fmt.Errorf("不支持与每日目标比较的营养素: %s", goal.Nutrient)
+		}
+		if goal.Direction != models.TargetDirectionUnder && goal.Direction != models.TargetDirectionReach {
+			return fmt.Errorf("direction 只能是 under 或 reach")
+		}
+		return nil
+	}
+	return fmt.Errorf("不支持的目标类型: %s", goal.Type)
+}
+
+// ValidatePeriod 校验挑战的起止时间
+func ValidatePeriod(start, end time.Time) error {
+	if start.IsZero() || end.IsZero() {
+		return errors.New("请设置开始和结束时间")
+	}
+	if !end.After(start) {
+		return errors.New("结束时间必须晚于开始时间")
+	}
+	if end.Sub(start) > MaxDuration {
+		return errors.New("挑战最长持续一年")
+	}
+	return nil
+}
+
+// Evaluate 根据挑战期间的记录计算用户的当前进度
+func Evaluate(userID uint, challenge *models.Challenge) (int, error) {
+	goal := &challenge.Goal
+	switch goal.Type {
+	case models.ChallengeGoalTargetDays, models.ChallengeGoalTargetStreak:
+		return evaluateTarget(userID, goal, challenge.StartAt, challenge.EndAt, time.Now())
+	}
+	result, err := achievement.EvaluatePeriod(userID, ruleDefinition(goal), challenge.StartAt, challenge.EndAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.Current, nil
+}
+
+// Refresh 重新计算用户在挑战中的进度，首次完成时发放奖励并通知用户
+// 完成状态先于奖励保存，奖励发放失败只记录日志并保留待发放状态，下次刷新或管理员重试时再发放，不影响挑战结算
+// 挑战尚未开始时不做任何操作
+func Refresh(userID uint, challenge *models.Challenge, now time.Time) error {
+	if now.Before(challenge.StartAt) {
+		return nil
+	}
+	progress, err := Evaluate(userID, challenge)
+	if err != nil {
+		return err
+	}
+
+	completed := progress >= challenge.Goal.Target
+	reward := rewardRequest(userID, challenge)
+	newlyCompleted, rewardPending, err := models.UpdateChallengeProgress(challenge.ID, userID, progress, completed, reward != nil, now)
+	if err != nil {
+		return err
+	}
+
+	rewarded := false
+	if rewardPending && reward != nil {
+		rewarded, err = models.GrantChallengeReward(challenge.ID, userID, *reward)
+		if err != nil {
+			fmt.Printf("发放用户 %d 在挑战 %d 的奖励失败: %v\n", userID, challenge.ID, err)
+		}
+	}
+	if newlyCompleted {
+		notifyCompleted(userID, challenge, rewarded)
+	}
+	return nil
+}
+
+// RetryRewards 重新发放挑战中所有待发放的奖励，返回发放成功的人数，用于挑战结算后管理员处理发放失败的奖励
+func RetryRewards(challenge *models.Challenge) (int, error) {
+	userIDs, err := models.GetPendingRewardParticipantIDs(challenge.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	granted := 0
+	var firstErr error
+	for _, userID := range userIDs {
+		reward := rewardRequest(userID, challenge)
+		if reward == nil {
+			break
+		}
+		ok, err := models.GrantChallengeReward(challenge.ID, userID, *reward)
+		if err != nil {
+			fmt.Printf("重新发放用户 %d 在挑战 %d 的奖励失败: %v\n", userID, challenge.ID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if ok {
+			granted++
+		}
+	}
+	return granted, firstErr
+}
+
+// RefreshUser 重新计算用户在所有进行中挑战的进度，用于记录变化后的实时更新
+func RefreshUser(userID uint) {
+	now := time.Now()
+	challenges, err := models.GetJoinedRunningChallenges(userID, now)
+	if err != nil {
+		fmt.Printf("获取用户 %d 参与的挑战失败: %v\n", userID, err)
+		return
+	}
+	for i := range challenges {
+		if challenges[i].Status(now) != models.ChallengeStatusActive {
+			continue
+		}
+		if err := Refresh(userID, &challenges[i], now); err != nil {
+			fmt.Printf("更新用户 %d 在挑战 %d 的进度失败: %v\n", userID, challenges[i].ID, err)
+		}
+	}
+}
+
+// ruleDefinition 将挑战目标转换为成就规则定义
+func ruleDefinition(goal *models.ChallengeGoal) *models.RuleDefinition {
+	return &models.RuleDefinition{
+		Type:      goal.Type,
+		Source:    goal.Source,
+		Filters:   goal.Filters,
+		MinPerDay: goal.MinPerDay,
+		Target:    goal.Target,
+	}
+}
+
+// rewardRequest 返回完成挑战的物品奖励，没有物品奖励时返回 nil
+// 来源标识保证同一挑战的奖励只会发放一次
+func rewardRequest(userID uint, challenge *models.Challenge) *models.GrantRequest {
+	if challenge.RewardItemID == nil {
+		return nil
+	}
+	return &models.GrantRequest{
+		UserID:    userID,
+		ItemID:    *challenge.RewardItemID,
+		Quantity:  challenge.RewardQuantity,
+		Source:    "challenge",
+		SourceRef: fmt.Sprintf("challenge:%d", challenge.ID),
+		Reason:    fmt.Sprintf("完成挑战：%s", challenge.Title),
+	}
+}
+
+// notifyCompleted 通知用户完成挑战，rewarded 表示奖励物品是否已经发放，失败只记录日志
+func notifyCompleted(userID uint, challenge *models.Challenge, rewarded bool) {
+	data := map[string]interface{}{"challenge_id": challenge.ID}
+	if rewarded {
+		data["item_id"] = *challenge.RewardItemID
+		data["quantity"] = challenge.RewardQuantity
+	} else if challenge.RewardItemID != nil {
+		data["reward_pending"] = true
+	}
+
+	notification := models.Notification{
+		UserID:  userID,
+		Type:    models.NotificationTypeChallengeCompleted,
+		Title:   fmt.Sprintf("完成挑战：%s", challenge.Title),
+		Content: challenge.Description,
+		Data:    data,
+	}
+	if err := models.CreateNotifications([]models.Notification{notification}); err != nil {
+		fmt.Printf("保存用户 %d 的挑战通知失败: %v\n", userID, err)
+	}
+}
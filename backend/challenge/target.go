@@ -0,0 +1,101 @@
+package challenge
+
+import (
+	"backend/calendar"
+	"backend/models"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// nutrientSpec 可与每日营养目标比较的营养素
+type nutrientSpec struct {
+	value  func(f *models.FoodRecord) float64
+	target func(t *models.NutritionTarget) float64
+}
+
+var targetNutrients = map[string]nutrientSpec{
+	"calories": {
+		func(f *models.FoodRecord) float64 { return f.Calories },
+		func(t *models.NutritionTarget) float64 { return t.Calories },
+	},
+	"protein": {
+		func(f *models.FoodRecord) float64 { return f.Protein },
+		func(t *models.NutritionTarget) float64 { return t.Protein },
+	},
+	"total_fat": {
+		func(f *models.FoodRecord) float64 { return f.TotalFat },
+		func(t *models.NutritionTarget) float64 { return t.TotalFat },
+	},
+	"carbohydrates": {
+		func(f *models.FoodRecord) float64 { return f.Carbohydrates },
+		func(t *models.NutritionTarget) float64 { return t.Carbohydrates },
+	},
+	"fiber": {
+		func(f *models.FoodRecord) float64 { return f.Fiber },
+		func(t *models.NutritionTarget) float64 { return t.Fiber },
+	},
+	"sugar": {
+		func(f *models.FoodRecord) float64 { return f.Sugar },
+		func(t *models.NutritionTarget) float64 { return t.Sugar },
+	},
+	"sodium": {
+		func(f *models.FoodRecord) float64 { return f.Sodium },
+		func(t *models.NutritionTarget) float64 { return t.Sodium },
+	},
+}
+
+// evaluateTarget 按天汇总挑战期间的饮食记录并与用户当前的每日营养目标比较
+// 只有记录了饮食的日子才参与比较；under 需要当天已经结束才算达标，避免一天刚开始就计入
+func evaluateTarget(userID uint, goal *models.ChallengeGoal, start, end, now time.Time) (int, error) {
+	spec, ok := targetNutrients[goal.Nutrient]
+	if !ok {
+		return 0, fmt.Errorf("不支持与每日目标比较的营养素: %s", goal.Nutrient)
+	}
+
+	profile, err := models.GetUserProfile(userID)
+	if err != nil {
+		return 0, fmt.Errorf("获取用户资料失败: %v", err)
+	}
+	loc := profile.Location()
+	target, err := models.GetUserNutritionTargets(userID)
+	if err != nil {
+		return 0, fmt.Errorf("获取营养目标失败: %v", err)
+	}
+
+	limit := end
+	if now.Before(limit) {
+		limit = now
+	}
+	records, err := models.GetUserFoodRecords(userID, start, limit.Add(-time.Nanosecond))
+	if err != nil {
+		return 0, fmt.Errorf("获取用户饮食记录失败: %v", err)
+	}
+
+	totals := make(map[string]float64)
+	for i := range records {
+		totals[calendar.DateKey(records[i].RecordTime, loc)] += spec.value(&records[i])
+	}
+
+	dailyTarget := spec.target(target)
+	lastDay := calendar.DateKey(limit, loc)
+	days := make([]string, 0, len(totals))
+	for day, total := range totals {
+		switch goal.Direction {
+		case models.TargetDirectionUnder:
+			if day < lastDay && total <= dailyTarget {
+				days = append(days, day)
+			}
+		case models.TargetDirectionReach:
+			if total >= dailyTarget {
+				days = append(days, day)
+			}
+		}
+	}
+	sort.Strings(days)
+
+	if goal.Type == models.ChallengeGoalTargetStreak {
+		return calendar.LongestStreak(days), nil
+	}
+	return len(days), nil
+}
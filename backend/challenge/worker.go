@@ -0,0 +1,68 @@
+package challenge
+
+import (
+	"backend/events"
+	"backend/models"
+	"fmt"
+	"time"
+)
+
+// RegisterEventHandlers 订阅饮食和打卡事件，实时更新用户在进行中挑战的进度
+func RegisterEventHandlers() {
+	for _, t := range []events.Type{
+		events.FoodRecordCreated,
+		events.FoodRecordUpdated,
+		events.FoodRecordDeleted,
+		events.CheckInCreated,
+	} {
+		events.Subscribe(t, func(e events.Event) {
+			RefreshUser(e.UserID)
+		})
+	}
+}
+
+// StartWorker 启动后台任务，定期重新计算所有进行中挑战的进度，并对已结束的挑战做最终结算
+// 按天与营养目标比较的挑战需要在一天结束后才能计入，不能只依赖记录事件
+func StartWorker(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			settle(time.Now())
+			<-ticker.C
+		}
+	}()
+}
+
+// settle 更新所有已开始且未结算挑战的参与者进度，已结束的挑战在更新后标记为已结算
+func settle(now time.Time) {
+	challenges, err := models.GetUnfinalizedChallenges(now)
+	if err != nil {
+		fmt.Printf("获取待结算的挑战失败: %v\n", err)
+		return
+	}
+
+	for i := range challenges {
+		challenge := &challenges[i]
+		userIDs, err := models.GetChallengeParticipantIDs(challenge.ID)
+		if err != nil {
+			fmt.Printf("获取挑战 %d 的参与者失败: %v\n", challenge.ID, err)
+			continue
+		}
+
+		failed := false
+		for _, userID := range userIDs {
+			if err := Refresh(userID, challenge, now); err != nil {
+				fmt.Printf("更新用户 %d 在挑战 %d 的进度失败: %v\n", userID, challenge.ID, err)
+				failed = true
+			}
+		}
+
+		// 有参与者更新失败时下次继续结算
+		if challenge.Status(now) == models.ChallengeStatusEnded && !failed {
+			if err := models.MarkChallengeFinalized(challenge.ID, now); err != nil {
+				fmt.Printf("标记挑战 %d 已结算失败: %v\n", challenge.ID, err)
+			}
+		}
+	}
+}
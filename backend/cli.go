@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"backend/achievement"
+	"backend/handlers"
+	"backend/models"
+)
+
+// runCommand 执行命令行子命令，返回 false 表示没有子命令需要执行
+func runCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	switch args[0] {
+	case "admin":
+		runAdminCommand(args[1:])
+	case "achievements":
+		runAchievementsCommand(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "未知命令: %s\n", args[0])
+		printUsage()
+		os.Exit(2)
+	}
+	return true
+}
+
+// runAdminCommand 处理 admin 子命令
+func runAdminCommand(args []string) {
+	if len(args) == 0 || args[0] != "create" {
+		printUsage()
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("admin create", flag.ExitOnError)
+	name := fs.String("name", "管理员", "管理员名称")
+	email := fs.String("email", os.Getenv("ADMIN_EMAIL"), "管理员邮箱")
+	password := fs.String("password", os.Getenv("ADMIN_PASSWORD"), "管理员密码（至少6位）")
+	force := fs.Bool("force", false, "邮箱已注册时将该账户提升为管理员并重置密码")
+	fs.Parse(args[1:])
+
+	// 只连接数据库，不检查管理员是否存在：该命令正是用来创建第一个管理员的
+	models.InitDB()
+
+	user, err := handlers.CreateAdminUser(*name, *email, *password, *force)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建管理员失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("管理员账户已就绪：%s (ID: %d)\n", user.Email, user.ID)
+}
+
+// runAchievementsCommand 处理 achievements 子命令
+func runAchievementsCommand(args []string) {
+	if len(args) == 0 || args[0] != "backfill" {
+		printUsage()
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("achievements backfill", flag.ExitOnError)
+	rules := fs.String("rules", "", "需要回溯的规则ID，逗号分隔，默认全部启用的规则")
+	dryRun := fs.Bool("dry-run", false, "试运行，只输出会达成的用户，不发放物品")
+	batch := fs.Int("batch", achievement.DefaultBackfillBatchSize, "每批处理的用户数")
+	resume := fs.Uint("resume", 0, "继续执行指定ID的回溯任务")
+	fs.Parse(args[1:])
+
+	// 只连接数据库：回溯使用服务运行时已创建的规则和物品，不检查管理员，避免 release 模式下没有管理员时直接退出
+	models.InitDB()
+
+	jobID := *resume
+	if jobID == 0 {
+		ruleIDs, err := parseIDList(*rules)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "无效的规则ID: %v\n", err)
+			os.Exit(2)
+		}
+		job, err := achievement.NewBackfillJob(ruleIDs, *dryRun, *batch, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "创建回溯任务失败: %v\n", err)
+			os.Exit(1)
+		}
+		jobID = job.ID
+		fmt.Printf("已创建回溯任务 %d，规则: %v，试运行: %v\n", job.ID, job.RuleIDs, job.DryRun)
+	}
+
+	// 收到中断信号后处理完当前批次再退出，之后可以用 -resume 继续
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	job, err := achievement.RunBackfill(ctx, jobID, func(job *models.BackfillJob) {
+		fmt.Printf("进度: %d/%d 个用户，达成 %d 次，已处理到用户ID %d\n",
+			job.ProcessedUsers, job.TotalUsers, job.Matched, job.LastUserID)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "回溯任务 %d 未完成: %v\n", jobID, err)
+		fmt.Fprintf(os.Stderr, "可以使用 backend achievements backfill -resume %d 继续执行\n", jobID)
+		os.Exit(1)
+	}
+
+	if job.DryRun {
+		printBackfillMatches(job.ID)
+	}
+	fmt.Printf("回溯任务 %d 已完成：处理 %d 个用户，达成 %d 次\n", job.ID, job.ProcessedUsers, job.Matched)
+}
+
+// printBackfillMatches 输出回溯任务中达成规则的用户
+func printBackfillMatches(jobID uint) {
+	const pageSize = 500
+	for page := 1; ; page++ {
+		matches, _, err := models.GetBackfillMatches(jobID, page, pageSize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "获取回溯结果失败: %v\n", err)
+			return
+		}
+		for _, m := range matches {
+			fmt.Printf("用户 %d 达成规则 %d（物品 %d）：%d/%d\n", m.UserID, m.RuleID, m.ItemID, m.Current, m.Target)
+		}
+		if len(matches) < pageSize {
+			return
+		}
+	}
+}
+
+// parseIDList 解析逗号分隔的ID列表
+func parseIDList(s string) ([]uint, error) {
+	var ids []uint
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "用法:")
+	fmt.Fprintln(os.Stderr, "  backend                       启动服务器")
+	fmt.Fprintln(os.Stderr, "  backend admin create -email <邮箱> -password <密码> [-name <名称>] [-force]")
+	fmt.Fprintln(os.Stderr, "  backend achievements backfill [-rules 1,2] [-dry-run] [-batch 100] [-resume <任务ID>]")
+}
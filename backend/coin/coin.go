@@ -0,0 +1,51 @@
+package coin
+
+import (
+	"backend/events"
+	"backend/models"
+	"fmt"
+)
+
+// CheckInReward 每次打卡奖励的金币
+const CheckInReward = 10
+
+// RegisterEventHandlers 订阅打卡事件，为用户发放打卡金币奖励
+func RegisterEventHandlers() {
+	events.Subscribe(events.CheckInCreated, handleCheckInCreated)
+}
+
+// handleCheckInCreated 为新打卡发放金币，补签消耗的是补签卡，不发放打卡奖励
+func handleCheckInCreated(e events.Event) {
+	checkIn, ok := e.Payload.(*models.CheckIn)
+	if !ok || checkIn.MakeUp {
+		return
+	}
+	if _, err := RewardCheckIn(checkIn); err != nil {
+		fmt.Printf("发放用户 %d 的打卡金币失败: %v\n", e.UserID, err)
+	}
+}
+
+// RewardCheckIn 发放打卡金币奖励，同一条打卡记录只奖励一次，重复调用返回 nil
+func RewardCheckIn(checkIn *models.CheckIn) (*models.CoinTransaction, error) {
+	return models.CreditCoins(models.CoinChange{
+		UserID:    checkIn.UserID,
+		Amount:    CheckInReward,
+		Type:      models.CoinTxCheckIn,
+		SourceRef: fmt.Sprintf("check_in:%d", checkIn.ID),
+		Reason:    "打卡奖励",
+	})
+}
+
+// RewardAchievement 发放成就金币奖励，规则未配置奖励时不做处理，同一规则只奖励一次
+func RewardAchievement(userID uint, rule *models.AchievementRule) (*models.CoinTransaction, error) {
+	if rule.CoinReward <= 0 {
+		return nil, nil
+	}
+	return models.CreditCoins(models.CoinChange{
+		UserID:    userID,
+		Amount:    rule.CoinReward,
+		Type:      models.CoinTxAchievement,
+		SourceRef: fmt.Sprintf("rule:%d", rule.ID),
+		Reason:    fmt.Sprintf("达成成就：%s", rule.Name),
+	})
+}
@@ -11,6 +11,11 @@ type Config struct {
 	DBUser     string
 	DBPassword string
 	DBName     string
+
+	// 首次启动时用于创建管理员账户
+	AdminName     string
+	AdminEmail    string
+	AdminPassword string
 }
 
 func GetConfig() *Config {
@@ -20,6 +25,10 @@ func GetConfig() *Config {
 		DBUser:     getEnvOrDefault("DB_USER", "root"),
 		DBPassword: getEnvOrDefault("DB_PASSWORD", ""),
 		DBName:     getEnvOrDefault("DB_NAME", "quant"),
+
+		AdminName:     getEnvOrDefault("ADMIN_NAME", "管理员"),
+		AdminEmail:    os.Getenv("ADMIN_EMAIL"),
+		AdminPassword: os.Getenv("ADMIN_PASSWORD"),
 	}
 }
 
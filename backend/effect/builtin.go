@@ -0,0 +1,67 @@
+package effect
+
+import (
+	"backend/models"
+	"backend/streak"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// 分析次数卡的物品标识和默认名称
+const (
+	AnalysisCreditItemKey  = "analysis-credit"
+	AnalysisCreditItemName = "分析次数卡"
+)
+
+// defaultItems 内置效果对应的系统物品（冻结卡由 streak 包创建）
+var defaultItems = []models.Item{
+	{
+		Key:         models.ItemKey(AnalysisCreditItemKey),
+		Name:        AnalysisCreditItemName,
+		Description: "使用后增加一次健康分析次数，在每日免费次数用完后消耗。",
+		Source:      "系统",
+		Rarity:      models.RarityUncommon,
+		Category:    models.CategoryConsumable,
+		Effect:      models.EffectAnalysisCredit,
+		EffectValue: 1,
+	},
+}
+
+// RegisterBuiltins 注册内置的物品使用效果
+func RegisterBuiltins() {
+	Register(models.EffectStreakFreeze, streakFreeze)
+	Register(models.EffectAnalysisCredit, analysisCredit)
+}
+
+// EnsureItems 确保内置效果对应的物品存在
+func EnsureItems() error {
+	for _, item := range defaultItems {
+		if err := models.EnsureItem(item); err != nil {
+			return fmt.Errorf("创建物品 %s 失败: %v", item.Name, err)
+		}
+	}
+	return nil
+}
+
+// streakFreeze 冻结连续打卡，参数 date 为要冻结的日期（默认昨天），每次只能使用一张
+func streakFreeze(tx *gorm.DB, use *Use) (interface{}, error) {
+	if use.Quantity != 1 {
+		return nil, fmt.Errorf("%w：冻结卡每次只能使用一张", ErrInvalidQuantity)
+	}
+	date, _ := use.Params["date"].(string)
+	return streak.FreezeWithTx(tx, use.UserID, use.Item.ID, date, use.Now)
+}
+
+// analysisCredit 增加健康分析次数，每张增加 EffectValue 次（至少1次）
+func analysisCredit(tx *gorm.DB, use *Use) (interface{}, error) {
+	perItem := use.Item.EffectValue
+	if perItem < 1 {
+		perItem = 1
+	}
+	credits, err := models.AddAnalysisCredits(tx, use.UserID, perItem*use.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]int{"added": perItem * use.Quantity, "credits": credits}, nil
+}
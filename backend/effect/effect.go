@@ -0,0 +1,117 @@
+// Package effect 维护消耗品的使用效果，使用物品时扣减数量并在同一事务中执行对应效果
+package effect
+
+import (
+	"backend/models"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotConsumable   = errors.New("该物品不是消耗品，无法使用")
+	ErrNoEffect        = errors.New("该物品没有可直接触发的使用效果")
+	ErrInvalidQuantity = errors.New("使用数量无效")
+)
+
+// Use 一次物品使用
+type Use struct {
+	UserID   uint
+	Item     *models.Item
+	Quantity int
+	Params   map[string]interface{} // 效果参数，如冻结卡的 date
+	Now      time.Time
+}
+
+// Handler 效果处理函数，在扣减物品的事务中执行，返回错误时整个使用回滚
+type Handler func(tx *gorm.DB, use *Use) (interface{}, error)
+
+// Result 物品使用结果
+type Result struct {
+	Effect    string      `json:"effect"`
+	Quantity  int         `json:"quantity"`  // 本次使用数量
+	Remaining int         `json:"remaining"` // 剩余数量
+	Data      interface{} `json:"data"`      // 效果返回的数据
+}
+
+var (
+	mu       sync.RWMutex
+	handlers = make(map[string]Handler)
+)
+
+// Register 注册效果处理函数，重复注册会覆盖
+func Register(name string, h Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers[name] = h
+}
+
+// Registered 判断效果是否已注册
+func Registered(name string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, ok := handlers[name]
+	return ok
+}
+
+// Names 返回所有已注册的效果名称
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(handlers))
+	for name := range handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UseItem 使用消耗品：在一个事务中扣减用户物品数量并执行物品配置的效果
+func UseItem(userID, itemID uint, quantity int, params map[string]interface{}) (*Result, error) {
+	if quantity < 1 {
+		return nil, ErrInvalidQuantity
+	}
+
+	item, err := models.GetItemByID(itemID)
+	if err != nil {
+		return nil, err
+	}
+	if item.Category != models.CategoryConsumable {
+		return nil, ErrNotConsumable
+	}
+
+	mu.RLock()
+	handler, ok := handlers[item.Effect]
+	mu.RUnlock()
+	if item.Effect == "" || !ok {
+		return nil, ErrNoEffect
+	}
+
+	use := &Use{UserID: userID, Item: item, Quantity: quantity, Params: params, Now: time.Now()}
+	result := &Result{Effect: item.Effect, Quantity: quantity}
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		reason := fmt.Sprintf("使用 %s x%d", item.Name, quantity)
+		if err := models.ConsumeUserItem(tx, userID, itemID, quantity, "effect:"+item.Effect, reason); err != nil {
+			return err
+		}
+
+		data, err := handler(tx, use)
+		if err != nil {
+			return err
+		}
+		result.Data = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Remaining, err = models.GetUserItemQuantity(userID, itemID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,193 @@
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Type 事件类型
+type Type string
+
+// 领域事件
+const (
+	FoodRecordCreated   Type = "food_record.created"
+	FoodRecordUpdated   Type = "food_record.updated"
+	FoodRecordDeleted   Type = "food_record.deleted"
+	CheckInCreated      Type = "check_in.created"
+	HealthStateRecorded Type = "health_state.recorded"
+	HealthStateUpdated  Type = "health_state.updated"
+	HealthStateDeleted  Type = "health_state.deleted"
+)
+
+// Event 领域事件，Payload 为对应的模型（如 *models.FoodRecord）
+type Event struct {
+	Type       Type
+	UserID     uint
+	OccurredAt time.Time
+	Payload    interface{}
+
+	waiter *waiter
+}
+
+// waiter 收集处理函数的回复，供 PublishAndWait 的调用方读取
+type waiter struct {
+	mu      sync.Mutex
+	replies []interface{}
+	done    chan struct{}
+}
+
+// Reply 处理函数向发布方回复处理结果，只有通过 PublishAndWait 发布的事件才会被读取
+func (e Event) Reply(v interface{}) {
+	if e.waiter == nil {
+		return
+	}
+	e.waiter.mu.Lock()
+	e.waiter.replies = append(e.waiter.replies, v)
+	e.waiter.mu.Unlock()
+}
+
+// Handler 事件处理函数
+type Handler func(Event)
+
+// Bus 异步事件总线
+// 同一用户的事件总是分配到同一个队列，按发布顺序依次处理
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[Type][]Handler
+	queues   []chan Event
+	wg       sync.WaitGroup
+}
+
+// publishTimeout 队列已满时发布方最多等待的时间，避免长时间阻塞HTTP请求
+// 事件不持久化，丢弃或重启时丢失的事件需要处理方自行补偿，例如成就进度发现记录数不一致时重新计算
+const publishTimeout = time.Second
+
+// NewBus 创建事件总线，workers 为并发处理的队列数，buffer 为每个队列的缓冲大小
+func NewBus(workers, buffer int) *Bus {
+	if workers <= 0 {
+		workers = 1
+	}
+	b := &Bus{
+		handlers: make(map[Type][]Handler),
+		queues:   make([]chan Event, workers),
+	}
+	for i := range b.queues {
+		b.queues[i] = make(chan Event, buffer)
+	}
+	return b
+}
+
+// Subscribe 订阅事件
+func (b *Bus) Subscribe(t Type, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[t] = append(b.handlers[t], h)
+}
+
+// Start 启动处理队列
+func (b *Bus) Start() {
+	for _, queue := range b.queues {
+		b.wg.Add(1)
+		go func(queue chan Event) {
+			defer b.wg.Done()
+			for e := range queue {
+				b.dispatch(e)
+			}
+		}(queue)
+	}
+}
+
+// Publish 发布事件，队列已满时最多阻塞 publishTimeout，仍无法投递则丢弃事件并记录日志
+// 同一用户的事件在调用方内同步入队，保证按发布顺序处理
+func (b *Bus) Publish(e Event) {
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now()
+	}
+	queue := b.queues[int(e.UserID)%len(b.queues)]
+	select {
+	case queue <- e:
+		return
+	default:
+	}
+
+	log.Printf("事件队列已满，等待投递事件 %s (用户 %d)", e.Type, e.UserID)
+	timer := time.NewTimer(publishTimeout)
+	defer timer.Stop()
+	select {
+	case queue <- e:
+	case <-timer.C:
+		log.Printf("事件队列持续已满，丢弃事件 %s (用户 %d)", e.Type, e.UserID)
+	}
+}
+
+// PublishAndWait 发布事件并在超时时间内等待所有处理函数执行完毕，返回处理函数的回复
+// 超时后事件仍会在后台继续处理，此时返回 false
+func (b *Bus) PublishAndWait(e Event, timeout time.Duration) ([]interface{}, bool) {
+	w := &waiter{done: make(chan struct{})}
+	e.waiter = w
+	b.Publish(e)
+
+	select {
+	case <-w.done:
+	case <-time.After(timeout):
+		return nil, false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.replies, true
+}
+
+// Close 停止接收事件并等待队列中的事件处理完毕
+func (b *Bus) Close() {
+	for _, queue := range b.queues {
+		close(queue)
+	}
+	b.wg.Wait()
+}
+
+// dispatch 依次调用事件的所有处理函数，单个处理函数 panic 不影响其他事件
+func (b *Bus) dispatch(e Event) {
+	b.mu.RLock()
+	handlers := b.handlers[e.Type]
+	b.mu.RUnlock()
+
+	if e.waiter != nil {
+		defer close(e.waiter.done)
+	}
+
+	for _, h := range handlers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("处理事件 %s (用户 %d) 时发生错误: %v", e.Type, e.UserID, r)
+				}
+			}()
+			h(e)
+		}()
+	}
+}
+
+// 全局事件总线
+var defaultBus = NewBus(4, 256)
+
+// Default 返回全局事件总线
+func Default() *Bus {
+	return defaultBus
+}
+
+// Subscribe 在全局事件总线上订阅事件
+func Subscribe(t Type, h Handler) {
+	defaultBus.Subscribe(t, h)
+}
+
+// Publish 向全局事件总线发布事件
+func Publish(t Type, userID uint, payload interface{}) {
+	defaultBus.Publish(Event{Type: t, UserID: userID, Payload: payload})
+}
+
+// PublishAndWait 向全局事件总线发布事件并等待处理结果
+func PublishAndWait(t Type, userID uint, payload interface{}, timeout time.Duration) ([]interface{}, bool) {
+	return defaultBus.PublishAndWait(Event{Type: t, UserID: userID, Payload: payload}, timeout)
+}
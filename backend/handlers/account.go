@@ -0,0 +1,452 @@
+package handlers
+
+import (
+	"archive/zip"
+	"backend/models"
+	"backend/moderation"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 默认注销宽限期（天），可通过 ACCOUNT_DELETION_GRACE_DAYS 配置
+const defaultDeletionGraceDays = 30
+
+// getDeletionGracePeriod 获取账号注销宽限期
+func getDeletionGracePeriod() time.Duration {
+	days := defaultDeletionGraceDays
+	if value := os.Getenv("ACCOUNT_DELETION_GRACE_DAYS"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			days = parsed
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// ExportMyData 导出当前用户的全部个人数据（ZIP，包含 JSON、CSV 和图片）
+func ExportMyData(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	export, err := gatherUserExport(userID.(uint))
+	if err != nil {
+		log.Printf("收集用户 %d 的导出数据失败: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导出数据失败"})
+		return
+	}
+
+	filename := fmt.Sprintf("export_%d_%s.zip", userID, time.Now().Format("20060102150405"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+
+	if err := writeUserExport(c.Writer, export); err != nil {
+		// 响应头已发送，只能记录日志
+		log.Printf("写入用户 %d 的导出文件失败: %v", userID, err)
+	}
+}
+
+// DeleteMyAccount 申请注销当前账号，宽限期结束后删除全部数据
+func DeleteMyAccount(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	deletion, err := models.ScheduleAccountDeletion(userID.(uint), getDeletionGracePeriod())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "申请注销失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "注销申请已提交，宽限期内可随时撤销",
+		"deletion": deletion,
+	})
+}
+
+// CancelMyAccountDeletion 撤销注销申请
+func CancelMyAccountDeletion(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	if err := models.CancelAccountDeletion(userID.(uint)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "没有待处理的注销申请"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "撤销注销失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已撤销注销申请"})
+}
+
+// StartAccountPurgeWorker 定期删除已过宽限期的账号
+func StartAccountPurgeWorker(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			purgeDueAccounts()
+			<-ticker.C
+		}
+	}()
+}
+
+// purgeDueAccounts 删除所有已到期的注销账号及其图片文件
+func purgeDueAccounts() {
+	deletions, err := models.GetDueAccountDeletions(time.Now(), 100)
+	if err != nil {
+		log.Printf("查询待删除账号失败: %v", err)
+		return
+	}
+
+	for _, deletion := range deletions {
+		files, err := collectUserFiles(deletion.UserID)
+		if err != nil {
+			log.Printf("收集用户 %d 的文件失败: %v", deletion.UserID, err)
+			continue
+		}
+
+		if err := models.PurgeUserData(deletion.UserID); err != nil {
+			log.Printf("删除用户 %d 的数据失败: %v", deletion.UserID, err)
+			continue
+		}
+
+		for _, file := range files {
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				log.Printf("删除文件 %s 失败: %v", file, err)
+			}
+		}
+		log.Printf("用户 %d 的数据已彻底删除", deletion.UserID)
+	}
+}
+
+// userExport 用户导出数据
+type userExport struct {
+	User         models.User
+	Profile      *models.UserProfile
+	Identities   []models.UserIdentity
+	FoodRecords  []models.FoodRecord
+	HealthStates []models.UserHealthState
+	CheckIns     []models.CheckIn
+	UserItems    []models.UserItem
+	Coins        []models.CoinTransaction
+	Posts        []models.Post
+	Comments     []models.PostComment
+	Following    []models.Follow
+	Friends      []models.Friendship
+	Blocks       []models.Block
+	Challenges   []models.ChallengeParticipant
+	Files        []string
+}
+
+// gatherUserExport 从数据库收集用户的全部数据
+func gatherUserExport(userID uint) (*userExport, error) {
+	export := &userExport{}
+
+	if err := models.DB.First(&export.User, userID).Error; err != nil {
+		return nil, fmt.Errorf("获取用户失败: %v", err)
+	}
+	export.User.Password = ""
+
+	profile, err := models.GetUserProfile(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户资料失败: %v", err)
+	}
+	export.Profile = profile
+
+	if export.Identities, err = models.GetUserIdentities(userID); err != nil {
+		return nil, fmt.Errorf("获取第三方账号失败: %v", err)
+	}
+	if export.FoodRecords, err = models.GetAllUserFoodRecords(userID); err != nil {
+		return nil, fmt.Errorf("获取饮食记录失败: %v", err)
+	}
+	if err := models.DB.Where("user_id = ?", userID).Order("record_time DESC").Find(&export.HealthStates).Error; err != nil {
+		return nil, fmt.Errorf("获取健康状态失败: %v", err)
+	}
+	if export.CheckIns, err = models.GetUserCheckIns(userID); err != nil {
+		return nil, fmt.Errorf("获取打卡记录失败: %v", err)
+	}
+	if err := models.LoadCheckInFoodRecords(export.CheckIns); err != nil {
+		return nil, fmt.Errorf("获取打卡关联的饮食记录失败: %v", err)
+	}
+	if err := models.DB.Preload("Item").Where("user_id = ?", userID).Find(&export.UserItems).Error; err != nil {
+		return nil, fmt.Errorf("获取用户物品失败: %v", err)
+	}
+	if err := models.DB.Where("user_id = ?", userID).Order("id").Find(&export.Coins).Error; err != nil {
+		return nil, fmt.Errorf("获取金币流水失败: %v", err)
+	}
+	if export.Posts, err = models.GetUserPosts(userID); err != nil {
+		return nil, fmt.Errorf("获取帖子失败: %v", err)
+	}
+	if export.Comments, err = models.GetUserPostComments(userID); err != nil {
+		return nil, fmt.Errorf("获取评论失败: %v", err)
+	}
+	if err := models.DB.Where("follower_id = ?", userID).Order("created_at").Find(&export.Following).Error; err != nil {
+		return nil, fmt.Errorf("获取关注列表失败: %v", err)
+	}
+	if err := models.DB.Where("user_id = ?", userID).Order("created_at").Find(&export.Friends).Error; err != nil {
+		return nil, fmt.Errorf("获取好友列表失败: %v", err)
+	}
+	if err := models.DB.Where("blocker_id = ?", userID).Order("created_at").Find(&export.Blocks).Error; err != nil {
+		return nil, fmt.Errorf("获取黑名单失败: %v", err)
+	}
+	if export.Challenges, err = models.GetUserChallengeParticipations(userID); err != nil {
+		return nil, fmt.Errorf("获取挑战记录失败: %v", err)
+	}
+	if export.Files, err = collectUserFiles(userID); err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// writeUserExport 将导出数据写入 ZIP
+func writeUserExport(w io.Writer, export *userExport) error {
+	zw := zip.NewWriter(w)
+
+	jsonFiles := []struct {
+		name string
+		data interface{}
+	}{
+		{"user.json", export.User},
+		{"profile.json", export.Profile},
+		{"identities.json", export.Identities},
+		{"food_records.json", export.FoodRecords},
+		{"health_states.json", export.HealthStates},
+		{"check_ins.json", export.CheckIns},
+		{"user_items.json", export.UserItems},
+		{"coin_transactions.json", export.Coins},
+		{"posts.json", export.Posts},
+		{"post_comments.json", export.Comments},
+		{"following.json", export.Following},
+		{"friends.json", export.Friends},
+		{"blocks.json", export.Blocks},
+		{"challenges.json", export.Challenges},
+	}
+	for _, f := range jsonFiles {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(fw)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(f.data); err != nil {
+			return err
+		}
+	}
+
+	csvFiles := []struct {
+		name string
+		rows interface{}
+	}{
+		{"food_records.csv", export.FoodRecords},
+		{"health_states.csv", export.HealthStates},
+		{"check_ins.csv", export.CheckIns},
+		{"user_items.csv", export.UserItems},
+		{"coin_transactions.csv", export.Coins},
+	}
+	for _, f := range csvFiles {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if err := writeCSV(fw, f.rows); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range export.Files {
+		if err := addFileToZip(zw, file, "images/"+filepath.Base(file)); err != nil {
+			log.Printf("导出文件 %s 失败: %v", file, err)
+		}
+	}
+
+	return zw.Close()
+}
+
+// addFileToZip 将本地文件写入 ZIP
+func addFileToZip(zw *zip.Writer, path, name string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fw, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, file)
+	return err
+}
+
+// writeCSV 将结构体切片写为 CSV，列名使用 json 标签，嵌套结构体和切片字段会被跳过
+func writeCSV(w io.Writer, rows interface{}) error {
+	value := reflect.ValueOf(rows)
+	elemType := value.Type().Elem()
+
+	var headers []string
+	var indexes [][]int
+	collectCSVColumns(elemType, nil, &headers, &indexes)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(headers); err != nil {
+		return err
+	}
+
+	for i := 0; i < value.Len(); i++ {
+		row := value.Index(i)
+		record := make([]string, len(indexes))
+		for j, index := range indexes {
+			record[j] = formatCSVValue(row.FieldByIndex(index))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// collectCSVColumns 递归收集可导出为 CSV 的字段（展开 gorm.Model 等匿名嵌入字段）
+func collectCSVColumns(t reflect.Type, parent []int, headers *[]string, indexes *[][]int) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		index := append(append([]int{}, parent...), i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			collectCSVColumns(field.Type, index, headers, indexes)
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		switch field.Type.Kind() {
+		case reflect.Slice, reflect.Map:
+			continue
+		case reflect.Struct:
+			if field.Type != reflect.TypeOf(time.Time{}) && field.Type != reflect.TypeOf(gorm.DeletedAt{}) {
+				continue
+			}
+		}
+
+		*headers = append(*headers, name)
+		*indexes = append(*indexes, index)
+	}
+}
+
+// formatCSVValue 格式化单个 CSV 单元格
+func formatCSVValue(v reflect.Value) string {
+	switch value := v.Interface().(type) {
+	case time.Time:
+		if value.IsZero() {
+			return ""
+		}
+		return value.Format(time.RFC3339)
+	case gorm.DeletedAt:
+		if !value.Valid {
+			return ""
+		}
+		return value.Time.Format(time.RFC3339)
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		return formatCSVValue(v.Elem())
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// collectUserFiles 收集用户上传并保存在本地 static 目录（或审核隔离目录）下的文件
+func collectUserFiles(userID uint) ([]string, error) {
+	var urls []string
+
+	var foodImages []string
+	if err := models.DB.Unscoped().Model(&models.FoodRecord{}).Where("user_id = ? AND image_path <> ''", userID).Pluck("image_path", &foodImages).Error; err != nil {
+		return nil, fmt.Errorf("获取饮食记录图片失败: %v", err)
+	}
+	urls = append(urls, foodImages...)
+
+	var checkInImages []string
+	if err := models.DB.Unscoped().Model(&models.CheckIn{}).Where("user_id = ? AND image_url <> ''", userID).Pluck("image_url", &checkInImages).Error; err != nil {
+		return nil, fmt.Errorf("获取打卡图片失败: %v", err)
+	}
+	urls = append(urls, checkInImages...)
+
+	var postImages []string
+	posts := models.DB.Unscoped().Model(&models.Post{}).Select("id").Where("user_id = ?", userID)
+	if err := models.DB.Model(&models.PostImage{}).Where("post_id IN (?)", posts).Pluck("url", &postImages).Error; err != nil {
+		return nil, fmt.Errorf("获取帖子图片失败: %v", err)
+	}
+	urls = append(urls, postImages...)
+
+	profile, err := models.GetUserProfile(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户资料失败: %v", err)
+	}
+	if profile.AvatarURL != "" {
+		urls = append(urls, profile.AvatarURL)
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, url := range urls {
+		path, ok := localStaticPath(url)
+		if !ok || seen[path] {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			// 待审核或已下架内容的图片在隔离目录中
+			path = moderation.QuarantinedPath(path)
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+		}
+		seen[path] = true
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// localStaticPath 将 /static/... 形式的URL转换为本地文件路径，非本地文件返回 false
+func localStaticPath(url string) (string, bool) {
+	cleaned := filepath.Clean(strings.TrimPrefix(url, "/"))
+	if !strings.HasPrefix(cleaned, "static"+string(filepath.Separator)) {
+		return "", false
+	}
+	return cleaned, true
+}
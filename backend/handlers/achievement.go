@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"backend/achievement"
+	"backend/events"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// achievementWaitTimeout 新增记录后等待成就计算的最长时间，只覆盖队列空闲时的正常计算耗时，避免拖慢响应
+// 超时后成就仍会在后台发放并写入通知，客户端通过通知得知解锁
+const achievementWaitTimeout = 200 * time.Millisecond
+
+// publishAndCollectUnlocks 发布新增记录事件，并在超时时间内收集本次新解锁的成就
+func publishAndCollectUnlocks(t events.Type, userID uint, payload interface{}) []achievement.Status {
+	unlocked := []achievement.Status{}
+	replies, _ := events.PublishAndWait(t, userID, payload, achievementWaitTimeout)
+	for _, reply := range replies {
+		if statuses, ok := reply.([]achievement.Status); ok {
+			unlocked = append(unlocked, statuses...)
+		}
+	}
+	return unlocked
+}
+
+// GetMyAchievements 获取当前用户所有成就的解锁状态和进度
+func GetMyAchievements(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	statuses, err := achievement.UserAchievements(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成就失败"})
+		return
+	}
+
+	unlocked := 0
+	for _, s := range statuses {
+		if s.Unlocked {
+			unlocked++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":     statuses,
+		"total":    len(statuses),
+		"unlocked": unlocked,
+	})
+}
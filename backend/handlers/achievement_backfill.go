@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"backend/achievement"
+	"backend/models"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// BackfillRequest 创建成就回溯任务请求
+type BackfillRequest struct {
+	RuleIDs   []uint `json:"rule_ids"`   // 为空时回溯所有启用的规则
+	DryRun    bool   `json:"dry_run"`    // 试运行，只记录结果不发放物品
+	BatchSize int    `json:"batch_size"` // 每批处理的用户数
+}
+
+// CreateAchievementBackfill 创建成就回溯任务并在后台执行（管理员专用）
+func CreateAchievementBackfill(c *gin.Context) {
+	var req BackfillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	job, err := achievement.NewBackfillJob(req.RuleIDs, req.DryRun, req.BatchSize, currentActorID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := achievement.StartBackfill(job.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "启动回溯任务失败"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"data": job})
+}
+
+// GetAchievementBackfills 获取最近的成就回溯任务（管理员专用）
+func GetAchievementBackfills(c *gin.Context) {
+	jobs, err := models.GetBackfillJobs(50)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取回溯任务失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": jobs})
+}
+
+// GetAchievementBackfill 获取回溯任务的进度和达成结果（管理员专用）
+func GetAchievementBackfill(c *gin.Context) {
+	jobID, ok := parseBackfillJobID(c)
+	if !ok {
+		return
+	}
+
+	job, err := models.GetBackfillJob(jobID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "回溯任务不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取回溯任务失败"})
+		}
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 500 {
+		pageSize = 50
+	}
+
+	matches, total, err := models.GetBackfillMatches(jobID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取回溯结果失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    job,
+		"matches": matches,
+		"meta": gin.H{
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// ResumeAchievementBackfill 从上次中断的位置继续执行回溯任务（管理员专用）
+func ResumeAchievementBackfill(c *gin.Context) {
+	jobID, ok := parseBackfillJobID(c)
+	if !ok {
+		return
+	}
+
+	err := achievement.StartBackfill(jobID)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusAccepted, gin.H{"message": "回溯任务已继续执行"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "回溯任务不存在"})
+	case errors.Is(err, achievement.ErrBackfillRunning), errors.Is(err, achievement.ErrBackfillCompleted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "继续回溯任务失败"})
+	}
+}
+
+func parseBackfillJobID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
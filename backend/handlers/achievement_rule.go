@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"backend/achievement"
+	"backend/models"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AchievementRuleRequest 创建/更新成就规则请求
+type AchievementRuleRequest struct {
+	ItemID      uint                  `json:"item_id" binding:"required"`
+	Name        string                `json:"name" binding:"required"`
+	Description string                `json:"description"`
+	Definition  models.RuleDefinition `json:"definition"`
+	Enabled     *bool                 `json:"enabled"`     // 默认启用
+	CoinReward  int64                 `json:"coin_reward"` // 达成后奖励的金币，默认0
+}
+
+// DryRunRequest 规则试算请求，rule_id 和 definition 二选一
+type DryRunRequest struct {
+	UserID     uint                   `json:"user_id" binding:"required"`
+	RuleID     uint                   `json:"rule_id"`
+	Definition *models.RuleDefinition `json:"definition"`
+}
+
+// GetAchievementRules 获取所有成就规则（管理员专用）
+func GetAchievementRules(c *gin.Context) {
+	rules, err := models.GetAchievementRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成就规则失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rules})
+}
+
+// CreateAchievementRule 创建成就规则（管理员专用）
+func CreateAchievementRule(c *gin.Context) {
+	var req AchievementRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	rule := &models.AchievementRule{Enabled: true}
+	if !applyAchievementRuleRequest(c, rule, &req) {
+		return
+	}
+
+	if err := models.CreateAchievementRule(rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建成就规则失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rule})
+}
+
+// UpdateAchievementRule 更新成就规则（管理员专用）
+func UpdateAchievementRule(c *gin.Context) {
+	ruleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的规则ID"})
+		return
+	}
+
+	rule, err := models.GetAchievementRuleByID(uint(ruleID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "成就规则不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成就规则失败"})
+		}
+		return
+	}
+
+	var req AchievementRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	if !applyAchievementRuleRequest(c, rule, &req) {
+		return
+	}
+
+	if err := models.UpdateAchievementRule(rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新成就规则失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rule})
+}
+
+// DeleteAchievementRule 删除成就规则（管理员专用）
+func DeleteAchievementRule(c *gin.Context) {
+	ruleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的规则ID"})
+		return
+	}
+
+	if err := models.DeleteAchievementRule(uint(ruleID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除成就规则失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "成就规则已删除"})
+}
+
+// DryRunAchievementRule 在指定用户的数据上试算规则，不发放物品（管理员专用）
+func DryRunAchievementRule(c *gin.Context) {
+	var req DryRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	definition := req.Definition
+	if definition == nil {
+		if req.RuleID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请提供 rule_id 或 definition"})
+			return
+		}
+		rule, err := models.GetAchievementRuleByID(req.RuleID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "成就规则不存在"})
+			return
+		}
+		definition = &rule.Definition
+	}
+
+	var user models.User
+	if err := models.DB.First(&user, req.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	result, err := achievement.DryRun(user.ID, definition)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// applyAchievementRuleRequest 校验请求并写入规则，校验失败时写入错误响应并返回 false
+func applyAchievementRuleRequest(c *gin.Context, rule *models.AchievementRule, req *AchievementRuleRequest) bool {
+	if err := achievement.ValidateDefinition(&req.Definition); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	if req.CoinReward < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "金币奖励不能为负数"})
+		return false
+	}
+
+	if _, err := models.GetItemByID(req.ItemID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "物品不存在"})
+		return false
+	}
+
+	rule.ItemID = req.ItemID
+	rule.Name = req.Name
+	rule.Description = req.Description
+	rule.Definition = req.Definition
+	rule.CoinReward = req.CoinReward
+	rule.Item = nil
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	return true
+}
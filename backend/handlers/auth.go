@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"backend/achievement"
 	"backend/models"
 	"backend/utils"
 	"fmt"
@@ -158,7 +157,6 @@ func Login(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
 		return
 	}
-	achievement.CheckAchievements(user.ID)
 	log.Printf("登录成功: %s", req.Email)
 	c.JSON(http.StatusOK, gin.H{
 		"token": token,
@@ -294,17 +292,31 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 移除 "Bearer " 前缀
-		if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-			tokenString = tokenString[7:]
+		claims, ok := parseToken(tokenString)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的token"})
+			c.Abort()
+			return
 		}
 
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
+		c.Set("user_id", claims.UserID)
+		c.Set("role", claims.Role)
+		c.Next()
+	}
+}
 
-		if err != nil || !token.Valid {
+// OptionalAuthMiddleware 可选认证中间件，用于公开接口
+// 提供了有效 token 时设置当前用户，未提供时按未登录处理，token 无效时返回 401
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString := c.GetHeader("Authorization")
+		if tokenString == "" {
+			c.Next()
+			return
+		}
+
+		claims, ok := parseToken(tokenString)
+		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的token"})
 			c.Abort()
 			return
@@ -312,11 +324,27 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		c.Set("user_id", claims.UserID)
 		c.Set("role", claims.Role)
-		achievement.CheckAchievements(claims.UserID)
 		c.Next()
 	}
 }
 
+// parseToken 解析 Authorization 请求头中的 JWT
+func parseToken(tokenString string) (*Claims, bool) {
+	// 移除 "Bearer " 前缀
+	if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
+		tokenString = tokenString[7:]
+	}
+
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return jwtKey, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, false
+	}
+	return claims, true
+}
+
 // AdminAuthMiddleware 管理员认证中间件
 func AdminAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
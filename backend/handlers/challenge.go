@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"backend/challenge"
+	"backend/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ChallengeRequest 创建/更新挑战请求
+type ChallengeRequest struct {
+	Title          string               `json:"title" binding:"required"`
+	Description    string               `json:"description"`
+	Goal           models.ChallengeGoal `json:"goal"`
+	StartAt        time.Time            `json:"start_at"`
+	EndAt          time.Time            `json:"end_at"`
+	RewardItemID   *uint                `json:"reward_item_id"`  // 为空表示没有物品奖励
+	RewardQuantity int                  `json:"reward_quantity"` // 默认1
+}
+
+// GetChallenges 分页获取挑战列表，status 可选 upcoming、active、ended，joined=true 时只返回已加入的挑战
+func GetChallenges(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	page, pageSize := parsePagination(c, 20)
+
+	status := c.Query("status")
+	switch status {
+	case "", models.ChallengeStatusUpcoming, models.ChallengeStatusActive, models.ChallengeStatusEnded:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的挑战状态"})
+		return
+	}
+	var joinedBy uint
+	if c.Query("joined") == "true" {
+		joinedBy = userID.(uint)
+	}
+
+	challenges, total, err := models.GetChallengesPage(status, joinedBy, time.Now(), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取挑战列表失败"})
+		return
+	}
+	if err := models.LoadChallengeParticipations(challenges, userID.(uint)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取挑战列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, paginated(challenges, total, page, pageSize))
+}
+
+// GetChallenge 获取挑战详情，已加入时包含当前用户的进度和排名
+func GetChallenge(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	ch, ok := loadChallenge(c)
+	if !ok {
+		return
+	}
+
+	participant, err := models.GetChallengeParticipant(ch.ID, userID.(uint))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取挑战失败"})
+		return
+	}
+	ch.Participation = participant
+
+	c.JSON(http.StatusOK, gin.H{"data": ch})
+}
+
+// JoinChallenge 加入挑战，挑战已开始时立即计算进度
+// 加入后进度会出现在挑战排行榜中
+func JoinChallenge(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	ch, ok := loadChallenge(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if _, err := models.JoinChallenge(ch.ID, userID.(uint), now); err != nil {
+		respondChallengeError(c, err, "加入挑战失败")
+		return
+	}
+	if err := challenge.Refresh(userID.(uint), ch, now); err != nil {
+		log.Printf("计算用户 %d 在挑战 %d 的进度失败: %v", userID, ch.ID, err)
+	}
+
+	participant, err := models.GetChallengeParticipant(ch.ID, userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取挑战进度失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": participant})
+}
+
+// LeaveChallenge 退出挑战
+func LeaveChallenge(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	challengeID, ok := parseChallengeID(c)
+	if !ok {
+		return
+	}
+
+	if err := models.LeaveChallenge(challengeID, userID.(uint), time.Now()); err != nil {
+		respondChallengeError(c, err, "退出挑战失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已退出挑战"})
+}
+
+// GetChallengeLeaderboard 分页获取挑战排行榜，已加入时同时返回当前用户的排名
+func GetChallengeLeaderboard(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	ch, ok := loadChallenge(c)
+	if !ok {
+		return
+	}
+	page, pageSize := parsePagination(c, 20)
+
+	participants, total, err := models.GetChallengeLeaderboard(ch, userID.(uint), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取排行榜失败"})
+		return
+	}
+	me, err := models.GetChallengeParticipant(ch.ID, userID.(uint))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取排行榜失败"})
+		return
+	}
+
+	response := paginated(participants, total, page, pageSize)
+	response["me"] = me
+	c.JSON(http.StatusOK, response)
+}
+
+// AdminGetChallenges 分页获取所有挑战（管理员专用）
+func AdminGetChallenges(c *gin.Context) {
+	page, pageSize := parsePagination(c, 20)
+
+	challenges, total, err := models.GetChallengesPage(c.Query("status"), 0, time.Now(), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取挑战列表失败"})
+		return
+	}
+	c.JSON(http.StatusOK, paginated(challenges, total, page, pageSize))
+}
+
+// CreateChallenge 创建挑战（管理员专用）
+func CreateChallenge(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req ChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	ch := &models.Challenge{CreatedBy: userID.(uint)}
+	if !applyChallengeRequest(c, ch, &req, time.Now()) {
+		return
+	}
+
+	if err := models.CreateChallenge(ch); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建挑战失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": ch})
+}
+
+// UpdateChallenge 更新挑战（管理员专用），挑战开始后不能修改目标和开始时间
+func UpdateChallenge(c *gin.Context) {
+	ch, ok := loadChallenge(c)
+	if !ok {
+		return
+	}
+
+	var req ChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	if !applyChallengeRequest(c, ch, &req, time.Now()) {
+		return
+	}
+
+	if err := models.UpdateChallenge(ch); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新挑战失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": ch})
+}
+
+// DeleteChallenge 删除挑战（管理员专用）
+func DeleteChallenge(c *gin.Context) {
+	challengeID, ok := parseChallengeID(c)
+	if !ok {
+		return
+	}
+
+	if err := models.DeleteChallenge(challengeID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除挑战失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "挑战已删除"})
+}
+
+// AdminRetryChallengeRewards 重新发放挑战中发放失败的完成奖励（管理员专用）
+func AdminRetryChallengeRewards(c *gin.Context) {
+	ch, ok := loadChallenge(c)
+	if !ok {
+		return
+	}
+
+	granted, err := challenge.RetryRewards(ch)
+	pending, countErr := models.GetPendingRewardParticipantIDs(ch.ID)
+	if countErr != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重新发放奖励失败"})
+		return
+	}
+	response := gin.H{"message": "已重新发放奖励", "granted": granted, "pending": len(pending)}
+	if err != nil {
+		response["message"] = "部分奖励仍发放失败"
+		response["error"] = err.Error()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// applyChallengeRequest 校验请求并写入挑战，校验失败时写入错误响应并返回 false
+func applyChallengeRequest(c *gin.Context, ch *models.Challenge, req *ChallengeRequest, now time.Time) bool {
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "挑战名称不能为空"})
+		return false
+	}
+	if err := challenge.ValidateGoal(&req.Goal); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if err := challenge.ValidatePeriod(req.StartAt, req.EndAt); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	// 已开始的挑战修改目标或开始时间会使已有进度和排名失去意义
+	if ch.ID != 0 && !now.Before(ch.StartAt) {
+		if !req.StartAt.Equal(ch.StartAt) || challengeGoalKey(&req.Goal) != challengeGoalKey(&ch.Goal) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "挑战开始后不能修改目标和开始时间"})
+			return false
+		}
+	}
+
+	quantity := req.RewardQuantity
+	if quantity == 0 {
+		quantity = 1
+	}
+	if quantity < 1 || quantity > challenge.MaxRewardQuantity {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("奖励数量应在1到%d之间", challenge.MaxRewardQuantity)})
+		return false
+	}
+	if req.RewardItemID != nil {
+		if _, err := models.GetItemByID(*req.RewardItemID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "奖励物品不存在"})
+			return false
+		}
+	}
+
+	ch.Title = title
+	ch.Description = req.Description
+	ch.Goal = req.Goal
+	ch.StartAt = req.StartAt
+	ch.EndAt = req.EndAt
+	ch.RewardItemID = req.RewardItemID
+	ch.RewardQuantity = quantity
+	ch.RewardItem = nil
+	return true
+}
+
+// challengeGoalKey 用于比较两个挑战目标是否相同
+func challengeGoalKey(goal *models.ChallengeGoal) string {
+	data, _ := json.Marshal(goal)
+	return string(data)
+}
+
+// parseChallengeID 解析路径中的挑战ID
+func parseChallengeID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的挑战ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// loadChallenge 根据路径中的挑战ID加载挑战
+func loadChallenge(c *gin.Context) (*models.Challenge, bool) {
+	challengeID, ok := parseChallengeID(c)
+	if !ok {
+		return nil, false
+	}
+	ch, err := models.GetChallengeByID(challengeID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "挑战不存在"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取挑战失败"})
+		return nil, false
+	}
+	return ch, true
+}
+
+// respondChallengeError 将挑战操作的错误转换为响应
+func respondChallengeError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "挑战不存在"})
+	case errors.Is(err, models.ErrNotJoinedChallenge):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrChallengeEnded), errors.Is(err, models.ErrChallengeCompleted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -2,28 +2,50 @@ package handlers
 
 import (
 	"backend/achievement"
+	"backend/calendar"
+	"backend/events"
 	"backend/models"
+	"backend/moderation"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// 定义中国时区
-var cst *time.Location
+// CheckInRequest 打卡请求结构，支持 JSON 和 multipart/form-data（可附带 image 图片）
+type CheckInRequest struct {
+	Content       string `json:"content" form:"content" binding:"-"`                 // 打卡内容（可选）
+	Mood          int    `json:"mood" form:"mood" binding:"-"`                       // 心情评分 1-5（可选）
+	Energy        int    `json:"energy" form:"energy" binding:"-"`                   // 精力评分 1-5（可选）
+	Hunger        int    `json:"hunger" form:"hunger" binding:"-"`                   // 饥饿感评分 1-5（可选）
+	FoodRecordIDs []uint `json:"food_record_ids" form:"food_record_ids" binding:"-"` // 关联的当天饮食记录ID（可选）
+}
 
-func init() {
-	var err error
-	cst, err = time.LoadLocation("Asia/Shanghai")
-	if err != nil {
-		// 如果加载失败，手动设置UTC+8
-		cst = time.FixedZone("CST", 8*3600)
+// validate 校验评分范围并去除重复的饮食记录ID
+func (r *CheckInRequest) validate() string {
+	ratings := []struct {
+		name  string
+		value int
+	}{{"心情", r.Mood}, {"精力", r.Energy}, {"饥饿感", r.Hunger}}
+	for _, rating := range ratings {
+		if rating.value != 0 && (rating.value < models.MinCheckInRating || rating.value > models.MaxCheckInRating) {
+			return fmt.Sprintf("%s评分应在%d到%d之间", rating.name, models.MinCheckInRating, models.MaxCheckInRating)
+		}
 	}
-}
 
-// CheckInRequest 打卡请求结构
-type CheckInRequest struct {
-	Content string `json:"content" binding:"-"` // 打卡内容（可选）
+	seen := make(map[uint]bool)
+	ids := r.FoodRecordIDs[:0]
+	for _, id := range r.FoodRecordIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	r.FoodRecordIDs = ids
+	return ""
 }
 
 // CheckInResponse 打卡响应结构
@@ -33,6 +55,8 @@ type CheckInResponse struct {
 	HasFoodRecord    bool            `json:"has_food_record"`         // 是否有食物记录
 	AlreadyCheckedIn bool            `json:"already_checked_in"`      // 是否已经打卡
 	CheckInData      *models.CheckIn `json:"check_in_data,omitempty"` // 打卡数据
+
+	Achievements []achievement.Status `json:"achievements,omitempty"` // 本次新解锁的成就
 }
 
 // HandleCheckIn 处理用户打卡请求
@@ -47,8 +71,9 @@ func HandleCheckIn(c *gin.Context) {
 		return
 	}
 
-	// 2. 检查用户今日是否已打卡
-	hasCheckedIn, err := models.HasUserCheckedInToday(userID.(uint))
+	// 2. 检查用户今日是否已打卡（按用户时区计算"今日"）
+	loc := models.GetUserLocation(userID.(uint))
+	hasCheckedIn, err := models.HasUserCheckedInToday(userID.(uint), loc)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, CheckInResponse{
 			Success: false,
@@ -67,9 +92,7 @@ func HandleCheckIn(c *gin.Context) {
 	}
 
 	// 3. 检查用户今日是否有食物记录
-	today := time.Now().In(cst)
-	startOfDay := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, cst)
-	endOfDay := startOfDay.Add(24 * time.Hour)
+	startOfDay, endOfDay := calendar.DayRange(time.Now(), loc)
 
 	foodRecords, err := models.GetUserFoodRecords(userID.(uint), startOfDay, endOfDay)
 	if err != nil {
@@ -89,38 +112,122 @@ func HandleCheckIn(c *gin.Context) {
 		return
 	}
 
-	// 4. 解析请求体
+	// 4. 解析请求体（JSON 或带图片的表单）
 	var req CheckInRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	isMultipart := c.ContentType() == "multipart/form-data"
+	var bindErr error
+	if isMultipart {
+		bindErr = c.ShouldBind(&req)
+	} else {
+		bindErr = c.ShouldBindJSON(&req)
+	}
+	if bindErr != nil {
 		c.JSON(http.StatusBadRequest, CheckInResponse{
 			Success: false,
 			Message: "无效的请求数据",
 		})
 		return
 	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, CheckInResponse{
+			Success: false,
+			Message: msg,
+		})
+		return
+	}
+	result := moderation.CheckText(c.Request.Context(), req.Content)
+	if result.Rejected() {
+		c.JSON(http.StatusBadRequest, CheckInResponse{
+			Success: false,
+			Message: contentRejectedMessage,
+		})
+		return
+	}
 
-	// 5. 创建打卡记录
+	// 5. 保存打卡图片（可选）
+	imageURL := ""
+	if isMultipart {
+		if file, err := c.FormFile("image"); err == nil {
+			if imageURL, err = saveUserImage(c, file, "check-ins"); err != nil {
+				status := http.StatusInternalServerError
+				if errors.Is(err, errImageType) || errors.Is(err, errImageSize) {
+					status = http.StatusBadRequest
+				}
+				c.JSON(status, CheckInResponse{
+					Success: false,
+					Message: err.Error(),
+				})
+				return
+			}
+		}
+	}
+	var images []string
+	if imageURL != "" {
+		images = []string{imageURL}
+		imageResult := moderateImages(c, images)
+		if imageResult.Rejected() {
+			removeLocalImage(imageURL)
+			c.JSON(http.StatusBadRequest, CheckInResponse{
+				Success: false,
+				Message: imageRejectedMessage,
+			})
+			return
+		}
+		result.Merge(imageResult)
+	}
+
+	// 6. 创建打卡记录并关联当天的饮食记录
 	checkIn := &models.CheckIn{
 		UserID:    userID.(uint),
-		CheckInAt: time.Now().In(cst), // 使用中国时区
+		CheckInAt: time.Now().In(loc), // 使用用户时区
 		Content:   req.Content,
+		ImageURL:  imageURL,
+		Mood:      req.Mood,
+		Energy:    req.Energy,
+		Hunger:    req.Hunger,
+
+		ModerationStatus: result.Status(),
 	}
 
-	if err := models.CreateCheckIn(checkIn); err != nil {
+	if err := models.CreateCheckInWithFoodRecords(checkIn, req.FoodRecordIDs, startOfDay, endOfDay); err != nil {
+		removeLocalImage(imageURL)
+		if errors.Is(err, models.ErrAlreadyCheckedIn) {
+			c.JSON(http.StatusBadRequest, CheckInResponse{
+				Success:          false,
+				Message:          err.Error(),
+				AlreadyCheckedIn: true,
+			})
+			return
+		}
+		if errors.Is(err, models.ErrInvalidCheckInFoodRecord) {
+			c.JSON(http.StatusBadRequest, CheckInResponse{
+				Success:       false,
+				Message:       err.Error(),
+				HasFoodRecord: true,
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, CheckInResponse{
 			Success: false,
 			Message: "创建打卡记录失败",
 		})
 		return
 	}
-	achievement.CheckAchievements(userID.(uint))
-	// 6. 返回成功响应
+	moderation.Submit(models.ModerationContentCheckIn, checkIn.ID, checkIn.UserID, checkIn.Content, images, result)
+	unlocked := publishAndCollectUnlocks(events.CheckInCreated, checkIn.UserID, checkIn)
+
+	// 7. 返回成功响应
+	message := "打卡成功"
+	if result.NeedsReview() {
+		message = "打卡成功，" + pendingReviewMessage
+	}
 	c.JSON(http.StatusOK, CheckInResponse{
 		Success:          true,
-		Message:          "打卡成功",
+		Message:          message,
 		HasFoodRecord:    true,
 		AlreadyCheckedIn: false,
 		CheckInData:      checkIn,
+		Achievements:     unlocked,
 	})
 }
 
@@ -136,7 +243,8 @@ func GetTodayCheckIn(c *gin.Context) {
 	}
 
 	// 检查今日是否已打卡
-	hasCheckedIn, err := models.HasUserCheckedInToday(userID.(uint))
+	loc := models.GetUserLocation(userID.(uint))
+	hasCheckedIn, err := models.HasUserCheckedInToday(userID.(uint), loc)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -146,9 +254,7 @@ func GetTodayCheckIn(c *gin.Context) {
 	}
 
 	// 检查今日是否有食物记录
-	today := time.Now().In(cst)
-	startOfDay := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, cst)
-	endOfDay := startOfDay.Add(24 * time.Hour)
+	startOfDay, endOfDay := calendar.DayRange(time.Now(), loc)
 
 	foodRecords, err := models.GetUserFoodRecords(userID.(uint), startOfDay, endOfDay)
 	if err != nil {
@@ -168,7 +274,8 @@ func GetTodayCheckIn(c *gin.Context) {
 	})
 }
 
-// GetUserCheckIns 获取用户所有打卡记录
+// GetUserCheckIns 分页获取用户的打卡记录
+// 支持 page/page_size 页码分页，也支持 cursor 游标分页（传入上一页返回的 next_cursor）
 func GetUserCheckIns(c *gin.Context) {
 	// 1. 获取用户ID
 	userID, exists := c.Get("user_id")
@@ -180,12 +287,30 @@ func GetUserCheckIns(c *gin.Context) {
 		return
 	}
 
-	// 2. 从查询参数获取分页信息（可选）
-	page := c.DefaultQuery("page", "1")
-	pageSize := c.DefaultQuery("page_size", "10")
+	// 2. 从查询参数获取分页信息
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	var cursor *models.Cursor
+	if raw := c.Query("cursor"); raw != "" {
+		var err error
+		if cursor, err = models.DecodeCursor(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
 
 	// 3. 调用模型层获取打卡记录
-	checkIns, err := models.GetUserCheckIns(userID.(uint))
+	checkIns, next, err := models.GetUserCheckInsPage(userID.(uint), cursor, (page-1)*pageSize, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -194,15 +319,163 @@ func GetUserCheckIns(c *gin.Context) {
 		return
 	}
 
+	if err := models.LoadCheckInFoodRecords(checkIns); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取打卡关联的饮食记录失败",
+		})
+		return
+	}
+
+	total, err := models.CountUserCheckIns(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取打卡记录失败",
+		})
+		return
+	}
+
+	nextCursor := ""
+	if next != nil {
+		nextCursor = next.Encode()
+	}
+
 	// 4. 返回成功响应
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "获取打卡记录成功",
 		"data": gin.H{
-			"total":     len(checkIns),
-			"records":   checkIns,
-			"page":      page,
-			"page_size": pageSize,
+			"total":       total,
+			"records":     checkIns,
+			"page":        page,
+			"page_size":   pageSize,
+			"next_cursor": nextCursor,
+			"has_more":    next != nil,
+		},
+	})
+}
+
+// CalendarDay 打卡日历中的一天
+type CalendarDay struct {
+	Date            string  `json:"date"`              // 日期（用户时区 YYYY-MM-DD）
+	CheckedIn       bool    `json:"checked_in"`        // 是否打卡
+	MakeUp          bool    `json:"make_up"`           // 是否为补签
+	Frozen          bool    `json:"frozen"`            // 是否使用了冻结卡
+	FoodRecordCount int     `json:"food_record_count"` // 饮食记录数
+	Calories        float64 `json:"calories"`          // 总热量（千卡）
+}
+
+// GetCheckInCalendar 获取指定月份每天的打卡状态、饮食记录数和热量，month 格式为 YYYY-MM，默认本月
+func GetCheckInCalendar(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户未登录",
+		})
+		return
+	}
+
+	loc := models.GetUserLocation(userID.(uint))
+	month := time.Now().In(loc)
+	if raw := c.Query("month"); raw != "" {
+		parsed, err := time.Parse("2006-01", raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "月份格式错误，应为YYYY-MM",
+			})
+			return
+		}
+		month = parsed
+	}
+	start, end := calendar.MonthRange(month.Year(), month.Month(), loc)
+
+	checkIns, err := models.GetUserCheckInsBetween(userID.(uint), start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取打卡记录失败",
+		})
+		return
+	}
+	foodRecords, err := models.GetUserFoodRecords(userID.(uint), start, end.Add(-time.Second))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取食物记录失败",
+		})
+		return
+	}
+	freezes, err := models.GetUserStreakFreezes(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取冻结记录失败",
+		})
+		return
+	}
+
+	// 按日期生成整月的日历
+	var days []CalendarDay
+	index := make(map[string]int)
+	for day := start; day.Before(end); _, day = calendar.DayRange(day, loc) {
+		key := calendar.DateKey(day, loc)
+		index[key] = len(days)
+		days = append(days, CalendarDay{Date: key})
+	}
+
+	for _, checkIn := range checkIns {
+		if i, ok := index[calendar.DateKey(checkIn.CheckInAt, loc)]; ok {
+			days[i].CheckedIn = true
+			days[i].MakeUp = days[i].MakeUp || checkIn.MakeUp
+		}
+	}
+	for _, freeze := range freezes {
+		if i, ok := index[freeze.Date]; ok {
+			days[i].Frozen = true
+		}
+	}
+
+	var totalCalories float64
+	recordedDays := 0
+	for _, record := range foodRecords {
+		if i, ok := index[calendar.DateKey(record.RecordTime, loc)]; ok {
+			if days[i].FoodRecordCount == 0 {
+				recordedDays++
+			}
+			days[i].FoodRecordCount++
+			days[i].Calories += record.Calories
+			totalCalories += record.Calories
+		}
+	}
+
+	checkedInDays := 0
+	for _, day := range days {
+		if day.CheckedIn {
+			checkedInDays++
+		}
+	}
+	averageCalories := 0.0
+	if recordedDays > 0 {
+		averageCalories = totalCalories / float64(recordedDays)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "获取打卡日历成功",
+		"data": gin.H{
+			"month": start.In(loc).Format("2006-01"),
+			"days":  days,
+			"stats": gin.H{
+				"checked_in_days":   checkedInDays,
+				"food_record_days":  recordedDays,
+				"food_record_count": len(foodRecords),
+				"total_calories":    totalCalories,
+				"average_calories":  averageCalories, // 有饮食记录的日子的平均热量
+				"days_in_month":     len(days),
+			},
 		},
 	})
 }
@@ -1,7 +1,8 @@
 package handlers
 
 import (
-	"backend/achievement"
+	"backend/calendar"
+	"backend/events"
 	"backend/models"
 	"errors"
 	"fmt"
@@ -111,11 +112,13 @@ func CreateFoodRecordHandler(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存食物记录失败"})
 		return
 	}
-	achievement.CheckAchievements(userID.(uint))
+	unlocked := publishAndCollectUnlocks(events.FoodRecordCreated, record.UserID, &record)
+
 	// 返回成功响应
 	c.JSON(http.StatusOK, gin.H{
-		"message": "食物记录创建成功",
-		"record":  record,
+		"message":      "食物记录创建成功",
+		"record":       record,
+		"achievements": unlocked, // 本次新解锁的成就
 	})
 }
 
@@ -134,10 +137,11 @@ func GetFoodRecordsHandler(c *gin.Context) {
 
 	var startTime, endTime time.Time
 	var err error
+	loc := models.GetUserLocation(userID.(uint))
 
-	// 如果提供了开始日期，解析它
+	// 如果提供了开始日期，按用户时区解析它
 	if startDateStr != "" {
-		startTime, err = time.Parse("2006-01-02", startDateStr)
+		startTime, err = calendar.ParseDate(startDateStr, loc)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "开始日期格式错误"})
 			return
@@ -149,13 +153,14 @@ func GetFoodRecordsHandler(c *gin.Context) {
 
 	// 如果提供了结束日期，解析它
 	if endDateStr != "" {
-		endTime, err = time.Parse("2006-01-02", endDateStr)
+		endTime, err = calendar.ParseDate(endDateStr, loc)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "结束日期格式错误"})
 			return
 		}
-		// 设置为当天的结束时间
-		endTime = endTime.Add(24*time.Hour - time.Second)
+		// 设置为当天的结束时间（夏令时切换日不一定是24小时）
+		_, endTime = calendar.DayRange(endTime, loc)
+		endTime = endTime.Add(-time.Second)
 	} else {
 		// 默认为当前时间
 		endTime = time.Now()
@@ -213,9 +218,9 @@ func GetFoodRecordHandler(c *gin.Context) {
 		return
 	}
 
-	// 验证记录是否属于当前用户
-	if record.UserID != userID.(uint) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "无权访问此记录"})
+	// 其他用户的记录遵循对方的饮食记录可见范围
+	if record.UserID != userID.(uint) &&
+		!checkContentVisible(c, record.UserID, func(p *models.PrivacySettings) string { return p.FoodRecordVisibility }) {
 		return
 	}
 
@@ -273,7 +278,8 @@ func UpdateFoodRecordHandler(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新记录失败"})
 		return
 	}
-	achievement.CheckAchievements(userID.(uint))
+	events.Publish(events.FoodRecordUpdated, updatedRecord.UserID, &updatedRecord)
+
 	// 返回更新后的记录
 	c.JSON(http.StatusOK, gin.H{
 		"message": "记录更新成功",
@@ -320,6 +326,7 @@ func DeleteFoodRecordHandler(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除记录失败"})
 		return
 	}
+	events.Publish(events.FoodRecordDeleted, existingRecord.UserID, existingRecord)
 
 	// 返回成功响应
 	c.JSON(http.StatusOK, gin.H{"message": "记录已成功删除"})
@@ -369,11 +376,13 @@ func AnalyzeAndSaveFoodHandler(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存食物记录失败"})
 		return
 	}
-	achievement.CheckAchievements(userID.(uint))
+	unlocked := publishAndCollectUnlocks(events.FoodRecordCreated, record.UserID, record)
+
 	// 返回成功响应
 	c.JSON(http.StatusOK, gin.H{
-		"message":  "食物分析和记录保存成功",
-		"record":   record,
-		"analysis": analysis,
+		"message":      "食物分析和记录保存成功",
+		"record":       record,
+		"analysis":     analysis,
+		"achievements": unlocked, // 本次新解锁的成就
 	})
 }
@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"backend/calendar"
 	"backend/models"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -53,6 +57,17 @@ func RegisterHealthAnalysisRoutes(router *gin.Engine, handler *HealthAnalysisHan
 	healthAnalysisGroup.POST("/health-analysis", handler.AnalyzeHealth)
 }
 
+// getDailyFreeHealthAnalyses 获取每天免费的健康分析次数，可通过 HEALTH_ANALYSIS_DAILY_FREE_LIMIT 配置
+// 默认 0 表示不限制次数，此时不记录使用情况，也不消耗分析次数卡增加的次数
+func getDailyFreeHealthAnalyses() int {
+	if value := os.Getenv("HEALTH_ANALYSIS_DAILY_FREE_LIMIT"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			return parsed
+		}
+	}
+	return 0
+}
+
 // AnalyzeHealth 处理健康分析请求
 func (h *HealthAnalysisHandler) AnalyzeHealth(c *gin.Context) {
 	// 获取当前用户ID
@@ -69,21 +84,22 @@ func (h *HealthAnalysisHandler) AnalyzeHealth(c *gin.Context) {
 		return
 	}
 
-	// 验证日期格式
-	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	// 验证日期格式，日期按用户时区解析
+	loc := models.GetUserLocation(userID.(uint))
+	startDate, err := calendar.ParseDate(req.StartDate, loc)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "起始日期格式错误，应为YYYY-MM-DD"})
 		return
 	}
 
-	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	endDate, err := calendar.ParseDate(req.EndDate, loc)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "结束日期格式错误，应为YYYY-MM-DD"})
 		return
 	}
 
-	// 结束日期需要包含当天的所有记录，所以增加一天
-	endDate = endDate.AddDate(0, 0, 1)
+	// 结束日期需要包含当天的所有记录，所以取下一天的开始时间
+	_, endDate = calendar.DayRange(endDate, loc)
 
 	// 验证日期范围
 	if startDate.After(endDate) {
@@ -105,18 +121,50 @@ func (h *HealthAnalysisHandler) AnalyzeHealth(c *gin.Context) {
 		return
 	}
 
-	// 将食物记录转换为字符串
-	recordsStr := formatFoodRecordsToString(foodRecords)
+	// 获取同一时间范围内的打卡记录（包含心情、精力、饥饿感评分和关联的饮食）
+	checkIns, err := models.GetUserCheckInsBetween(userID.(uint), startDate, endDate)
+	if err == nil {
+		err = models.LoadCheckInFoodRecords(checkIns)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取打卡记录失败: %v", err)})
+		return
+	}
+
+	// 将食物记录和打卡记录转换为字符串
+	recordsStr := formatFoodRecordsToString(foodRecords, loc) + formatCheckInsToString(checkIns, loc)
 
 	// 获取分析类型名称
 	analysisTypeName := getAnalysisTypeName(req.AnalysisType)
 
 	// 构建提示词
-	prompt := constructAnalysisPrompt(recordsStr, analysisTypeName, req.Description)
+	prompt := constructAnalysisPrompt(userID.(uint), recordsStr, analysisTypeName, req.Description)
+
+	// 配置了每天免费次数时占用一次分析额度，免费次数用完后消耗分析次数卡增加的次数
+	freeLimit := getDailyFreeHealthAnalyses()
+	today := calendar.DateKey(time.Now(), loc)
+	usedCredit := false
+	if freeLimit > 0 {
+		usedCredit, err = models.ReserveHealthAnalysis(userID.(uint), today, freeLimit)
+		if errors.Is(err, models.ErrNoAnalysisCredits) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("检查分析次数失败: %v", err)})
+			return
+		}
+	}
 
 	// 调用OpenAI获取分析结果
 	analysis, err := h.callOpenAI(prompt)
 	if err != nil {
+		// 分析失败不计入次数
+		if freeLimit > 0 {
+			if releaseErr := models.ReleaseHealthAnalysis(userID.(uint), today, usedCredit); releaseErr != nil {
+				log.Printf("归还用户 %d 的分析次数失败: %v", userID, releaseErr)
+			}
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("调用AI分析失败: %v", err)})
 		return
 	}
@@ -125,15 +173,33 @@ func (h *HealthAnalysisHandler) AnalyzeHealth(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"analysis": analysis})
 }
 
+// GetHealthAnalysisQuota 获取当前用户今天的健康分析额度
+func (h *HealthAnalysisHandler) GetHealthAnalysisQuota(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户未认证"})
+		return
+	}
+
+	today := calendar.DateKey(time.Now(), models.GetUserLocation(userID.(uint)))
+	quota, err := models.GetAnalysisQuota(userID.(uint), today, getDailyFreeHealthAnalyses())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分析次数失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": quota})
+}
+
 // 将食物记录格式化为字符串
-func formatFoodRecordsToString(records []models.FoodRecord) string {
+func formatFoodRecordsToString(records []models.FoodRecord, loc *time.Location) string {
 	var builder strings.Builder
 
 	builder.WriteString(fmt.Sprintf("食物记录总数: %d\n\n", len(records)))
 
 	for i, record := range records {
 		builder.WriteString(fmt.Sprintf("记录 #%d:\n", i+1))
-		builder.WriteString(fmt.Sprintf("- 时间: %s\n", record.RecordTime.Format("2006-01-02 15:04:05")))
+		builder.WriteString(fmt.Sprintf("- 时间: %s\n", record.RecordTime.In(loc).Format("2006-01-02 15:04:05")))
 		builder.WriteString(fmt.Sprintf("- 食物名称: %s\n", record.FoodName))
 		builder.WriteString(fmt.Sprintf("- 重量: %.1f克\n", record.Weight))
 		builder.WriteString(fmt.Sprintf("- 热量: %.1f卡路里\n", record.Calories))
@@ -176,6 +242,43 @@ func formatFoodRecordsToString(records []models.FoodRecord) string {
 	return builder.String()
 }
 
+// 将打卡记录格式化为字符串，没有打卡记录时返回空字符串
+func formatCheckInsToString(checkIns []models.CheckIn, loc *time.Location) string {
+	if len(checkIns) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("打卡记录总数: %d（评分为1-5分）\n\n", len(checkIns)))
+
+	for i, checkIn := range checkIns {
+		builder.WriteString(fmt.Sprintf("打卡 #%d:\n", i+1))
+		builder.WriteString(fmt.Sprintf("- 时间: %s\n", checkIn.CheckInAt.In(loc).Format("2006-01-02 15:04:05")))
+		if checkIn.Mood > 0 {
+			builder.WriteString(fmt.Sprintf("- 心情: %d分\n", checkIn.Mood))
+		}
+		if checkIn.Energy > 0 {
+			builder.WriteString(fmt.Sprintf("- 精力: %d分\n", checkIn.Energy))
+		}
+		if checkIn.Hunger > 0 {
+			builder.WriteString(fmt.Sprintf("- 饥饿感: %d分\n", checkIn.Hunger))
+		}
+		if len(checkIn.FoodRecords) > 0 {
+			names := make([]string, len(checkIn.FoodRecords))
+			for j, record := range checkIn.FoodRecords {
+				names[j] = record.FoodName
+			}
+			builder.WriteString(fmt.Sprintf("- 关联饮食: %s\n", strings.Join(names, "、")))
+		}
+		if checkIn.Content != "" {
+			builder.WriteString(fmt.Sprintf("- 打卡内容: %s\n", checkIn.Content))
+		}
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
+}
+
 // 获取分析类型的中文名称
 func getAnalysisTypeName(analysisType string) string {
 	switch analysisType {
@@ -193,10 +296,10 @@ func getAnalysisTypeName(analysisType string) string {
 }
 
 // 构建分析提示词
-func constructAnalysisPrompt(recordsStr, analysisType, userDescription string) string {
+func constructAnalysisPrompt(userID uint, recordsStr, analysisType, userDescription string) string {
 	// 获取用户最新的健康状态
 	var latestHealthState models.UserHealthState
-	result := models.DB.Order("created_at desc").First(&latestHealthState)
+	result := models.DB.Where("user_id = ?", userID).Order("created_at desc").First(&latestHealthState)
 	healthStateStr := ""
 	if result.Error == nil {
 		healthStateStr = fmt.Sprintf(`
@@ -213,28 +316,103 @@ func constructAnalysisPrompt(recordsStr, analysisType, userDescription string) s
 			latestHealthState.BMI,
 			latestHealthState.HeartRate,
 			latestHealthState.FastingGlucose,
-			latestHealthState.CreatedAt.Format("2006-01-02 15:04:05"))
+			latestHealthState.CreatedAt.In(models.GetUserLocation(userID)).Format("2006-01-02 15:04:05"))
 	}
 
+	// 获取用户资料和每日营养目标
+	profileStr := formatUserProfileToString(userID)
+
 	prompt := fmt.Sprintf(`你是一名专业的营养学家和健康顾问。请基于以下用户的饮食记录数据，进行%s。
 
 用户描述：%s
 
 用户当前基本状况：%s
-
+%s
 饮食记录数据：
 %s
 
 请提供分析结果，遵循以下要求：
 1. 首先给出一句20字以内的幽默、略带戏谑或鼓励的话。
-2. 然后基于数据进行专业、客观的分析，重点关注%s方面。
+2. 然后基于数据进行专业、客观的分析，重点关注%s方面。如有打卡记录，可结合心情、精力和饥饿感评分分析饮食对用户状态的影响。
 3. 必要时可以提供一些改进建议，但不要过于严厉，保持积极鼓励的态度。
 4. 整体分析不超过400字。
-`, analysisType, userDescription, healthStateStr, recordsStr, getAnalysisTypeDetails(analysisType))
+5. 建议必须尊重用户的饮食偏好和过敏源，不要推荐与之冲突的食物。
+`, analysisType, userDescription, healthStateStr, profileStr, recordsStr, getAnalysisTypeDetails(analysisType))
 
 	return prompt
 }
 
+// 将用户资料和每日营养目标格式化为字符串
+func formatUserProfileToString(userID uint) string {
+	profile, err := models.GetUserProfile(userID)
+	if err != nil {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString("\n用户资料：\n")
+	now := time.Now().In(profile.Location())
+	if age := profile.Age(now); age > 0 {
+		builder.WriteString(fmt.Sprintf("- 年龄: %d 岁\n", age))
+	}
+	if profile.Sex != "" {
+		builder.WriteString(fmt.Sprintf("- 性别: %s\n", getSexName(profile.Sex)))
+	}
+	builder.WriteString(fmt.Sprintf("- 活动水平: %s\n", getActivityLevelName(profile.ActivityLevel)))
+	if len(profile.DietaryPreferences) > 0 {
+		names := make([]string, 0, len(profile.DietaryPreferences))
+		for _, pref := range profile.DietaryPreferences {
+			names = append(names, models.DietaryPreferences[pref])
+		}
+		builder.WriteString(fmt.Sprintf("- 饮食偏好: %s\n", strings.Join(names, "、")))
+	}
+	if len(profile.Allergies) > 0 {
+		builder.WriteString(fmt.Sprintf("- 过敏源: %s\n", strings.Join(profile.Allergies, "、")))
+	}
+
+	target, err := models.GetUserNutritionTargets(userID)
+	if err == nil {
+		builder.WriteString("\n每日营养目标")
+		if target.Estimated {
+			builder.WriteString("（资料不完整，为通用参考值）")
+		}
+		builder.WriteString("：\n")
+		builder.WriteString(fmt.Sprintf("- 热量: %.0f 千卡\n", target.Calories))
+		builder.WriteString(fmt.Sprintf("- 蛋白质: %.0f克, 脂肪: %.0f克, 碳水化合物: %.0f克\n", target.Protein, target.TotalFat, target.Carbohydrates))
+		builder.WriteString(fmt.Sprintf("- 膳食纤维不少于 %.0f克, 糖不超过 %.0f克, 钠不超过 %.0fmg\n", target.Fiber, target.Sugar, target.Sodium))
+	}
+
+	return builder.String()
+}
+
+// 获取性别的中文名称
+func getSexName(sex string) string {
+	switch sex {
+	case models.SexMale:
+		return "男"
+	case models.SexFemale:
+		return "女"
+	default:
+		return "其他"
+	}
+}
+
+// 获取活动水平的中文名称
+func getActivityLevelName(level string) string {
+	switch level {
+	case models.ActivityLight:
+		return "轻度活动"
+	case models.ActivityModerate:
+		return "中度活动"
+	case models.ActivityActive:
+		return "高度活动"
+	case models.ActivityVeryActive:
+		return "极高强度活动"
+	default:
+		return "久坐"
+	}
+}
+
 // 获取分析类型的详细关注点
 func getAnalysisTypeDetails(analysisType string) string {
 	switch analysisType {
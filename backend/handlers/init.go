@@ -1,9 +1,17 @@
 package handlers
 
 import (
+	"backend/achievement"
+	"backend/config"
+	"backend/effect"
 	"backend/models"
+	"backend/seed"
+	"backend/streak"
+	"errors"
+	"fmt"
 	"log"
 
+	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -15,6 +23,36 @@ func InitDB() {
 
 	// 检查管理员用户
 	ensureAdminUser()
+
+	// 创建连续打卡使用的冻结卡、补签卡物品
+	if err := streak.EnsureItems(); err != nil {
+		log.Printf("初始化连续打卡物品失败: %v", err)
+	}
+
+	// 创建内置使用效果对应的消耗品
+	if err := effect.EnsureItems(); err != nil {
+		log.Printf("初始化消耗品物品失败: %v", err)
+	}
+
+	// 按版本创建成就物品目录，已执行过的版本不会重复执行
+	if err := seed.Run(); err != nil {
+		log.Printf("初始化物品目录失败: %v", err)
+	}
+
+	// 从设计稿目录复制成就物品的图标和图片
+	if err := seed.CopyItemAssets(); err != nil {
+		log.Printf("复制成就物品图片失败: %v", err)
+	}
+
+	// 为没有标识的物品生成标识，批量导入导出按标识匹配物品
+	if err := models.EnsureItemKeys(); err != nil {
+		log.Printf("生成物品标识失败: %v", err)
+	}
+
+	// 为已有的成就物品创建内置成就规则
+	if err := achievement.EnsureDefaultRules(); err != nil {
+		log.Printf("初始化内置成就规则失败: %v", err)
+	}
 }
 
 // GetDB 获取数据库连接
@@ -23,27 +61,70 @@ func GetDB() *gorm.DB {
 }
 
 // ensureAdminUser 确保系统中存在管理员用户
+// 首次启动时根据 ADMIN_EMAIL / ADMIN_PASSWORD 创建管理员；
+// 生产模式下若既没有管理员也没有配置管理员密码，则拒绝启动
 func ensureAdminUser() {
-	// 创建默认管理员账户
 	var adminCount int64
-	models.DB.Model(&models.User{}).Where("role = ?", "admin").Count(&adminCount)
-	if adminCount == 0 {
-		// 生成密码哈希
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte("admin123"), bcrypt.DefaultCost)
-		if err != nil {
-			log.Fatal("生成管理员密码哈希失败：", err)
-		}
+	if err := models.DB.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&adminCount).Error; err != nil {
+		log.Fatal("查询管理员账户失败：", err)
+	}
+	if adminCount > 0 {
+		return
+	}
 
-		adminUser := models.User{
-			Name:     "管理员",
-			Email:    "admin@example.com",
-			Password: string(hashedPassword),
-			Role:     "admin",
-			Verified: true,
-		}
-		if result := models.DB.Create(&adminUser); result.Error != nil {
-			log.Fatal("创建管理员账户失败：", result.Error)
+	conf := config.GetConfig()
+	if conf.AdminEmail == "" || conf.AdminPassword == "" {
+		if gin.Mode() == gin.ReleaseMode {
+			log.Fatal("错误：系统中没有管理员账户，且未设置 ADMIN_EMAIL / ADMIN_PASSWORD，拒绝在生产模式下启动")
 		}
-		log.Println("默认管理员账户已创建：admin@example.com / admin123")
+		log.Println("警告：系统中没有管理员账户，可设置 ADMIN_EMAIL / ADMIN_PASSWORD 或执行 `backend admin create` 创建")
+		return
+	}
+
+	if _, err := CreateAdminUser(conf.AdminName, conf.AdminEmail, conf.AdminPassword, false); err != nil {
+		log.Fatal("创建管理员账户失败：", err)
+	}
+	log.Printf("管理员账户已创建：%s", conf.AdminEmail)
+}
+
+// ErrAdminEmailTaken 邮箱已被其他账户使用，未指定 force 时不会提升为管理员
+var ErrAdminEmailTaken = errors.New("该邮箱已注册，如需将其提升为管理员并重置密码，请使用 backend admin create -force")
+
+// CreateAdminUser 创建管理员账户
+// 邮箱已存在时返回 ErrAdminEmailTaken，force 为 true 时将其提升为管理员并重置密码
+func CreateAdminUser(name, email, password string, force bool) (*models.User, error) {
+	if email == "" {
+		return nil, errors.New("邮箱不能为空")
+	}
+	if len(password) < 6 {
+		return nil, errors.New("密码长度不能少于6位")
+	}
+
+	// 生成密码哈希
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("生成密码哈希失败: %v", err)
+	}
+
+	var user models.User
+	err = models.DB.Where("email = ?", email).First(&user).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("查询用户失败: %v", err)
+	}
+	if err == nil && !force {
+		return nil, ErrAdminEmailTaken
+	}
+
+	if name != "" {
+		user.Name = name
+	}
+	user.Email = email
+	user.Password = string(hashedPassword)
+	user.Role = models.RoleAdmin
+	user.Verified = true
+
+	if err := models.DB.Save(&user).Error; err != nil {
+		return nil, fmt.Errorf("保存管理员账户失败: %v", err)
 	}
+	return &user, nil
 }
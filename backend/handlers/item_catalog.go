@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"backend/catalog"
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCatalogPackageSize 物品导入包的最大大小
+const maxCatalogPackageSize = 50 << 20
+
+// ImportItems 通过 ZIP 导入包批量创建或更新物品（管理员）
+// 包内需包含 manifest.json 或 manifest.csv，dry_run=true 时只校验不写入
+func ImportItems(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请选择要导入的 ZIP 文件"})
+		return
+	}
+	if file.Size > maxCatalogPackageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("导入包大小超过限制（最大%dMB）", maxCatalogPackageSize>>20)})
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.DefaultPostForm("dry_run", c.Query("dry_run")))
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取导入包失败"})
+		return
+	}
+	defer src.Close()
+
+	result, err := catalog.Import(src, file.Size, dryRun)
+	if errors.Is(err, catalog.ErrInvalidPackage) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "data": result})
+		return
+	}
+	if err != nil {
+		log.Printf("导入物品失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导入物品失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// ExportItems 将全部物品导出为 ZIP 包，格式与导入相同（管理员）
+func ExportItems(c *gin.Context) {
+	format := c.DefaultQuery("format", catalog.FormatJSON)
+	if format != catalog.FormatJSON && format != catalog.FormatCSV {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format 只能为 json 或 csv"})
+		return
+	}
+
+	// 先写入内存，导出失败时仍能返回错误
+	var buf bytes.Buffer
+	if err := catalog.Export(&buf, format); err != nil {
+		log.Printf("导出物品失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导出物品失败"})
+		return
+	}
+
+	filename := fmt.Sprintf("items_%s.zip", time.Now().Format("20060102150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
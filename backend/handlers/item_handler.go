@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -17,6 +18,10 @@ func CreateItem(c *gin.Context) {
 		return
 	}
 
+	if !validateItemRequest(c, &item) {
+		return
+	}
+
 	// 检查物品名称是否已存在
 	exists, err := models.ExistsItemByName(item.Name)
 	if err != nil {
@@ -28,6 +33,18 @@ func CreateItem(c *gin.Context) {
 		return
 	}
 
+	if item.Key != nil {
+		exists, err := models.ExistsItemByKey(*item.Key)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "检查物品标识时发生错误"})
+			return
+		}
+		if exists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "该物品标识已存在"})
+			return
+		}
+	}
+
 	if err := models.CreateItem(&item); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建物品失败"})
 		return
@@ -54,25 +71,9 @@ func GetItem(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": item})
 }
 
-// GetItems 获取物品列表（支持分页）
+// GetItems 获取物品列表（支持分页、筛选和排序）
 func GetItems(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
-
-	items, total, err := models.GetAllItems(page, pageSize)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取物品列表失败"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"data": items,
-		"meta": gin.H{
-			"total":     total,
-			"page":      page,
-			"page_size": pageSize,
-		},
-	})
+	listItems(c, parseItemFilter(c))
 }
 
 // UpdateItem 更新物品信息
@@ -91,11 +92,21 @@ func UpdateItem(c *gin.Context) {
 		return
 	}
 
-	// 绑定更新数据
+	// 绑定更新数据，物品标识创建后不可修改
+	key := existingItem.Key
 	if err := c.ShouldBindJSON(existingItem); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
 		return
 	}
+	if existingItem.Key != nil && (key == nil || *existingItem.Key != *key) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "物品标识创建后不可修改"})
+		return
+	}
+	existingItem.Key = key
+
+	if !validateItemRequest(c, existingItem) {
+		return
+	}
 
 	if err := models.UpdateItem(existingItem); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新物品失败"})
@@ -122,29 +133,45 @@ func DeleteItem(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "物品已成功删除"})
 }
 
-// SearchItems 搜索物品
+// SearchItems 在物品名称和介绍中全文搜索，支持与 GetItems 相同的筛选、排序和分页
 func SearchItems(c *gin.Context) {
-	name := c.Query("name")
-	source := c.Query("source")
-
-	var items []models.Item
-	var err error
-
-	if name != "" {
-		items, err = models.GetItemsByName(name)
-	} else if source != "" {
-		items, err = models.GetItemsBySource(source)
-	} else {
+	filter := parseItemFilter(c)
+	if filter.Keyword == "" && filter.Source == "" && filter.Rarity == "" && filter.Category == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "请提供搜索条件"})
 		return
 	}
+	listItems(c, filter)
+}
 
+// parseItemFilter 解析物品列表的查询参数：q（兼容旧参数 name）、source、rarity、category、sort
+func parseItemFilter(c *gin.Context) models.ItemFilter {
+	keyword := c.Query("q")
+	if keyword == "" {
+		keyword = c.Query("name")
+	}
+	return models.ItemFilter{
+		Keyword:  keyword,
+		Source:   c.Query("source"),
+		Rarity:   c.Query("rarity"),
+		Category: c.Query("category"),
+		Sort:     c.Query("sort"),
+	}
+}
+
+// listItems 按筛选条件返回分页的物品列表
+func listItems(c *gin.Context, filter models.ItemFilter) {
+	page, pageSize := parsePagination(c, 10)
+	items, total, err := models.SearchItems(filter, page, pageSize)
+	if errors.Is(err, models.ErrInvalidItemFilter) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索物品失败"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取物品列表失败"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": items})
+	c.JSON(http.StatusOK, paginated(items, total, page, pageSize))
 }
 
 // GetItemSources 获取所有物品来源
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"backend/effect"
+	"backend/models"
+	"backend/streak"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UseItemRequest 使用物品请求
+type UseItemRequest struct {
+	Quantity int                    `json:"quantity"` // 使用数量，默认1
+	Params   map[string]interface{} `json:"params"`   // 效果参数，如冻结卡的 {"date": "2024-01-01"}
+}
+
+// UseItem 使用当前用户持有的消耗品，扣减数量并触发物品的使用效果
+func UseItem(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的物品ID"})
+		return
+	}
+
+	var req UseItemRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+			return
+		}
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+
+	result, err := effect.UseItem(userID.(uint), uint(itemID), req.Quantity, req.Params)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "物品不存在"})
+		case errors.Is(err, effect.ErrNotConsumable),
+			errors.Is(err, effect.ErrNoEffect),
+			errors.Is(err, effect.ErrInvalidQuantity),
+			errors.Is(err, models.ErrInsufficientItems),
+			errors.Is(err, streak.ErrInvalidFreezeDate),
+			errors.Is(err, streak.ErrFreezeLimit):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, models.ErrStreakDayTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "使用物品失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// GetItemEffects 获取可配置给消耗品的使用效果（管理员专用）
+func GetItemEffects(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"effects":    effect.Names(),
+			"rarities":   models.Rarities,
+			"categories": models.Categories,
+		},
+	})
+}
+
+// validateItemRequest 校验物品的稀有度、分类和使用效果，校验失败时写入错误响应并返回 false
+func validateItemRequest(c *gin.Context, item *models.Item) bool {
+	if err := models.ValidateItem(item); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if item.Effect != "" && !effect.Registered(item.Effect) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未知的使用效果: " + item.Effect})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"backend/models"
+	"backend/moderation"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 内容审核的提示信息
+const (
+	contentRejectedMessage = "内容包含不当信息，请修改后重试"
+	imageRejectedMessage   = "图片包含不当内容，请更换后重试"
+	pendingReviewMessage   = "内容正在审核，通过后其他用户才能看到"
+)
+
+// ModerationKeywordRequest 添加审核关键词请求
+type ModerationKeywordRequest struct {
+	Word   string `json:"word" binding:"required"`
+	Action string `json:"action"` // review 或 reject，默认 review
+}
+
+// ModerationReviewRequest 处理审核记录请求
+type ModerationReviewRequest struct {
+	Note string `json:"note"` // 处理备注，下架时会发送给作者
+}
+
+// TakeDownRequest 直接下架内容请求
+type TakeDownRequest struct {
+	ContentType string `json:"content_type" binding:"required"` // post、post_comment 或 check_in
+	ContentID   uint   `json:"content_id" binding:"required"`
+	Note        string `json:"note"`
+}
+
+// moderateImages 审核已保存的用户图片，返回合并后的结果，遇到拒绝的图片时不再继续
+func moderateImages(c *gin.Context, urls []string) *moderation.Result {
+	result := moderation.Allowed()
+	for _, url := range urls {
+		path, ok := localStaticPath(url)
+		if !ok {
+			continue
+		}
+		result.Merge(moderation.CheckImage(c.Request.Context(), path))
+		if result.Rejected() {
+			break
+		}
+	}
+	return result
+}
+
+// AdminGetModerationCases 分页获取审核记录（管理员专用），status 可选 pending、approved、removed
+func AdminGetModerationCases(c *gin.Context) {
+	page, pageSize := parsePagination(c, 20)
+
+	status := c.Query("status")
+	switch status {
+	case "", models.ModerationStatusPending, models.ModerationStatusApproved, models.ModerationStatusRemoved:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的审核状态"})
+		return
+	}
+	contentType := c.Query("content_type")
+	if contentType != "" && !models.IsValidModerationContentType(contentType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的内容类型"})
+		return
+	}
+
+	cases, total, err := models.GetModerationCasesPage(status, contentType, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取审核记录失败"})
+		return
+	}
+	c.JSON(http.StatusOK, paginated(cases, total, page, pageSize))
+}
+
+// AdminApproveModerationCase 审核通过，内容对其他用户可见（管理员专用）
+func AdminApproveModerationCase(c *gin.Context) {
+	resolveModerationCase(c, true)
+}
+
+// AdminRemoveModerationCase 审核不通过，下架内容并通知作者（管理员专用）
+func AdminRemoveModerationCase(c *gin.Context) {
+	resolveModerationCase(c, false)
+}
+
+func resolveModerationCase(c *gin.Context, approve bool) {
+	userID, _ := c.Get("user_id")
+
+	caseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的审核记录ID"})
+		return
+	}
+	var req ModerationReviewRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+			return
+		}
+	}
+	note, ok := parseModerationNote(c, req.Note)
+	if !ok {
+		return
+	}
+
+	mc, err := moderation.Resolve(uint(caseID), approve, userID.(uint), note)
+	if err != nil {
+		respondModerationError(c, err, "处理审核记录失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": mc})
+}
+
+// AdminTakeDownContent 直接下架已发布的内容并通知作者（管理员专用）
+func AdminTakeDownContent(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req TakeDownRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	if !models.IsValidModerationContentType(req.ContentType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的内容类型"})
+		return
+	}
+	note, ok := parseModerationNote(c, req.Note)
+	if !ok {
+		return
+	}
+
+	mc, err := moderation.TakeDown(req.ContentType, req.ContentID, userID.(uint), note)
+	if err != nil {
+		respondModerationError(c, err, "下架内容失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": mc})
+}
+
+// AdminGetQuarantinedImage 查看待审核或已下架内容被隔离的图片（管理员专用）
+// 路径与原图片URL中 /static 之后的部分相同，例如 /static/posts/a.jpg 对应 /moderation/images/posts/a.jpg
+func AdminGetQuarantinedImage(c *gin.Context) {
+	path, ok := localStaticPath("/static" + c.Param("filepath"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的图片路径"})
+		return
+	}
+	path = moderation.QuarantinedPath(path)
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "图片不存在"})
+		return
+	}
+	c.File(path)
+}
+
+// AdminGetModerationKeywords 获取审核关键词列表（管理员专用）
+func AdminGetModerationKeywords(c *gin.Context) {
+	keywords, err := models.GetModerationKeywords()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取审核关键词失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": keywords})
+}
+
+// AdminCreateModerationKeyword 添加审核关键词，已存在时更新处理方式（管理员专用）
+func AdminCreateModerationKeyword(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req ModerationKeywordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	word := strings.TrimSpace(req.Word)
+	if word == "" || utf8.RuneCountInString(word) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "关键词长度应在1到100个字符之间"})
+		return
+	}
+	action := req.Action
+	if action == "" {
+		action = moderation.VerdictReview
+	}
+	if !moderation.IsValidKeywordAction(action) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action 只能是 review 或 reject"})
+		return
+	}
+
+	keyword, err := models.SaveModerationKeyword(word, action, userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存审核关键词失败"})
+		return
+	}
+	moderation.ReloadKeywords()
+	c.JSON(http.StatusOK, gin.H{"data": keyword})
+}
+
+// AdminDeleteModerationKeyword 删除审核关键词（管理员专用）
+func AdminDeleteModerationKeyword(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的关键词ID"})
+		return
+	}
+
+	err = models.DeleteModerationKeyword(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "关键词不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除审核关键词失败"})
+		return
+	}
+	moderation.ReloadKeywords()
+	c.JSON(http.StatusOK, gin.H{"message": "关键词已删除"})
+}
+
+// parseModerationNote 校验处理备注，校验失败时写入错误响应并返回 false
+func parseModerationNote(c *gin.Context, note string) (string, bool) {
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "备注不能超过500个字符"})
+		return "", false
+	}
+	return note, true
+}
+
+// respondModerationError 将审核操作的错误转换为响应
+func respondModerationError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "审核记录或内容不存在"})
+	case errors.Is(err, models.ErrModerationCaseResolved):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrInvalidModerationTarget):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
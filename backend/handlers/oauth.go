@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"backend/identity"
+	"backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 第三方账号没有邮箱时使用的占位邮箱域名（.invalid 为保留顶级域名，不会真实存在）
+const oauthPlaceholderEmailDomain = "oauth.invalid"
+
+// errOAuthEmailUnverified 第三方账号的邮箱已被未验证的本地账号注册，不能自动关联
+var errOAuthEmailUnverified = errors.New("该邮箱已注册但尚未验证，请先验证邮箱并登录，再在账号设置中绑定第三方账号")
+
+// OAuthCodeRequest 第三方登录/绑定请求
+type OAuthCodeRequest struct {
+	Code        string `json:"code" binding:"required"`
+	RedirectURI string `json:"redirect_uri"`
+}
+
+// GetOAuthProviders 获取已启用的第三方登录方式
+func GetOAuthProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": identity.Names()})
+}
+
+// OAuthLogin 使用第三方身份登录，首次登录时自动关联已有账号或创建新账号
+func OAuthLogin(c *gin.Context) {
+	ident, ok := exchangeOAuthCode(c)
+	if !ok {
+		return
+	}
+
+	user, isNewUser, err := resolveOAuthUser(ident)
+	if errors.Is(err, errOAuthEmailUnverified) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("第三方登录失败(%s): %v", ident.Provider, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "第三方登录失败"})
+		return
+	}
+
+	token, err := generateToken(*user)
+	if err != nil {
+		log.Printf("生成token失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+		return
+	}
+
+	log.Printf("第三方登录成功: %s 用户ID %d", ident.Provider, user.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"token":       token,
+		"user":        user,
+		"is_new_user": isNewUser,
+	})
+}
+
+// LinkIdentity 为当前登录用户绑定第三方身份
+func LinkIdentity(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	ident, ok := exchangeOAuthCode(c)
+	if !ok {
+		return
+	}
+
+	existing, err := models.GetUserIdentity(ident.Provider, ident.Subject)
+	if err == nil {
+		if existing.UserID != userID.(uint) {
+			c.JSON(http.StatusConflict, gin.H{"error": "该第三方账号已绑定其他用户"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "已绑定", "identity": existing})
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询第三方账号失败"})
+		return
+	}
+
+	userIdentity := newUserIdentity(userID.(uint), ident)
+	if err := models.CreateUserIdentity(userIdentity); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "绑定第三方账号失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "绑定成功", "identity": userIdentity})
+}
+
+// GetMyIdentities 获取当前用户绑定的第三方身份
+func GetMyIdentities(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	identities, err := models.GetUserIdentities(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取第三方账号失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+// UnlinkIdentity 解除当前用户与第三方身份的绑定
+func UnlinkIdentity(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+	provider := c.Param("provider")
+
+	var user models.User
+	if err := models.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	identities, err := models.GetUserIdentities(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取第三方账号失败"})
+		return
+	}
+
+	linked := false
+	for _, identity := range identities {
+		if identity.Provider == provider {
+			linked = true
+			break
+		}
+	}
+	if !linked {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未绑定该第三方账号"})
+		return
+	}
+
+	// 没有设置密码的账号至少要保留一种登录方式
+	if user.Password == "" && len(identities) <= 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "这是账号唯一的登录方式，无法解绑"})
+		return
+	}
+
+	if err := models.DeleteUserIdentity(user.ID, provider); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "解绑第三方账号失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "解绑成功"})
+}
+
+// exchangeOAuthCode 解析请求并向对应的身份提供方换取用户身份
+func exchangeOAuthCode(c *gin.Context) (*identity.Identity, bool) {
+	provider, err := identity.Get(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "不支持的登录方式"})
+		return nil, false
+	}
+
+	var req OAuthCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return nil, false
+	}
+
+	ident, err := provider.Exchange(c.Request.Context(), req.Code, req.RedirectURI)
+	if err != nil {
+		log.Printf("第三方身份验证失败(%s): %v", provider.Name(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "第三方身份验证失败"})
+		return nil, false
+	}
+
+	return ident, true
+}
+
+// resolveOAuthUser 查找第三方身份对应的用户：
+// 已绑定则直接返回；提供方验证过的邮箱与已验证的本地账号一致则自动绑定；否则创建新用户
+// 邮箱属于未验证的本地账号时返回 errOAuthEmailUnverified，避免他人抢先用该邮箱注册后接管第三方账号
+func resolveOAuthUser(ident *identity.Identity) (*models.User, bool, error) {
+	var user models.User
+
+	existing, err := models.GetUserIdentity(ident.Provider, ident.Subject)
+	if err == nil {
+		if err := models.DB.First(&user, existing.UserID).Error; err != nil {
+			return nil, false, fmt.Errorf("获取绑定用户失败: %v", err)
+		}
+		models.TouchUserIdentity(existing)
+		return &user, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	isNewUser := false
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		found := false
+		if ident.Email != "" && ident.EmailVerified {
+			err := tx.Where("email = ?", ident.Email).First(&user).Error
+			if err == nil {
+				if err := checkOAuthAutoLink(&user); err != nil {
+					return err
+				}
+				found = true
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+
+		if !found {
+			user = newOAuthUser(ident)
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			isNewUser = true
+		}
+
+		return tx.Create(newUserIdentity(user.ID, ident)).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &user, isNewUser, nil
+}
+
+// checkOAuthAutoLink 检查邮箱相同的本地账号能否自动关联第三方身份，只有验证过邮箱的账号才可以
+func checkOAuthAutoLink(user *models.User) error {
+	if !user.Verified {
+		return errOAuthEmailUnverified
+	}
+	return nil
+}
+
+// newOAuthUser 为第三方身份创建新用户，没有提供方验证过的邮箱时使用占位邮箱
+func newOAuthUser(ident *identity.Identity) models.User {
+	email := ident.Email
+	if email == "" || !ident.EmailVerified {
+		email = fmt.Sprintf("%s_%s@%s", ident.Provider, ident.Subject, oauthPlaceholderEmailDomain)
+	}
+	name := ident.Name
+	if name == "" {
+		name = "用户"
+	}
+	return models.User{
+		Name:     name,
+		Email:    email,
+		Role:     models.RoleUser,
+		Verified: true,
+	}
+}
+
+func newUserIdentity(userID uint, ident *identity.Identity) *models.UserIdentity {
+	return &models.UserIdentity{
+		UserID:      userID,
+		Provider:    ident.Provider,
+		Subject:     ident.Subject,
+		UnionID:     ident.UnionID,
+		Email:       ident.Email,
+		LastLoginAt: time.Now(),
+	}
+}
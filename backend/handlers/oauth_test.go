@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"backend/identity"
+	"backend/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeProvider 测试用的身份提供方，code 直接对应预设的身份
+type fakeProvider struct {
+	identities map[string]*identity.Identity
+}
+
+func (fakeProvider) Name() string {
+	return "fake"
+}
+
+func (p fakeProvider) Exchange(_ context.Context, code, _ string) (*identity.Identity, error) {
+	ident, ok := p.identities[code]
+	if !ok {
+		return nil, fmt.Errorf("无效的 code: %s", code)
+	}
+	copied := *ident
+	copied.Provider = p.Name()
+	return &copied, nil
+}
+
+// newOAuthRouter 注册与 main.go 相同路径的第三方登录路由，userID 不为 0 时模拟已登录用户
+func newOAuthRouter(userID uint) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/oauth/:provider/login", OAuthLogin)
+	r.POST("/api/me/identities/:provider", func(c *gin.Context) {
+		if userID != 0 {
+			c.Set("user_id", userID)
+		}
+	}, LinkIdentity)
+	return r
+}
+
+func postJSON(r *gin.Engine, path string, body interface{}) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+var testDBOnce sync.Once
+
+// setupTestDB 连接 TEST_DB_NAME 指定的 MySQL 测试库（连接参数与 DB_HOST 等配置相同），未设置时跳过测试
+// 测试会写入用户和第三方身份，不要指向正式数据库
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	name := os.Getenv("TEST_DB_NAME")
+	if name == "" {
+		t.Skip("未设置 TEST_DB_NAME，跳过需要数据库的测试")
+	}
+	testDBOnce.Do(func() {
+		os.Setenv("DB_NAME", name)
+		models.InitDB()
+	})
+}
+
+// uniqueSuffix 生成本次测试唯一的后缀，避免与之前运行留下的数据冲突
+func uniqueSuffix() string {
+	return fmt.Sprintf("%d", time.Now().UnixNano())
+}
+
+func createTestUser(t *testing.T, email string, verified bool) *models.User {
+	t.Helper()
+	user := &models.User{Name: "测试用户", Email: email, Role: models.RoleUser, Verified: verified}
+	if err := models.DB.Create(user).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	return user
+}
+
+type oauthLoginResponse struct {
+	Token     string      `json:"token"`
+	User      models.User `json:"user"`
+	IsNewUser bool        `json:"is_new_user"`
+	Error     string      `json:"error"`
+}
+
+func decodeLogin(t *testing.T, w *httptest.ResponseRecorder) oauthLoginResponse {
+	t.Helper()
+	var resp oauthLoginResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v (%s)", err, w.Body.String())
+	}
+	return resp
+}
+
+func TestOAuthRequestValidation(t *testing.T) {
+	identity.Register(fakeProvider{})
+
+	tests := []struct {
+		name     string
+		path     string
+		userID   uint
+		body     interface{}
+		wantCode int
+	}{
+		{"登录-不支持的提供方", "/api/oauth/unknown/login", 0, OAuthCodeRequest{Code: "a"}, http.StatusNotFound},
+		{"登录-缺少 code", "/api/oauth/fake/login", 0, map[string]string{}, http.StatusBadRequest},
+		{"登录-提供方验证失败", "/api/oauth/fake/login", 0, OAuthCodeRequest{Code: "bad"}, http.StatusUnauthorized},
+		{"绑定-未登录", "/api/me/identities/fake", 0, OAuthCodeRequest{Code: "a"}, http.StatusUnauthorized},
+		{"绑定-不支持的提供方", "/api/me/identities/unknown", 1, OAuthCodeRequest{Code: "a"}, http.StatusNotFound},
+		{"绑定-提供方验证失败", "/api/me/identities/fake", 1, OAuthCodeRequest{Code: "bad"}, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postJSON(newOAuthRouter(tt.userID), tt.path, tt.body)
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d (%s)", w.Code, tt.wantCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestOAuthLogin(t *testing.T) {
+	setupTestDB(t)
+	suffix := uniqueSuffix()
+	existing := createTestUser(t, "existing_"+suffix+"@example.com", true)
+	unverifiedLocal := createTestUser(t, "unverified_local_"+suffix+"@example.com", false)
+
+	identity.Register(fakeProvider{identities: map[string]*identity.Identity{
+		"new":        {Subject: "new_" + suffix, Name: "新用户"},
+		"verified":   {Subject: "verified_" + suffix, Email: existing.Email, EmailVerified: true},
+		"unverified": {Subject: "unverified_" + suffix, Email: existing.Email},
+		"takeover":   {Subject: "takeover_" + suffix, Email: unverifiedLocal.Email, EmailVerified: true},
+	}})
+	r := newOAuthRouter(0)
+
+	t.Run("首次登录创建新用户", func(t *testing.T) {
+		resp := decodeLogin(t, postJSON(r, "/api/oauth/fake/login", OAuthCodeRequest{Code: "new"}))
+		if !resp.IsNewUser || resp.Token == "" {
+			t.Fatalf("响应 = %+v，期望创建新用户并返回 token", resp)
+		}
+		wantEmail := fmt.Sprintf("fake_new_%s@%s", suffix, oauthPlaceholderEmailDomain)
+		if resp.User.Email != wantEmail {
+			t.Errorf("email = %s, want %s", resp.User.Email, wantEmail)
+		}
+
+		again := decodeLogin(t, postJSON(r, "/api/oauth/fake/login", OAuthCodeRequest{Code: "new"}))
+		if again.IsNewUser || again.User.ID != resp.User.ID {
+			t.Errorf("再次登录 = 用户 %d（新用户 %v），want 用户 %d", again.User.ID, again.IsNewUser, resp.User.ID)
+		}
+	})
+
+	t.Run("已验证邮箱自动关联已有账号", func(t *testing.T) {
+		resp := decodeLogin(t, postJSON(r, "/api/oauth/fake/login", OAuthCodeRequest{Code: "verified"}))
+		if resp.IsNewUser || resp.User.ID != existing.ID {
+			t.Fatalf("登录到用户 %d（新用户 %v），want 已有用户 %d", resp.User.ID, resp.IsNewUser, existing.ID)
+		}
+		linked, err := models.GetUserIdentity("fake", "verified_"+suffix)
+		if err != nil || linked.UserID != existing.ID {
+			t.Errorf("身份关联 = %+v, %v，want 关联到用户 %d", linked, err, existing.ID)
+		}
+	})
+
+	t.Run("未验证邮箱不关联已有账号", func(t *testing.T) {
+		resp := decodeLogin(t, postJSON(r, "/api/oauth/fake/login", OAuthCodeRequest{Code: "unverified"}))
+		if !resp.IsNewUser || resp.User.ID == existing.ID {
+			t.Fatalf("登录到用户 %d（新用户 %v），不应关联已有用户 %d", resp.User.ID, resp.IsNewUser, existing.ID)
+		}
+		if resp.User.Email == existing.Email {
+			t.Errorf("新用户不应使用未验证的邮箱 %s", resp.User.Email)
+		}
+	})
+
+	t.Run("邮箱属于未验证的本地账号时拒绝登录", func(t *testing.T) {
+		w := postJSON(r, "/api/oauth/fake/login", OAuthCodeRequest{Code: "takeover"})
+		if w.Code != http.StatusConflict {
+			t.Fatalf("status = %d, want %d (%s)", w.Code, http.StatusConflict, w.Body.String())
+		}
+		if _, err := models.GetUserIdentity("fake", "takeover_"+suffix); err == nil {
+			t.Errorf("第三方身份不应关联到未验证的账号 %d", unverifiedLocal.ID)
+		}
+	})
+}
+
+func TestCheckOAuthAutoLink(t *testing.T) {
+	tests := []struct {
+		name     string
+		verified bool
+		wantErr  error
+	}{
+		{"已验证的本地账号", true, nil},
+		{"未验证的本地账号", false, errOAuthEmailUnverified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkOAuthAutoLink(&models.User{Email: "a@example.com", Verified: tt.verified})
+			if err != tt.wantErr {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewOAuthUser(t *testing.T) {
+	placeholder := "fake_sub@" + oauthPlaceholderEmailDomain
+
+	tests := []struct {
+		name      string
+		ident     identity.Identity
+		wantEmail string
+		wantName  string
+	}{
+		{"已验证邮箱", identity.Identity{Provider: "fake", Subject: "sub", Email: "a@example.com", EmailVerified: true, Name: "小明"}, "a@example.com", "小明"},
+		{"未验证邮箱使用占位邮箱", identity.Identity{Provider: "fake", Subject: "sub", Email: "a@example.com"}, placeholder, "用户"},
+		{"没有邮箱使用占位邮箱", identity.Identity{Provider: "fake", Subject: "sub"}, placeholder, "用户"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := newOAuthUser(&tt.ident)
+			if user.Email != tt.wantEmail || user.Name != tt.wantName {
+				t.Errorf("user = %s/%s, want %s/%s", user.Email, user.Name, tt.wantEmail, tt.wantName)
+			}
+			if !user.Verified || user.Role != models.RoleUser {
+				t.Errorf("新用户应为已验证的普通用户，got verified=%v role=%s", user.Verified, user.Role)
+			}
+		})
+	}
+}
+
+func TestLinkIdentity(t *testing.T) {
+	setupTestDB(t)
+	suffix := uniqueSuffix()
+	owner := createTestUser(t, "owner_"+suffix+"@example.com", true)
+	other := createTestUser(t, "other_"+suffix+"@example.com", true)
+
+	identity.Register(fakeProvider{identities: map[string]*identity.Identity{
+		"link": {Subject: "link_" + suffix},
+	}})
+
+	tests := []struct {
+		name     string
+		userID   uint
+		wantCode int
+		wantMsg  string
+	}{
+		{"首次绑定", owner.ID, http.StatusOK, "绑定成功"},
+		{"重复绑定", owner.ID, http.StatusOK, "已绑定"},
+		{"已绑定其他用户", other.ID, http.StatusConflict, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postJSON(newOAuthRouter(tt.userID), "/api/me/identities/fake", OAuthCodeRequest{Code: "link"})
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			var resp struct {
+				Message string `json:"message"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if resp.Message != tt.wantMsg {
+				t.Errorf("message = %q, want %q", resp.Message, tt.wantMsg)
+			}
+		})
+	}
+
+	linked, err := models.GetUserIdentity("fake", "link_"+suffix)
+	if err != nil || linked.UserID != owner.ID {
+		t.Errorf("身份关联 = %+v, %v，want 关联到用户 %d", linked, err, owner.ID)
+	}
+
+	// 绑定后可以用第三方身份登录到原账号
+	resp := decodeLogin(t, postJSON(newOAuthRouter(0), "/api/oauth/fake/login", OAuthCodeRequest{Code: "link"}))
+	if resp.IsNewUser || resp.User.ID != owner.ID {
+		t.Errorf("登录到用户 %d（新用户 %v），want 用户 %d", resp.User.ID, resp.IsNewUser, owner.ID)
+	}
+}
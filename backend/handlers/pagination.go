@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parsePagination 解析 page/page_size 查询参数，page_size 超出 1-100 时使用默认值
+func parsePagination(c *gin.Context, defaultPageSize int) (page, pageSize int) {
+	page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ = strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = defaultPageSize
+	}
+	return page, pageSize
+}
+
+// paginated 生成分页列表响应
+func paginated(data interface{}, total int64, page, pageSize int) gin.H {
+	return gin.H{
+		"data": data,
+		"meta": gin.H{
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	}
+}
@@ -0,0 +1,451 @@
+package handlers
+
+import (
+	"backend/models"
+	"backend/moderation"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxPostContentLength 帖子内容的最大长度（字符）
+const maxPostContentLength = 5000
+
+// CreatePostRequest 发布帖子请求，使用 multipart 表单时可通过 images 字段上传多张图片
+type CreatePostRequest struct {
+	Title        string `json:"title" form:"title"`
+	Content      string `json:"content" form:"content"`
+	FoodRecordID *uint  `json:"food_record_id" form:"food_record_id"` // 关联的饮食记录（可选）
+}
+
+// validate 校验帖子内容，返回错误信息（为空表示通过）
+func (r *CreatePostRequest) validate(imageCount int) string {
+	r.Title = strings.TrimSpace(r.Title)
+	r.Content = strings.TrimSpace(r.Content)
+	if r.Content == "" && imageCount == 0 {
+		return "帖子内容和图片不能都为空"
+	}
+	if utf8.RuneCountInString(r.Title) > 100 {
+		return "标题不能超过100个字符"
+	}
+	if utf8.RuneCountInString(r.Content) > maxPostContentLength {
+		return fmt.Sprintf("内容不能超过%d个字符", maxPostContentLength)
+	}
+	if imageCount > models.MaxPostImages {
+		return fmt.Sprintf("每个帖子最多%d张图片", models.MaxPostImages)
+	}
+	return ""
+}
+
+// CreatePostCommentRequest 发表评论请求
+type CreatePostCommentRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// CreatePost 发布帖子（JSON 或带图片的 multipart 表单）
+// 文字和图片先经过内容审核，需要复核的帖子通过审核前只有作者可见
+func CreatePost(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CreatePostRequest
+	isMultipart := c.ContentType() == "multipart/form-data"
+	var bindErr error
+	if isMultipart {
+		bindErr = c.ShouldBind(&req)
+	} else {
+		bindErr = c.ShouldBindJSON(&req)
+	}
+	if bindErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	var files []*multipart.FileHeader
+	if isMultipart {
+		if form, err := c.MultipartForm(); err == nil {
+			files = form.File["images"]
+		}
+	}
+	if msg := req.validate(len(files)); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	result := moderation.CheckText(c.Request.Context(), req.Title, req.Content)
+	if result.Rejected() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": contentRejectedMessage})
+		return
+	}
+
+	imageURLs := make([]string, 0, len(files))
+	for _, file := range files {
+		url, err := saveUserImage(c, file, "posts")
+		if err != nil {
+			for _, saved := range imageURLs {
+				removeLocalImage(saved)
+			}
+			status := http.StatusInternalServerError
+			if errors.Is(err, errImageType) || errors.Is(err, errImageSize) {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		imageURLs = append(imageURLs, url)
+	}
+	imageResult := moderateImages(c, imageURLs)
+	if imageResult.Rejected() {
+		for _, saved := range imageURLs {
+			removeLocalImage(saved)
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": imageRejectedMessage})
+		return
+	}
+	result.Merge(imageResult)
+
+	post := &models.Post{
+		UserID:           userID.(uint),
+		Title:            req.Title,
+		Content:          req.Content,
+		FoodRecordID:     req.FoodRecordID,
+		ModerationStatus: result.Status(),
+	}
+	if err := models.CreatePostWithImages(post, imageURLs); err != nil {
+		for _, saved := range imageURLs {
+			removeLocalImage(saved)
+		}
+		if errors.Is(err, models.ErrInvalidPostFoodRecord) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发布帖子失败"})
+		return
+	}
+
+	excerpt := strings.TrimSpace(req.Title + "\n" + req.Content)
+	moderation.Submit(models.ModerationContentPost, post.ID, post.UserID, excerpt, imageURLs, result)
+
+	posts := []models.Post{*post}
+	if err := models.LoadPostDetails(posts, post.UserID); err != nil {
+		log.Printf("加载帖子 %d 的详情失败: %v", post.ID, err)
+	}
+	response := gin.H{"data": posts[0]}
+	if result.NeedsReview() {
+		response["message"] = pendingReviewMessage
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetFeed 按发布时间倒序获取社区帖子，使用 cursor 游标分页，可通过 user_id 只看某个用户的帖子
+func GetFeed(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	_, pageSize := parsePagination(c, 20)
+
+	var authorID *uint
+	if raw := c.Query("user_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+			return
+		}
+		author := uint(id)
+		authorID = &author
+	}
+
+	var cursor *models.Cursor
+	if raw := c.Query("cursor"); raw != "" {
+		var err error
+		if cursor, err = models.DecodeCursor(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	posts, next, err := models.GetFeedPage(userID.(uint), authorID, cursor, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取帖子列表失败"})
+		return
+	}
+	if err := models.LoadPostDetails(posts, userID.(uint)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取帖子列表失败"})
+		return
+	}
+
+	nextCursor := ""
+	if next != nil {
+		nextCursor = next.Encode()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": posts,
+		"meta": gin.H{
+			"page_size":   pageSize,
+			"next_cursor": nextCursor,
+			"has_more":    next != nil,
+		},
+	})
+}
+
+// GetPost 获取帖子详情
+func GetPost(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	post, ok := loadPost(c)
+	if !ok {
+		return
+	}
+
+	posts := []models.Post{*post}
+	if err := models.LoadPostDetails(posts, userID.(uint)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取帖子失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": posts[0]})
+}
+
+// DeletePost 删除帖子，仅作者或管理员可操作
+func DeletePost(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	post, ok := loadPost(c)
+	if !ok {
+		return
+	}
+	if post.UserID != userID.(uint) && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权删除该帖子"})
+		return
+	}
+
+	if err := models.DeletePost(post); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除帖子失败"})
+		return
+	}
+	for _, image := range post.Images {
+		removeLocalImage(image.URL)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "帖子已删除"})
+}
+
+// LikePost 点赞帖子，重复点赞不会重复计数
+func LikePost(c *gin.Context) {
+	setPostLike(c, true)
+}
+
+// UnlikePost 取消点赞
+func UnlikePost(c *gin.Context) {
+	setPostLike(c, false)
+}
+
+func setPostLike(c *gin.Context, like bool) {
+	userID, _ := c.Get("user_id")
+
+	post, ok := loadPost(c)
+	if !ok {
+		return
+	}
+
+	count, err := models.SetPostLike(post.ID, userID.(uint), like)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "帖子不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"liked": like, "like_count": count}})
+}
+
+// GetPostComments 按时间顺序分页获取帖子的评论
+func GetPostComments(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	post, ok := loadPost(c)
+	if !ok {
+		return
+	}
+	page, pageSize := parsePagination(c, 20)
+
+	comments, total, err := models.GetPostComments(post.ID, userID.(uint), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取评论失败"})
+		return
+	}
+	if err := models.LoadCommentAuthors(comments); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取评论失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, paginated(comments, total, page, pageSize))
+}
+
+// CreatePostComment 发表评论，并通知帖子作者；需要复核的评论通过审核前只有评论人可见
+func CreatePostComment(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	post, ok := loadPost(c)
+	if !ok {
+		return
+	}
+
+	var req CreatePostCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "评论内容不能为空"})
+		return
+	}
+	if utf8.RuneCountInString(content) > models.MaxPostCommentLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("评论不能超过%d个字符", models.MaxPostCommentLength)})
+		return
+	}
+
+	result := moderation.CheckText(c.Request.Context(), content)
+	if result.Rejected() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": contentRejectedMessage})
+		return
+	}
+
+	comment := &models.PostComment{
+		PostID:           post.ID,
+		UserID:           userID.(uint),
+		Content:          content,
+		ModerationStatus: result.Status(),
+	}
+	if err := models.CreatePostComment(comment); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "帖子不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发表评论失败"})
+		return
+	}
+
+	moderation.Submit(models.ModerationContentPostComment, comment.ID, comment.UserID, comment.Content, nil, result)
+
+	comments := []models.PostComment{*comment}
+	if err := models.LoadCommentAuthors(comments); err != nil {
+		log.Printf("加载评论 %d 的作者失败: %v", comment.ID, err)
+	}
+	// 帖子作者看不到待审核的评论，暂不发送通知
+	if post.UserID != comment.UserID && !result.NeedsReview() {
+		notifyPostComment(post, &comments[0])
+	}
+
+	response := gin.H{"data": comments[0]}
+	if result.NeedsReview() {
+		response["message"] = pendingReviewMessage
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// DeletePostComment 删除评论，评论人、帖子作者或管理员可操作
+func DeletePostComment(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	post, ok := loadPost(c)
+	if !ok {
+		return
+	}
+	commentID, err := strconv.ParseUint(c.Param("comment_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的评论ID"})
+		return
+	}
+
+	comment, err := models.GetPostComment(post.ID, uint(commentID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "评论不存在"})
+		return
+	}
+	if comment.UserID != userID.(uint) && post.UserID != userID.(uint) && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权删除该评论"})
+		return
+	}
+
+	if err := models.DeletePostComment(comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除评论失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "评论已删除"})
+}
+
+// notifyPostComment 通知帖子作者收到新评论，失败只记录日志
+func notifyPostComment(post *models.Post, comment *models.PostComment) {
+	title := "有人评论了你的帖子"
+	if comment.Author != nil && comment.Author.Name != "" {
+		title = fmt.Sprintf("%s 评论了你的帖子", comment.Author.Name)
+	}
+	notification := models.Notification{
+		UserID:  post.UserID,
+		Type:    models.NotificationTypePostComment,
+		Title:   title,
+		Content: comment.Content,
+		Data: map[string]interface{}{
+			"post_id":    post.ID,
+			"comment_id": comment.ID,
+			"user_id":    comment.UserID,
+		},
+	}
+	if err := models.CreateNotifications([]models.Notification{notification}); err != nil {
+		log.Printf("保存帖子 %d 的评论通知失败: %v", post.ID, err)
+	}
+}
+
+// parsePostID 解析路径中的帖子ID
+func parsePostID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的帖子ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// loadPost 根据路径中的帖子ID加载帖子及其图片
+// 与作者存在拉黑关系，或帖子未通过审核且不是作者本人时按帖子不存在处理，管理员不受限制
+func loadPost(c *gin.Context) (*models.Post, bool) {
+	userID, _ := c.Get("user_id")
+
+	postID, ok := parsePostID(c)
+	if !ok {
+		return nil, false
+	}
+	post, err := models.GetPostWithImages(postID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "帖子不存在"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取帖子失败"})
+		return nil, false
+	}
+
+	if !isAdmin(c) {
+		blocked, err := models.IsBlockedEither(userID.(uint), post.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取帖子失败"})
+			return nil, false
+		}
+		if blocked {
+			c.JSON(http.StatusNotFound, gin.H{"error": "帖子不存在"})
+			return nil, false
+		}
+		if post.ModerationStatus != models.ModerationStatusApproved && post.UserID != userID.(uint) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "帖子不存在"})
+			return nil, false
+		}
+	}
+	return post, true
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"backend/models"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ShopListingRequest 创建/更新商品请求
+type ShopListingRequest struct {
+	ItemID  uint  `json:"item_id" binding:"required"`
+	Price   int64 `json:"price"`   // 单价（金币）
+	Stock   *int  `json:"stock"`   // 库存，为空表示不限量
+	Enabled *bool `json:"enabled"` // 默认上架
+}
+
+// PurchaseRequest 购买请求
+type PurchaseRequest struct {
+	Quantity int `json:"quantity"` // 购买份数，默认1
+}
+
+// AdjustCoinsRequest 管理员调整金币请求
+type AdjustCoinsRequest struct {
+	UserID uint   `json:"user_id" binding:"required"`
+	Amount int64  `json:"amount" binding:"required"` // 正数增加，负数扣除
+	Reason string `json:"reason"`
+}
+
+// GetMyCoins 获取当前用户的金币余额
+func GetMyCoins(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	balance, err := models.GetCoinBalance(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取金币余额失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"balance": balance}})
+}
+
+// GetMyCoinTransactions 分页获取当前用户的金币流水，可按 type 过滤
+func GetMyCoinTransactions(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	page, pageSize := parsePagination(c, 20)
+
+	transactions, total, err := models.GetCoinTransactions(userID.(uint), c.Query("type"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取金币流水失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, paginated(transactions, total, page, pageSize))
+}
+
+// GetShopListings 获取上架中的商品
+func GetShopListings(c *gin.Context) {
+	page, pageSize := parsePagination(c, 20)
+
+	listings, total, err := models.GetShopListings(true, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取商品列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, paginated(listings, total, page, pageSize))
+}
+
+// PurchaseShopListing 使用金币购买商品
+func PurchaseShopListing(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	listingID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的商品ID"})
+		return
+	}
+
+	var req PurchaseRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+			return
+		}
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	if req.Quantity < 1 || req.Quantity > models.MaxPurchaseQuantity {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "购买数量无效"})
+		return
+	}
+
+	purchase, err := models.PurchaseShopListing(userID.(uint), uint(listingID), req.Quantity)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrListingUnavailable):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, models.ErrOutOfStock), errors.Is(err, models.ErrInsufficientCoins), errors.Is(err, models.ErrStackLimit):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "购买失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": purchase})
+}
+
+// AdminGetShopListings 获取所有商品，包括已下架的（管理员专用）
+func AdminGetShopListings(c *gin.Context) {
+	page, pageSize := parsePagination(c, 20)
+
+	listings, total, err := models.GetShopListings(false, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取商品列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, paginated(listings, total, page, pageSize))
+}
+
+// CreateShopListing 上架商品（管理员专用）
+func CreateShopListing(c *gin.Context) {
+	var req ShopListingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	listing := &models.ShopListing{Enabled: true}
+	if !applyShopListingRequest(c, listing, &req) {
+		return
+	}
+
+	if err := models.CreateShopListing(listing); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建商品失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": listing})
+}
+
+// UpdateShopListing 更新商品（管理员专用）
+func UpdateShopListing(c *gin.Context) {
+	listingID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的商品ID"})
+		return
+	}
+
+	listing, err := models.GetShopListingByID(uint(listingID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "商品不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取商品失败"})
+		}
+		return
+	}
+
+	var req ShopListingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	if !applyShopListingRequest(c, listing, &req) {
+		return
+	}
+
+	if err := models.UpdateShopListing(listing); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新商品失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": listing})
+}
+
+// DeleteShopListing 删除商品（管理员专用）
+func DeleteShopListing(c *gin.Context) {
+	listingID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的商品ID"})
+		return
+	}
+
+	if err := models.DeleteShopListing(uint(listingID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除商品失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "商品已删除"})
+}
+
+// AdjustUserCoins 调整用户金币余额（管理员专用）
+func AdjustUserCoins(c *gin.Context) {
+	var req AdjustCoinsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	var user models.User
+	if err := models.DB.First(&user, req.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	transaction, err := models.CreditCoins(models.CoinChange{
+		UserID:  req.UserID,
+		Amount:  req.Amount,
+		Type:    models.CoinTxAdmin,
+		ActorID: currentActorID(c),
+		Reason:  req.Reason,
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrInsufficientCoins) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "调整金币失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": transaction})
+}
+
+// GetUserCoinTransactions 分页获取指定用户的金币流水（管理员专用）
+func GetUserCoinTransactions(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+	page, pageSize := parsePagination(c, 20)
+
+	transactions, total, err := models.GetCoinTransactions(uint(userID), c.Query("type"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取金币流水失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, paginated(transactions, total, page, pageSize))
+}
+
+// applyShopListingRequest 校验请求并写入商品，校验失败时写入错误响应并返回 false
+func applyShopListingRequest(c *gin.Context, listing *models.ShopListing, req *ShopListingRequest) bool {
+	if req.Price <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "商品价格必须大于0"})
+		return false
+	}
+	if req.Stock != nil && *req.Stock < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "库存不能为负数"})
+		return false
+	}
+
+	item, err := models.GetItemByID(req.ItemID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "物品不存在"})
+		return false
+	}
+
+	listing.ItemID = req.ItemID
+	listing.Price = req.Price
+	listing.Stock = req.Stock
+	listing.Item = item
+	if req.Enabled != nil {
+		listing.Enabled = *req.Enabled
+	}
+	return true
+}
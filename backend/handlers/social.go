@@ -0,0 +1,448 @@
+package handlers
+
+import (
+	"backend/models"
+	"backend/social"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SendFriendRequestRequest 发送好友请求
+type SendFriendRequestRequest struct {
+	Message string `json:"message"`
+}
+
+// GetUserPublicProfile 获取用户的公开资料、与当前用户的关系和关系统计
+func GetUserPublicProfile(c *gin.Context) {
+	viewerID := currentViewerID(c)
+	targetID, ok := parseRelationTarget(c)
+	if !ok {
+		return
+	}
+
+	summaries, err := models.GetUserSummaries([]uint{targetID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户资料失败"})
+		return
+	}
+	summary, exists := summaries[targetID]
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	var relation *models.Relation
+	if targetID != viewerID {
+		if relation, err = models.GetRelation(viewerID, targetID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户资料失败"})
+			return
+		}
+		// 被对方拉黑时按用户不存在处理
+		if relation.BlockedBy && !isAdmin(c) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+			return
+		}
+	}
+
+	followers, following, friends, err := models.CountRelations(targetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户资料失败"})
+		return
+	}
+	privacy, err := models.GetPrivacySettings(targetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户资料失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{
+		"user":     summary,
+		"relation": relation,
+		"privacy":  privacy,
+		"stats": gin.H{
+			"followers": followers,
+			"following": following,
+			"friends":   friends,
+		},
+	}})
+}
+
+// GetUserFollowers 分页获取关注该用户的用户
+func GetUserFollowers(c *gin.Context) {
+	listRelatedUsers(c, models.GetFollowers)
+}
+
+// GetUserFollowing 分页获取该用户关注的用户
+func GetUserFollowing(c *gin.Context) {
+	listRelatedUsers(c, models.GetFollowing)
+}
+
+// GetOtherUserFoodRecords 分页获取其他用户的饮食记录，遵循对方的饮食记录可见范围
+func GetOtherUserFoodRecords(c *gin.Context) {
+	targetID, ok := parseRelationTarget(c)
+	if !ok {
+		return
+	}
+	if !checkContentVisible(c, targetID, func(p *models.PrivacySettings) string { return p.FoodRecordVisibility }) {
+		return
+	}
+	page, pageSize := parsePagination(c, 20)
+
+	records, total, err := models.GetUserFoodRecordsPage(targetID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取饮食记录失败"})
+		return
+	}
+	c.JSON(http.StatusOK, paginated(records, total, page, pageSize))
+}
+
+// GetOtherUserCheckIns 分页获取其他用户的打卡记录，遵循对方的打卡可见范围
+// 关联的饮食记录另外遵循饮食记录的可见范围，未通过审核的打卡不展示内容和图片
+func GetOtherUserCheckIns(c *gin.Context) {
+	viewerID := currentViewerID(c)
+	targetID, ok := parseRelationTarget(c)
+	if !ok {
+		return
+	}
+	if !checkContentVisible(c, targetID, func(p *models.PrivacySettings) string { return p.CheckInVisibility }) {
+		return
+	}
+	page, pageSize := parsePagination(c, 20)
+
+	checkIns, _, err := models.GetUserCheckInsPage(targetID, nil, (page-1)*pageSize, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取打卡记录失败"})
+		return
+	}
+	total, err := models.CountUserCheckIns(targetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取打卡记录失败"})
+		return
+	}
+
+	privacy, err := models.GetPrivacySettings(targetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取打卡记录失败"})
+		return
+	}
+	showFoodRecords := isAdmin(c)
+	if !showFoodRecords {
+		if showFoodRecords, err = models.CanView(viewerID, targetID, privacy.FoodRecordVisibility); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取打卡记录失败"})
+			return
+		}
+	}
+	if showFoodRecords {
+		if err := models.LoadCheckInFoodRecords(checkIns); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取打卡关联的饮食记录失败"})
+			return
+		}
+	}
+	if viewerID != targetID && !isAdmin(c) {
+		models.HideUnapprovedCheckInContent(checkIns)
+	}
+
+	c.JSON(http.StatusOK, paginated(checkIns, total, page, pageSize))
+}
+
+// FollowUser 关注用户
+func FollowUser(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	targetID, ok := parseRelationTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := social.Follow(userID.(uint), targetID); err != nil {
+		respondSocialError(c, err, "关注失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已关注"})
+}
+
+// UnfollowUser 取消关注
+func UnfollowUser(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	targetID, ok := parseRelationTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := social.Unfollow(userID.(uint), targetID); err != nil {
+		respondSocialError(c, err, "取消关注失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已取消关注"})
+}
+
+// SendFriendRequest 向用户发送好友请求，对方已向自己发出请求时直接成为好友
+func SendFriendRequest(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	targetID, ok := parseRelationTarget(c)
+	if !ok {
+		return
+	}
+
+	var req SendFriendRequestRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+			return
+		}
+	}
+
+	friends, err := social.SendFriendRequest(userID.(uint), targetID, req.Message)
+	if err != nil {
+		respondSocialError(c, err, "发送好友请求失败")
+		return
+	}
+	if friends {
+		c.JSON(http.StatusOK, gin.H{"message": "你们已成为好友", "data": gin.H{"friends": true}})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "好友请求已发送", "data": gin.H{"friends": false}})
+}
+
+// CancelFriendRequest 撤回发给该用户的好友请求
+func CancelFriendRequest(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	targetID, ok := parseRelationTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := social.Cancel(userID.(uint), targetID); err != nil {
+		respondSocialError(c, err, "撤回好友请求失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "好友请求已撤回"})
+}
+
+// AcceptFriendRequest 接受该用户发来的好友请求
+func AcceptFriendRequest(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	targetID, ok := parseRelationTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := social.Accept(userID.(uint), targetID); err != nil {
+		respondSocialError(c, err, "接受好友请求失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "你们已成为好友"})
+}
+
+// DeclineFriendRequest 拒绝该用户发来的好友请求
+func DeclineFriendRequest(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	targetID, ok := parseRelationTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := social.Decline(userID.(uint), targetID); err != nil {
+		respondSocialError(c, err, "拒绝好友请求失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已拒绝好友请求"})
+}
+
+// RemoveFriend 解除好友关系
+func RemoveFriend(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	targetID, ok := parseRelationTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := social.RemoveFriend(userID.(uint), targetID); err != nil {
+		respondSocialError(c, err, "解除好友关系失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已解除好友关系"})
+}
+
+// BlockUser 拉黑用户
+func BlockUser(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	targetID, ok := parseRelationTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := social.Block(userID.(uint), targetID); err != nil {
+		respondSocialError(c, err, "拉黑失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已拉黑"})
+}
+
+// UnblockUser 取消拉黑
+func UnblockUser(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	targetID, ok := parseRelationTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := social.Unblock(userID.(uint), targetID); err != nil {
+		respondSocialError(c, err, "取消拉黑失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已取消拉黑"})
+}
+
+// GetMyFriends 分页获取当前用户的好友
+func GetMyFriends(c *gin.Context) {
+	listMyRelatedUsers(c, models.GetFriends, "获取好友列表失败")
+}
+
+// GetMyBlocks 分页获取当前用户的黑名单
+func GetMyBlocks(c *gin.Context) {
+	listMyRelatedUsers(c, models.GetBlockedUsers, "获取黑名单失败")
+}
+
+// GetMyFriendRequests 分页获取收到（box=incoming，默认）或发出（box=outgoing）的好友请求
+func GetMyFriendRequests(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	page, pageSize := parsePagination(c, 20)
+
+	var incoming bool
+	switch c.DefaultQuery("box", "incoming") {
+	case "incoming":
+		incoming = true
+	case "outgoing":
+		incoming = false
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "box 只能是 incoming 或 outgoing"})
+		return
+	}
+
+	requests, total, err := models.GetFriendRequests(userID.(uint), incoming, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取好友请求失败"})
+		return
+	}
+
+	userIDs := make([]uint, len(requests))
+	for i, request := range requests {
+		userIDs[i] = request.ReceiverID
+		if incoming {
+			userIDs[i] = request.SenderID
+		}
+	}
+	summaries, err := models.GetUserSummaries(userIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取好友请求失败"})
+		return
+	}
+	for i := range requests {
+		requests[i].User = summaries[userIDs[i]]
+	}
+
+	c.JSON(http.StatusOK, paginated(requests, total, page, pageSize))
+}
+
+// listRelatedUsers 分页获取路径中用户的关注者或关注列表，与对方存在拉黑关系时不可查看
+func listRelatedUsers(c *gin.Context, list func(userID uint, page, pageSize int) ([]models.UserSummary, int64, error)) {
+	viewerID := currentViewerID(c)
+	targetID, ok := parseRelationTarget(c)
+	if !ok {
+		return
+	}
+	if targetID != viewerID && !isAdmin(c) {
+		blocked, err := models.IsBlockedEither(viewerID, targetID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户列表失败"})
+			return
+		}
+		if blocked {
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+			return
+		}
+	}
+	page, pageSize := parsePagination(c, 20)
+
+	users, total, err := list(targetID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户列表失败"})
+		return
+	}
+	c.JSON(http.StatusOK, paginated(users, total, page, pageSize))
+}
+
+func listMyRelatedUsers(c *gin.Context, list func(userID uint, page, pageSize int) ([]models.UserSummary, int64, error), fallback string) {
+	userID, _ := c.Get("user_id")
+	page, pageSize := parsePagination(c, 20)
+
+	users, total, err := list(userID.(uint), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+		return
+	}
+	c.JSON(http.StatusOK, paginated(users, total, page, pageSize))
+}
+
+// currentViewerID 返回当前登录用户的ID，未登录时返回 0
+func currentViewerID(c *gin.Context) uint {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return 0
+	}
+	return userID.(uint)
+}
+
+// checkContentVisible 判断当前用户能否查看 ownerID 的某类内容，visibility 从对方的可见范围设置中选出对应的一项
+// 本人和管理员不受限制；不可查看或出错时写入响应并返回 false
+func checkContentVisible(c *gin.Context, ownerID uint, visibility func(*models.PrivacySettings) string) bool {
+	viewerID := currentViewerID(c)
+	if (viewerID != 0 && viewerID == ownerID) || isAdmin(c) {
+		return true
+	}
+
+	privacy, err := models.GetPrivacySettings(ownerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取隐私设置失败"})
+		return false
+	}
+	visible, err := models.CanView(viewerID, ownerID, visibility(privacy))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取隐私设置失败"})
+		return false
+	}
+	if !visible {
+		c.JSON(http.StatusForbidden, gin.H{"error": "对方未公开该内容"})
+		return false
+	}
+	return true
+}
+
+// parseRelationTarget 解析路径中的目标用户ID，与 parseTargetUserID 不同，不要求是当前用户
+func parseRelationTarget(c *gin.Context) (uint, bool) {
+	targetID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return 0, false
+	}
+	return uint(targetID), true
+}
+
+// respondSocialError 将关系操作的错误转换为响应
+func respondSocialError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, social.ErrUserNotFound), errors.Is(err, social.ErrRequestNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, social.ErrSelf), errors.Is(err, social.ErrMessageTooLong):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, social.ErrBlocked):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, social.ErrAlreadyFriends):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
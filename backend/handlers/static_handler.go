@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"backend/moderation"
+	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -42,6 +46,14 @@ func (h *StaticFileHandler) UploadImage(c *gin.Context) {
 	// 获取自定义文件名，如果没有提供则生成UUID
 	customFilename := c.PostForm("filename")
 
+	// 自定义文件名会出现在公开的图片地址中，同样需要审核
+	if customFilename != "" && moderation.CheckText(c.Request.Context(), customFilename).Rejected() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "文件名包含不当信息",
+		})
+		return
+	}
+
 	// 验证目录名称安全性
 	if !isValidDirectory(directory) {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -108,6 +120,15 @@ func (h *StaticFileHandler) UploadImage(c *gin.Context) {
 		return
 	}
 
+	// 管理员上传的图片只拦截明确违规的内容，不进入审核队列
+	if moderation.CheckImage(c.Request.Context(), savePath).Rejected() {
+		os.Remove(savePath)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": imageRejectedMessage,
+		})
+		return
+	}
+
 	// 返回可访问的URL
 	imageURL := fmt.Sprintf("/static/%s/%s", directory, newFileName)
 
@@ -117,6 +138,73 @@ func (h *StaticFileHandler) UploadImage(c *gin.Context) {
 	})
 }
 
+// 用户上传图片的限制
+const maxUserImageSize = 2 * 1024 * 1024
+
+var (
+	errImageType = errors.New("不支持的文件类型，仅支持 JPG, PNG, GIF, WebP 格式")
+	errImageSize = errors.New("文件大小超过限制（最大2MB）")
+)
+
+// userImageExtensions 用户可上传的图片类型（按文件内容识别）及保存时使用的扩展名
+// /static 按扩展名决定响应类型，扩展名只能来自这里，不能使用客户端提供的文件名或 Content-Type
+var userImageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// detectImageExtension 根据文件开头的内容识别图片类型，返回保存时使用的扩展名
+func detectImageExtension(file *multipart.FileHeader) (string, error) {
+	f, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("读取上传文件失败: %v", err)
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("读取上传文件失败: %v", err)
+	}
+	ext, ok := userImageExtensions[http.DetectContentType(head[:n])]
+	if !ok {
+		return "", errImageType
+	}
+	return ext, nil
+}
+
+// saveUserImage 校验并保存用户上传的图片到 static/<directory> 下，文件名使用UUID，返回可访问的URL
+func saveUserImage(c *gin.Context, file *multipart.FileHeader, directory string) (string, error) {
+	if file.Size > maxUserImageSize {
+		return "", errImageSize
+	}
+	ext, err := detectImageExtension(file)
+	if err != nil {
+		return "", err
+	}
+
+	dirPath := filepath.Join("static", directory)
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return "", fmt.Errorf("创建目录失败: %v", err)
+	}
+
+	newFileName := uuid.New().String() + ext
+	if err := c.SaveUploadedFile(file, filepath.Join(dirPath, newFileName)); err != nil {
+		return "", fmt.Errorf("保存文件失败: %v", err)
+	}
+	return fmt.Sprintf("/static/%s/%s", directory, newFileName), nil
+}
+
+// removeLocalImage 删除 saveUserImage 保存的图片，包括被审核隔离的文件
+func removeLocalImage(url string) {
+	if path, ok := localStaticPath(url); ok {
+		os.Remove(path)
+		os.Remove(moderation.QuarantinedPath(path))
+	}
+}
+
 // 验证目录名称安全性
 func isValidDirectory(directory string) bool {
 	// 不允许空目录名
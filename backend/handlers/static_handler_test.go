@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+)
+
+// newFileHeader 构造带指定文件名、Content-Type 和内容的上传文件
+func newFileHeader(t *testing.T, filename, contentType string, content []byte) *multipart.FileHeader {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="file"; filename="`+filename+`"`)
+	header.Set("Content-Type", contentType)
+	part, err := w.CreatePart(header)
+	if err != nil {
+		t.Fatalf("创建上传文件失败: %v", err)
+	}
+	part.Write(content)
+	w.Close()
+
+	req := httptest.NewRequest("POST", "/", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	if err := req.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatalf("解析上传文件失败: %v", err)
+	}
+	return req.MultipartForm.File["file"][0]
+}
+
+func TestDetectImageExtension(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	jpeg := []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")
+	gif := []byte("GIF89a\x01\x00\x01\x00")
+	webp := []byte("RIFF\x24\x00\x00\x00WEBPVP8 ")
+
+	tests := []struct {
+		name        string
+		filename    string
+		contentType string
+		content     []byte
+		wantExt     string
+		wantErr     bool
+	}{
+		{"PNG", "a.png", "image/png", png, ".png", false},
+		{"JPEG", "a.jpeg", "image/jpeg", jpeg, ".jpg", false},
+		{"GIF", "a.gif", "image/gif", gif, ".gif", false},
+		{"WebP", "a.webp", "image/webp", webp, ".webp", false},
+		{"扩展名以内容为准", "a.html", "image/png", png, ".png", false},
+		{"声明为图片的HTML", "x.html", "image/png", []byte("<html><script>alert(1)</script></html>"), "", true},
+		{"SVG", "a.svg", "image/svg+xml", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), "", true},
+		{"空文件", "a.png", "image/png", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ext, err := detectImageExtension(newFileHeader(t, tt.filename, tt.contentType, tt.content))
+			if tt.wantErr {
+				if err != errImageType {
+					t.Errorf("err = %v, want errImageType", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("detectImageExtension 返回错误: %v", err)
+			}
+			if ext != tt.wantExt {
+				t.Errorf("ext = %s, want %s", ext, tt.wantExt)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"backend/events"
+	"backend/models"
+	"backend/moderation"
+	"backend/streak"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FreezeStreakRequest 使用冻结卡请求
+type FreezeStreakRequest struct {
+	Date string `json:"date"` // 需要冻结的日期（YYYY-MM-DD），默认昨天
+}
+
+// MakeUpCheckInRequest 补签请求
+type MakeUpCheckInRequest struct {
+	Content string `json:"content"` // 打卡内容（可选）
+}
+
+// GetCheckInStreak 获取当前用户的连续打卡状态
+func GetCheckInStreak(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	summary, err := streak.GetSummary(userID.(uint), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取连续打卡信息失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": summary})
+}
+
+// FreezeCheckInStreak 使用一张冻结卡冻结未打卡的日期
+func FreezeCheckInStreak(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var req FreezeStreakRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+			return
+		}
+	}
+
+	freeze, err := streak.Freeze(userID.(uint), req.Date, time.Now())
+	if err != nil {
+		respondStreakError(c, err, "使用冻结卡失败")
+		return
+	}
+
+	summary, err := streak.GetSummary(userID.(uint), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取连续打卡信息失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "冻结成功", "freeze": freeze, "data": summary})
+}
+
+// MakeUpCheckIn 使用一张补签卡为昨天补签
+func MakeUpCheckIn(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var req MakeUpCheckInRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+			return
+		}
+	}
+
+	result := moderation.CheckText(c.Request.Context(), req.Content)
+	if result.Rejected() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": contentRejectedMessage})
+		return
+	}
+
+	checkIn, err := streak.MakeUp(userID.(uint), req.Content, result.Status(), time.Now())
+	if err != nil {
+		respondStreakError(c, err, "补签失败")
+		return
+	}
+	moderation.Submit(models.ModerationContentCheckIn, checkIn.ID, checkIn.UserID, checkIn.Content, nil, result)
+	unlocked := publishAndCollectUnlocks(events.CheckInCreated, checkIn.UserID, checkIn)
+
+	summary, err := streak.GetSummary(userID.(uint), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取连续打卡信息失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "补签成功",
+		"check_in_data": checkIn,
+		"data":          summary,
+		"achievements":  unlocked, // 本次新解锁的成就
+	})
+}
+
+// respondStreakError 将连续打卡相关的错误转换为HTTP响应
+func respondStreakError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, models.ErrInsufficientItems):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "物品数量不足"})
+	case errors.Is(err, models.ErrStreakDayTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, streak.ErrInvalidFreezeDate),
+		errors.Is(err, streak.ErrFreezeLimit),
+		errors.Is(err, streak.ErrItemUnavailable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"backend/models"
+	"backend/trade"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateTradeOfferRequest 发起赠送或交易请求，requested_items 为空时即为赠送
+type CreateTradeOfferRequest struct {
+	ReceiverID     uint         `json:"receiver_id" binding:"required"`
+	Items          []trade.Line `json:"items" binding:"required"` // 赠送给对方的物品
+	RequestedItems []trade.Line `json:"requested_items"`          // 向对方索要的物品
+	Message        string       `json:"message" binding:"max=255"`
+}
+
+// CreateTradeOffer 发起赠送或交易请求
+func CreateTradeOffer(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CreateTradeOfferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	offer, err := trade.CreateOffer(trade.OfferRequest{
+		SenderID:   userID.(uint),
+		ReceiverID: req.ReceiverID,
+		Offered:    req.Items,
+		Requested:  req.RequestedItems,
+		Message:    req.Message,
+	}, time.Now())
+	if err != nil {
+		respondTradeError(c, err, "发起交易失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": offer})
+}
+
+// GetMyTradeOffers 分页获取收到（box=incoming，默认）或发出（box=outgoing）的交易，可按 status 过滤
+func GetMyTradeOffers(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	page, pageSize := parsePagination(c, 20)
+
+	box := c.DefaultQuery("box", "incoming")
+	if box != "incoming" && box != "outgoing" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "box 只能为 incoming 或 outgoing"})
+		return
+	}
+
+	if _, err := models.ExpireTradeOffers(time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取交易列表失败"})
+		return
+	}
+
+	offers, total, err := models.GetUserTradeOffers(userID.(uint), box == "incoming", c.Query("status"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取交易列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, paginated(offers, total, page, pageSize))
+}
+
+// GetTradeOffer 获取交易详情，仅交易双方可查看
+func GetTradeOffer(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	offerID, ok := parseTradeOfferID(c)
+	if !ok {
+		return
+	}
+
+	offer, err := models.GetTradeOffer(offerID)
+	if err != nil || (offer.SenderID != userID.(uint) && offer.ReceiverID != userID.(uint)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": offer})
+}
+
+// AcceptTradeOffer 接受交易，双方物品立即转移
+func AcceptTradeOffer(c *gin.Context) {
+	respondToTradeOffer(c, trade.Accept, "接受交易失败")
+}
+
+// DeclineTradeOffer 拒绝交易
+func DeclineTradeOffer(c *gin.Context) {
+	respondToTradeOffer(c, trade.Decline, "拒绝交易失败")
+}
+
+// CancelTradeOffer 取消自己发起的交易
+func CancelTradeOffer(c *gin.Context) {
+	respondToTradeOffer(c, trade.Cancel, "取消交易失败")
+}
+
+func respondToTradeOffer(c *gin.Context, action func(offerID, userID uint, now time.Time) (*models.TradeOffer, error), fallback string) {
+	userID, _ := c.Get("user_id")
+
+	offerID, ok := parseTradeOfferID(c)
+	if !ok {
+		return
+	}
+
+	offer, err := action(offerID, userID.(uint), time.Now())
+	if err != nil {
+		respondTradeError(c, err, fallback)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": offer})
+}
+
+func parseTradeOfferID(c *gin.Context) (uint, bool) {
+	offerID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的交易ID"})
+		return 0, false
+	}
+	return uint(offerID), true
+}
+
+// respondTradeError 将交易相关的错误转换为HTTP响应
+func respondTradeError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, trade.ErrReceiverNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易或用户不存在"})
+	case errors.Is(err, trade.ErrSelfTrade),
+		errors.Is(err, trade.ErrEmptyOffer),
+		errors.Is(err, trade.ErrTooManyLines),
+		errors.Is(err, trade.ErrInvalidQuantity),
+		errors.Is(err, models.ErrItemNotTradable),
+		errors.Is(err, models.ErrInsufficientItems):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, trade.ErrNotVerified), errors.Is(err, trade.ErrAccountTooNew), errors.Is(err, trade.ErrBlocked):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, trade.ErrPendingLimit),
+		errors.Is(err, trade.ErrReceiverBusy),
+		errors.Is(err, trade.ErrDailyLimit):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrTradeNotPending), errors.Is(err, models.ErrStackLimit):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"backend/calendar"
+	"backend/events"
 	"backend/models"
 	"errors"
 	"net/http"
@@ -55,6 +57,7 @@ func CreateUserHealthStateHandler(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存健康状态记录失败"})
 		return
 	}
+	events.Publish(events.HealthStateRecorded, record.UserID, &record)
 
 	// 返回成功响应
 	c.JSON(http.StatusOK, gin.H{
@@ -78,10 +81,11 @@ func GetUserHealthStatesHandler(c *gin.Context) {
 
 	var startTime, endTime time.Time
 	var err error
+	loc := models.GetUserLocation(userID.(uint))
 
-	// 如果提供了开始日期，解析它
+	// 如果提供了开始日期，按用户时区解析它
 	if startDateStr != "" {
-		startTime, err = time.Parse("2006-01-02", startDateStr)
+		startTime, err = calendar.ParseDate(startDateStr, loc)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "开始日期格式错误"})
 			return
@@ -93,13 +97,14 @@ func GetUserHealthStatesHandler(c *gin.Context) {
 
 	// 如果提供了结束日期，解析它
 	if endDateStr != "" {
-		endTime, err = time.Parse("2006-01-02", endDateStr)
+		endTime, err = calendar.ParseDate(endDateStr, loc)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "结束日期格式错误"})
 			return
 		}
-		// 设置为当天的结束时间
-		endTime = endTime.Add(24*time.Hour - time.Second)
+		// 设置为当天的结束时间（夏令时切换日不一定是24小时）
+		_, endTime = calendar.DayRange(endTime, loc)
+		endTime = endTime.Add(-time.Second)
 	} else {
 		// 默认为当前时间
 		endTime = time.Now()
@@ -231,6 +236,7 @@ func UpdateUserHealthStateHandler(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新记录失败"})
 		return
 	}
+	events.Publish(events.HealthStateUpdated, updatedRecord.UserID, &updatedRecord)
 
 	// 返回更新后的记录
 	c.JSON(http.StatusOK, gin.H{
@@ -278,6 +284,7 @@ func DeleteUserHealthStateHandler(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除记录失败"})
 		return
 	}
+	events.Publish(events.HealthStateDeleted, existingRecord.UserID, existingRecord)
 
 	// 返回成功响应
 	c.JSON(http.StatusOK, gin.H{"message": "记录已成功删除"})
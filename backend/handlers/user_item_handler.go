@@ -1,65 +1,128 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
-	"time"
 
 	"backend/models"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-// CreateUserItem 创建用户物品关联
-func CreateUserItem(c *gin.Context) {
-	// 从URL参数中获取用户ID
-	userIDStr := c.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+// parseTargetUserID 解析路径中的用户ID，并校验当前用户是否为本人或管理员
+func parseTargetUserID(c *gin.Context) (uint, bool) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return 0, false
+	}
+
+	currentUserID, _ := c.Get("user_id")
+	role, _ := c.Get("role")
+	if role != models.RoleAdmin && currentUserID != uint(userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权访问该用户的物品"})
+		return 0, false
+	}
+
+	return uint(userID), true
+}
+
+// isAdmin 判断当前请求用户是否为管理员
+func isAdmin(c *gin.Context) bool {
+	role, _ := c.Get("role")
+	return role == models.RoleAdmin
+}
+
+// currentActorID 返回当前操作人的用户ID，用于记录物品流水
+func currentActorID(c *gin.Context) *uint {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return nil
+	}
+	id := userID.(uint)
+	return &id
+}
+
+// GrantUserItemRequest 管理员发放物品请求
+type GrantUserItemRequest struct {
+	ItemID       uint   `json:"item_id" binding:"required"`
+	Quantity     int    `json:"quantity"`      // 发放数量，默认1
+	ObtainedFrom string `json:"obtained_from"` // 获得来源
+	GrantKey     string `json:"grant_key"`     // 幂等标识，相同来源和标识只会发放一次
+	Reason       string `json:"reason"`        // 发放原因
+}
+
+// CreateUserItem 创建用户物品关联（仅管理员可发放物品）
+func CreateUserItem(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "需要管理员权限"})
 		return
 	}
 
-	var userItem models.UserItem
-	if err := c.ShouldBindJSON(&userItem); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+	// 从URL参数中获取用户ID
+	userID, ok := parseTargetUserID(c)
+	if !ok {
 		return
 	}
 
-	// 设置用户ID和获得时间
-	userItem.UserID = uint(userID)
-	userItem.ObtainedAt = time.Now()
+	var req GrantUserItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+	if req.Quantity < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "数量不能为负数"})
+		return
+	}
 
 	// 如果没有提供获得来源，设置默认值
-	if userItem.ObtainedFrom == "" {
-		userItem.ObtainedFrom = "未知来源"
+	if req.ObtainedFrom == "" {
+		req.ObtainedFrom = "未知来源"
 	}
 
 	// 检查物品是否存在
-	_, err = models.GetItemByID(userItem.ItemID)
+	_, err := models.GetItemByID(req.ItemID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "物品不存在"})
 		return
 	}
 
-	// 创建用户物品关联
-	if err := models.CreateUserItem(&userItem); err != nil {
+	// 发放物品并记录流水
+	userItem, granted, err := models.GrantItem(models.GrantRequest{
+		UserID:    userID,
+		ItemID:    req.ItemID,
+		Quantity:  req.Quantity,
+		Source:    req.ObtainedFrom,
+		SourceRef: req.GrantKey,
+		ActorID:   currentActorID(c),
+		Reason:    req.Reason,
+	})
+	if errors.Is(err, models.ErrStackLimit) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建用户物品关联失败"})
 		return
 	}
+	if !granted {
+		c.JSON(http.StatusConflict, gin.H{"error": "该物品已通过相同来源发放过"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"data": userItem})
 }
 
 // GetUserItems 获取用户的所有物品
 func GetUserItems(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+	userID, ok := parseTargetUserID(c)
+	if !ok {
 		return
 	}
 
-	items, err := models.GetUserItems(uint(userID))
+	items, err := models.GetUserItems(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户物品列表失败"})
 		return
@@ -70,9 +133,8 @@ func GetUserItems(c *gin.Context) {
 
 // GetUserItemDetails 获取用户特定物品的详细信息
 func GetUserItemDetails(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+	userID, ok := parseTargetUserID(c)
+	if !ok {
 		return
 	}
 
@@ -82,7 +144,7 @@ func GetUserItemDetails(c *gin.Context) {
 		return
 	}
 
-	userItem, err := models.GetUserItemDetails(uint(userID), uint(itemID))
+	userItem, err := models.GetUserItemDetails(userID, uint(itemID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "未找到用户物品信息"})
 		return
@@ -93,9 +155,8 @@ func GetUserItemDetails(c *gin.Context) {
 
 // UpdateUserItemQuantity 更新用户物品数量
 func UpdateUserItemQuantity(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+	userID, ok := parseTargetUserID(c)
+	if !ok {
 		return
 	}
 
@@ -106,7 +167,8 @@ func UpdateUserItemQuantity(c *gin.Context) {
 	}
 
 	var request struct {
-		Quantity int `json:"quantity" binding:"required"`
+		Quantity int    `json:"quantity" binding:"required"`
+		Reason   string `json:"reason"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -119,7 +181,28 @@ func UpdateUserItemQuantity(c *gin.Context) {
 		return
 	}
 
-	err = models.UpdateUserItemQuantity(uint(userID), uint(itemID), request.Quantity)
+	// 普通用户只能减少自己的物品数量，增加数量需要管理员权限
+	if !isAdmin(c) {
+		userItem, err := models.GetUserItemDetails(userID, uint(itemID))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "未找到用户物品信息"})
+			return
+		}
+		if request.Quantity > userItem.Quantity {
+			c.JSON(http.StatusForbidden, gin.H{"error": "无权增加物品数量"})
+			return
+		}
+	}
+
+	err = models.ChangeUserItemQuantity(userID, uint(itemID), request.Quantity, currentActorID(c), request.Reason)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未找到用户物品信息"})
+		return
+	}
+	if errors.Is(err, models.ErrStackLimit) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新物品数量失败"})
 		return
@@ -130,9 +213,8 @@ func UpdateUserItemQuantity(c *gin.Context) {
 
 // DeleteUserItem 删除用户物品
 func DeleteUserItem(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+	userID, ok := parseTargetUserID(c)
+	if !ok {
 		return
 	}
 
@@ -142,7 +224,11 @@ func DeleteUserItem(c *gin.Context) {
 		return
 	}
 
-	err = models.DeleteUserItem(uint(userID), uint(itemID))
+	err = models.RemoveUserItem(userID, uint(itemID), currentActorID(c), c.Query("reason"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未找到用户物品信息"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除用户物品失败"})
 		return
@@ -153,9 +239,8 @@ func DeleteUserItem(c *gin.Context) {
 
 // GetUserItemsBySource 获取用户特定来源的物品
 func GetUserItemsBySource(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+	userID, ok := parseTargetUserID(c)
+	if !ok {
 		return
 	}
 
@@ -165,7 +250,7 @@ func GetUserItemsBySource(c *gin.Context) {
 		return
 	}
 
-	items, err := models.GetUserItemsBySource(uint(userID), source)
+	items, err := models.GetUserItemsBySource(userID, source)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户物品失败"})
 		return
@@ -176,13 +261,12 @@ func GetUserItemsBySource(c *gin.Context) {
 
 // GetUserItemCount 获取用户物品总数
 func GetUserItemCount(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+	userID, ok := parseTargetUserID(c)
+	if !ok {
 		return
 	}
 
-	count, err := models.GetUserItemCount(uint(userID))
+	count, err := models.GetUserItemCount(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户物品总数失败"})
 		return
@@ -193,9 +277,8 @@ func GetUserItemCount(c *gin.Context) {
 
 // CheckUserHasItem 检查用户是否拥有特定物品
 func CheckUserHasItem(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+	userID, ok := parseTargetUserID(c)
+	if !ok {
 		return
 	}
 
@@ -205,7 +288,7 @@ func CheckUserHasItem(c *gin.Context) {
 		return
 	}
 
-	hasItem, err := models.HasUserItem(uint(userID), uint(itemID))
+	hasItem, err := models.HasUserItem(userID, uint(itemID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查用户物品失败"})
 		return
@@ -213,3 +296,98 @@ func CheckUserHasItem(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"has_item": hasItem})
 }
+
+// UpdateUserItemShowcase 设置物品是否在个人主页展示（仅本人可操作）
+func UpdateUserItemShowcase(c *gin.Context) {
+	userID, ok := parseTargetUserID(c)
+	if !ok {
+		return
+	}
+
+	currentUserID, _ := c.Get("user_id")
+	if currentUserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只能设置自己的展示物品"})
+		return
+	}
+
+	itemID, err := strconv.ParseUint(c.Param("item_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的物品ID"})
+		return
+	}
+
+	var request struct {
+		Showcased *bool `json:"showcased" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	hasItem, err := models.HasUserItem(userID, uint(itemID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查用户物品失败"})
+		return
+	}
+	if !hasItem {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未找到用户物品信息"})
+		return
+	}
+
+	if err := models.SetUserItemShowcased(userID, uint(itemID), *request.Showcased); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新展示状态失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "展示状态更新成功"})
+}
+
+// GetUserShowcase 获取用户公开展示的物品（公开访问），遵循对方的物品展示可见范围
+func GetUserShowcase(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+	if !checkContentVisible(c, uint(userID), func(p *models.PrivacySettings) string { return p.ShowcaseVisibility }) {
+		return
+	}
+
+	items, err := models.GetUserShowcaseItems(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取展示物品失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": items})
+}
+
+// GetItemLedger 查询物品流水（管理员专用），可按 user_id、item_id 过滤
+func GetItemLedger(c *gin.Context) {
+	userID, _ := strconv.ParseUint(c.Query("user_id"), 10, 32)
+	itemID, _ := strconv.ParseUint(c.Query("item_id"), 10, 32)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	entries, total, err := models.GetItemLedger(uint(userID), uint(itemID), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取物品流水失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": entries,
+		"meta": gin.H{
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"backend/models"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateProfileRequest 更新用户资料请求，未提供的字段保持不变
+type UpdateProfileRequest struct {
+	BirthDate          *string   `json:"birth_date"` // 格式 YYYY-MM-DD，传空字符串表示清除
+	Sex                *string   `json:"sex"`
+	ActivityLevel      *string   `json:"activity_level"`
+	DietaryPreferences *[]string `json:"dietary_preferences"`
+	Allergies          *[]string `json:"allergies"`
+	Timezone           *string   `json:"timezone"`
+	UnitSystem         *string   `json:"unit_system"`
+	AvatarURL          *string   `json:"avatar_url"`
+
+	// 可见范围：public、followers、friends、private，饮食和打卡记录不支持 followers
+	FoodRecordVisibility *string `json:"food_record_visibility"`
+	CheckInVisibility    *string `json:"check_in_visibility"`
+	ShowcaseVisibility   *string `json:"showcase_visibility"`
+}
+
+// GetMyProfile 获取当前用户资料
+func GetMyProfile(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	profile, err := models.GetUserProfile(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户资料失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"profile": profile})
+}
+
+// UpdateMyProfile 更新当前用户资料
+func UpdateMyProfile(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求数据"})
+		return
+	}
+
+	profile, err := models.GetUserProfile(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户资料失败"})
+		return
+	}
+
+	if err := applyProfileUpdate(profile, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := models.SaveUserProfile(profile); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存用户资料失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "用户资料更新成功",
+		"profile": profile,
+	})
+}
+
+// GetMyNutritionTargets 根据用户资料和最新健康状态获取每日营养目标
+func GetMyNutritionTargets(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	target, err := models.GetUserNutritionTargets(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "计算营养目标失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"targets": target})
+}
+
+// applyProfileUpdate 校验并应用资料更新
+func applyProfileUpdate(profile *models.UserProfile, req *UpdateProfileRequest) error {
+	if req.BirthDate != nil {
+		if *req.BirthDate == "" {
+			profile.BirthDate = nil
+		} else {
+			birthDate, err := time.Parse("2006-01-02", *req.BirthDate)
+			if err != nil {
+				return errors.New("出生日期格式错误，应为YYYY-MM-DD")
+			}
+			if birthDate.After(time.Now()) {
+				return errors.New("出生日期不能晚于今天")
+			}
+			profile.BirthDate = &birthDate
+		}
+	}
+
+	if req.Sex != nil {
+		switch *req.Sex {
+		case "", models.SexMale, models.SexFemale, models.SexOther:
+			profile.Sex = *req.Sex
+		default:
+			return errors.New("无效的性别")
+		}
+	}
+
+	if req.ActivityLevel != nil {
+		switch *req.ActivityLevel {
+		case models.ActivitySedentary, models.ActivityLight, models.ActivityModerate,
+			models.ActivityActive, models.ActivityVeryActive:
+			profile.ActivityLevel = *req.ActivityLevel
+		default:
+			return errors.New("无效的活动水平")
+		}
+	}
+
+	if req.DietaryPreferences != nil {
+		prefs := make([]string, 0, len(*req.DietaryPreferences))
+		for _, pref := range *req.DietaryPreferences {
+			if _, ok := models.DietaryPreferences[pref]; !ok {
+				return errors.New("不支持的饮食偏好: " + pref)
+			}
+			prefs = append(prefs, pref)
+		}
+		profile.DietaryPreferences = prefs
+	}
+
+	if req.Allergies != nil {
+		allergies := make([]string, 0, len(*req.Allergies))
+		for _, allergy := range *req.Allergies {
+			if allergy = strings.TrimSpace(allergy); allergy != "" {
+				allergies = append(allergies, allergy)
+			}
+		}
+		profile.Allergies = allergies
+	}
+
+	if req.Timezone != nil {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "" {
+			return errors.New("无效的时区")
+		}
+		profile.Timezone = *req.Timezone
+	}
+
+	if req.UnitSystem != nil {
+		switch *req.UnitSystem {
+		case models.UnitMetric, models.UnitImperial:
+			profile.UnitSystem = *req.UnitSystem
+		default:
+			return errors.New("无效的单位制")
+		}
+	}
+
+	if req.AvatarURL != nil {
+		profile.AvatarURL = *req.AvatarURL
+	}
+
+	for _, setting := range []struct {
+		value *string
+		dest  *string
+		valid func(string) bool
+	}{
+		{req.FoodRecordVisibility, &profile.FoodRecordVisibility, models.IsValidDietVisibility},
+		{req.CheckInVisibility, &profile.CheckInVisibility, models.IsValidDietVisibility},
+		{req.ShowcaseVisibility, &profile.ShowcaseVisibility, models.IsValidVisibility},
+	} {
+		if setting.value == nil {
+			continue
+		}
+		if !setting.valid(*setting.value) {
+			return errors.New("无效的可见范围")
+		}
+		*setting.dest = *setting.value
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package identity
+
+import (
+	"log"
+	"os"
+)
+
+// RegisterFromEnv 根据环境变量注册身份提供方
+//
+//	WECHAT_MP_APP_ID / WECHAT_MP_APP_SECRET       微信小程序
+//	OIDC_ISSUER / OIDC_CLIENT_ID / OIDC_CLIENT_SECRET / OIDC_PROVIDER_NAME  通用 OIDC
+//	OAUTH_MOCK_ENABLED=true                       本地调试用的 mock 提供方（仅 debug 模式生效）
+func RegisterFromEnv(debug bool) {
+	if appID, secret := os.Getenv("WECHAT_MP_APP_ID"), os.Getenv("WECHAT_MP_APP_SECRET"); appID != "" && secret != "" {
+		Register(NewWeChatMiniProgram(appID, secret))
+		log.Println("已启用微信小程序登录")
+	}
+
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		p := NewOIDC(os.Getenv("OIDC_PROVIDER_NAME"), issuer, os.Getenv("OIDC_CLIENT_ID"), os.Getenv("OIDC_CLIENT_SECRET"))
+		Register(p)
+		log.Printf("已启用 OIDC 登录：%s", p.Name())
+	}
+
+	if debug && os.Getenv("OAUTH_MOCK_ENABLED") == "true" {
+		Register(MockProvider{})
+		log.Println("警告：已启用 mock 身份提供方，仅用于本地调试")
+	}
+}
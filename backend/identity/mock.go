@@ -0,0 +1,31 @@
+package identity
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// MockProvider 本地调试用的身份提供方，不访问任何外部服务
+// code 格式为 "<subject>" 或 "<subject>:<email>"，带邮箱时视为已验证邮箱
+type MockProvider struct{}
+
+// Name 返回提供方名称
+func (MockProvider) Name() string {
+	return "mock"
+}
+
+// Exchange 直接从 code 中解析身份
+func (MockProvider) Exchange(_ context.Context, code, _ string) (*Identity, error) {
+	subject, email, _ := strings.Cut(code, ":")
+	if subject == "" {
+		return nil, fmt.Errorf("code 不能为空")
+	}
+	return &Identity{
+		Provider:      "mock",
+		Subject:       subject,
+		Email:         email,
+		EmailVerified: email != "",
+		Name:          subject,
+	}, nil
+}
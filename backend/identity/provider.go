@@ -0,0 +1,66 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrProviderNotFound 未注册的身份提供方
+var ErrProviderNotFound = errors.New("身份提供方不存在")
+
+// Identity 第三方身份提供方返回的用户身份信息
+type Identity struct {
+	Provider      string // 身份提供方名称，如 wechat_mp、oidc
+	Subject       string // 提供方内唯一的用户标识（openid / sub）
+	UnionID       string // 微信开放平台 unionid（可选）
+	Email         string // 邮箱（可选）
+	EmailVerified bool   // 邮箱是否已由提供方验证
+	Name          string // 昵称（可选）
+	AvatarURL     string // 头像（可选）
+}
+
+// Provider 身份提供方接口
+type Provider interface {
+	// Name 返回提供方名称，用于路由和存储
+	Name() string
+	// Exchange 用客户端拿到的授权码换取用户身份
+	Exchange(ctx context.Context, code, redirectURI string) (*Identity, error)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]Provider)
+)
+
+// Register 注册身份提供方，同名提供方会被覆盖
+func Register(p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[p.Name()] = p
+}
+
+// Get 根据名称获取身份提供方
+func Get(name string) (Provider, error) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	p, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotFound, name)
+	}
+	return p, nil
+}
+
+// Names 返回所有已注册的身份提供方名称
+func Names() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,82 @@
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const wechatAPIBase = "https://api.weixin.qq.com"
+
+// WeChatMiniProgram 微信小程序登录（code2session）
+type WeChatMiniProgram struct {
+	AppID     string
+	AppSecret string
+	BaseURL   string // 默认为微信官方接口地址，测试时可替换
+	Client    *http.Client
+}
+
+// NewWeChatMiniProgram 创建微信小程序身份提供方
+func NewWeChatMiniProgram(appID, appSecret string) *WeChatMiniProgram {
+	return &WeChatMiniProgram{
+		AppID:     appID,
+		AppSecret: appSecret,
+		BaseURL:   wechatAPIBase,
+		Client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name 返回提供方名称
+func (w *WeChatMiniProgram) Name() string {
+	return "wechat_mp"
+}
+
+// Exchange 调用 jscode2session 用 wx.login 返回的 code 换取 openid
+func (w *WeChatMiniProgram) Exchange(ctx context.Context, code, _ string) (*Identity, error) {
+	if code == "" {
+		return nil, fmt.Errorf("code 不能为空")
+	}
+
+	query := url.Values{}
+	query.Set("appid", w.AppID)
+	query.Set("secret", w.AppSecret)
+	query.Set("js_code", code)
+	query.Set("grant_type", "authorization_code")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.BaseURL+"/sns/jscode2session?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求微信接口失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OpenID     string `json:"openid"`
+		SessionKey string `json:"session_key"`
+		UnionID    string `json:"unionid"`
+		ErrCode    int    `json:"errcode"`
+		ErrMsg     string `json:"errmsg"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析微信响应失败: %v", err)
+	}
+	if result.ErrCode != 0 {
+		return nil, fmt.Errorf("微信登录失败: %d %s", result.ErrCode, result.ErrMsg)
+	}
+	if result.OpenID == "" {
+		return nil, fmt.Errorf("微信响应中没有 openid")
+	}
+
+	return &Identity{
+		Provider: w.Name(),
+		Subject:  result.OpenID,
+		UnionID:  result.UnionID,
+	}, nil
+}
@@ -3,8 +3,16 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
+	"backend/achievement"
+	"backend/challenge"
+	"backend/coin"
+	"backend/effect"
+	"backend/events"
 	"backend/handlers"
+	"backend/identity"
+	"backend/moderation"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -21,9 +29,35 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// 执行命令行子命令（如 admin create）
+	if runCommand(os.Args[1:]) {
+		return
+	}
+
 	// 初始化数据库
 	handlers.InitDB()
 
+	// 启动领域事件处理，成就在后台根据饮食、打卡和健康记录增量计算
+	achievement.RegisterEventHandlers()
+	coin.RegisterEventHandlers()
+	challenge.RegisterEventHandlers()
+	events.Default().Start()
+
+	// 注册消耗品的使用效果
+	effect.RegisterBuiltins()
+
+	// 启动账号注销清理任务
+	handlers.StartAccountPurgeWorker(time.Hour)
+
+	// 启动挑战进度结算任务
+	challenge.StartWorker(time.Hour)
+
+	// 注册第三方登录身份提供方
+	identity.RegisterFromEnv(gin.Mode() == gin.DebugMode)
+
+	// 配置内容审核分类器
+	moderation.ConfigureFromEnv(gin.Mode() == gin.DebugMode)
+
 	// 获取OpenAI API密钥
 	openAIKey := os.Getenv("OPENAI_API_KEY")
 	if openAIKey == "" {
@@ -90,18 +124,15 @@ func main() {
 		api.POST("/verify-email", handlers.VerifyEmail)
 		api.POST("/resend-verification", handlers.ResendVerification)
 
+		// 第三方登录路由
+		api.GET("/oauth/providers", handlers.GetOAuthProviders)
+		api.POST("/oauth/:provider/login", handlers.OAuthLogin)
+
 		// 静态文件路由（公开访问）
 		api.GET("/image/:filename", staticFileHandler.GetImageInfo)
 
-		// 用户物品相关路由（公开访问）
-		api.GET("/user-items/:user_id", handlers.GetUserItems)
-		api.GET("/user-items/:user_id/:item_id", handlers.GetUserItemDetails)
-		api.GET("/user-items/:user_id/source", handlers.GetUserItemsBySource)
-		api.GET("/user-items/:user_id/count", handlers.GetUserItemCount)
-		api.GET("/user-items/:user_id/check/:item_id", handlers.CheckUserHasItem)
-		api.POST("/user-items/:user_id", handlers.CreateUserItem)
-		api.PUT("/user-items/:user_id/:item_id/quantity", handlers.UpdateUserItemQuantity)
-		api.DELETE("/user-items/:user_id/:item_id", handlers.DeleteUserItem)
+		// 用户物品展示路由（公开访问，仅返回用户选择展示的物品，登录后按关系判断可见范围）
+		api.GET("/users/:user_id/showcase", handlers.OptionalAuthMiddleware(), handlers.GetUserShowcase)
 
 		// 需要认证的路由
 		authorized := api.Group("/")
@@ -110,6 +141,21 @@ func main() {
 			authorized.POST("/analyze-food", foodAnalysisHandler.UploadAndAnalyze)
 			authorized.GET("/me", handlers.GetCurrentUser)
 
+			// 个人数据导出与账号注销路由
+			authorized.GET("/me/export", handlers.ExportMyData)
+			authorized.DELETE("/me", handlers.DeleteMyAccount)
+			authorized.POST("/me/restore", handlers.CancelMyAccountDeletion)
+
+			// 用户资料路由
+			authorized.GET("/me/profile", handlers.GetMyProfile)
+			authorized.PUT("/me/profile", handlers.UpdateMyProfile)
+			authorized.GET("/me/nutrition-targets", handlers.GetMyNutritionTargets)
+
+			// 第三方账号绑定路由
+			authorized.GET("/me/identities", handlers.GetMyIdentities)
+			authorized.POST("/me/identities/:provider", handlers.LinkIdentity)
+			authorized.DELETE("/me/identities/:provider", handlers.UnlinkIdentity)
+
 			// 食物记录路由
 			authorized.POST("/food-records", handlers.CreateFoodRecordHandler)
 			authorized.GET("/food-records", handlers.GetFoodRecordsHandler)
@@ -120,6 +166,7 @@ func main() {
 
 			// 健康分析路由
 			authorized.POST("/health-analysis", healthAnalysisHandler.AnalyzeHealth)
+			authorized.GET("/health-analysis/quota", healthAnalysisHandler.GetHealthAnalysisQuota)
 
 			// 用户健康状态路由
 			authorized.POST("/health-states", handlers.CreateUserHealthStateHandler)
@@ -130,15 +177,90 @@ func main() {
 			authorized.GET("/health-states/latest", handlers.GetLatestUserHealthStateHandler)
 
 			// 打卡相关路由
-			authorized.POST("/check-in", handlers.HandleCheckIn)        // 用户打卡
-			authorized.GET("/check-in/today", handlers.GetTodayCheckIn) // 获取今日打卡状态
-			authorized.GET("/check-ins", handlers.GetUserCheckIns)      // 获取用户所有打卡记录
+			authorized.POST("/check-in", handlers.HandleCheckIn)                     // 用户打卡
+			authorized.GET("/check-in/today", handlers.GetTodayCheckIn)              // 获取今日打卡状态
+			authorized.GET("/check-ins", handlers.GetUserCheckIns)                   // 分页获取用户打卡记录
+			authorized.GET("/check-ins/calendar", handlers.GetCheckInCalendar)       // 获取月度打卡日历和统计
+			authorized.GET("/check-in/streak", handlers.GetCheckInStreak)            // 获取连续打卡状态
+			authorized.POST("/check-in/streak/freeze", handlers.FreezeCheckInStreak) // 使用冻结卡
+			authorized.POST("/check-in/streak/make-up", handlers.MakeUpCheckIn)      // 使用补签卡为昨天补签
+
+			// 成就相关路由
+			authorized.GET("/achievements", handlers.GetMyAchievements) // 获取所有成就的解锁状态和进度
+
+			// 通知相关路由
+			authorized.GET("/notifications", handlers.GetMyNotifications)              // 获取通知列表
+			authorized.POST("/notifications/read", handlers.MarkMyNotificationsRead)   // 批量标记已读
+			authorized.PUT("/notifications/:id/read", handlers.MarkMyNotificationRead) // 标记单条已读
+
+			// 金币与商店路由
+			authorized.GET("/coins", handlers.GetMyCoins)                                // 获取金币余额
+			authorized.GET("/coins/transactions", handlers.GetMyCoinTransactions)        // 获取金币流水
+			authorized.GET("/shop/listings", handlers.GetShopListings)                   // 获取上架商品
+			authorized.POST("/shop/listings/:id/purchase", handlers.PurchaseShopListing) // 购买商品
+
+			// 物品赠送与交易路由
+			authorized.POST("/trades", handlers.CreateTradeOffer)              // 发起赠送或交易
+			authorized.GET("/trades", handlers.GetMyTradeOffers)               // 收到或发出的交易
+			authorized.GET("/trades/:id", handlers.GetTradeOffer)              // 交易详情
+			authorized.POST("/trades/:id/accept", handlers.AcceptTradeOffer)   // 接受交易
+			authorized.POST("/trades/:id/decline", handlers.DeclineTradeOffer) // 拒绝交易
+			authorized.POST("/trades/:id/cancel", handlers.CancelTradeOffer)   // 取消发出的交易
 
 			// 物品查询相关路由（所有用户可访问）
 			authorized.GET("/items", handlers.GetItems)
 			authorized.GET("/items/:id", handlers.GetItem)
 			authorized.GET("/items/search", handlers.SearchItems)
 			authorized.GET("/items/sources", handlers.GetItemSources)
+			authorized.POST("/items/:id/use", handlers.UseItem) // 使用持有的消耗品
+
+			// 社区帖子相关路由
+			authorized.GET("/posts", handlers.GetFeed)
+			authorized.POST("/posts", handlers.CreatePost)
+			authorized.GET("/posts/:id", handlers.GetPost)
+			authorized.DELETE("/posts/:id", handlers.DeletePost)
+			authorized.POST("/posts/:id/like", handlers.LikePost)
+			authorized.DELETE("/posts/:id/like", handlers.UnlikePost)
+			authorized.GET("/posts/:id/comments", handlers.GetPostComments)
+			authorized.POST("/posts/:id/comments", handlers.CreatePostComment)
+			authorized.DELETE("/posts/:id/comments/:comment_id", handlers.DeletePostComment)
+
+			// 用户关系与隐私路由
+			authorized.GET("/users/:user_id/profile", handlers.GetUserPublicProfile)          // 公开资料、关系和统计
+			authorized.GET("/users/:user_id/followers", handlers.GetUserFollowers)            // 关注者列表
+			authorized.GET("/users/:user_id/following", handlers.GetUserFollowing)            // 关注列表
+			authorized.GET("/users/:user_id/food-records", handlers.GetOtherUserFoodRecords)  // 按可见范围查看饮食记录
+			authorized.GET("/users/:user_id/check-ins", handlers.GetOtherUserCheckIns)        // 按可见范围查看打卡记录
+			authorized.POST("/users/:user_id/follow", handlers.FollowUser)                    // 关注
+			authorized.DELETE("/users/:user_id/follow", handlers.UnfollowUser)                // 取消关注
+			authorized.POST("/users/:user_id/friend-request", handlers.SendFriendRequest)     // 发送好友请求
+			authorized.DELETE("/users/:user_id/friend-request", handlers.CancelFriendRequest) // 撤回好友请求
+			authorized.POST("/users/:user_id/friend-request/accept", handlers.AcceptFriendRequest)
+			authorized.POST("/users/:user_id/friend-request/decline", handlers.DeclineFriendRequest)
+			authorized.DELETE("/users/:user_id/friend", handlers.RemoveFriend)  // 解除好友关系
+			authorized.POST("/users/:user_id/block", handlers.BlockUser)        // 拉黑
+			authorized.DELETE("/users/:user_id/block", handlers.UnblockUser)    // 取消拉黑
+			authorized.GET("/me/friends", handlers.GetMyFriends)                // 好友列表
+			authorized.GET("/me/friend-requests", handlers.GetMyFriendRequests) // 收到或发出的好友请求
+			authorized.GET("/me/blocks", handlers.GetMyBlocks)                  // 黑名单
+
+			// 用户物品相关路由（仅本人或管理员可访问）
+			authorized.GET("/user-items/:user_id", handlers.GetUserItems)
+			authorized.GET("/user-items/:user_id/:item_id", handlers.GetUserItemDetails)
+			authorized.GET("/user-items/:user_id/source", handlers.GetUserItemsBySource)
+			authorized.GET("/user-items/:user_id/count", handlers.GetUserItemCount)
+			authorized.GET("/user-items/:user_id/check/:item_id", handlers.CheckUserHasItem)
+			authorized.POST("/user-items/:user_id", handlers.CreateUserItem)
+			authorized.PUT("/user-items/:user_id/:item_id/quantity", handlers.UpdateUserItemQuantity)
+			authorized.PUT("/user-items/:user_id/:item_id/showcase", handlers.UpdateUserItemShowcase)
+			authorized.DELETE("/user-items/:user_id/:item_id", handlers.DeleteUserItem)
+
+			// 挑战相关路由
+			authorized.GET("/challenges", handlers.GetChallenges)                           // 挑战列表
+			authorized.GET("/challenges/:id", handlers.GetChallenge)                        // 挑战详情和我的进度
+			authorized.POST("/challenges/:id/join", handlers.JoinChallenge)                 // 加入挑战
+			authorized.DELETE("/challenges/:id/join", handlers.LeaveChallenge)              // 退出挑战
+			authorized.GET("/challenges/:id/leaderboard", handlers.GetChallengeLeaderboard) // 排行榜
 		}
 
 		// 管理员路由
@@ -155,6 +277,48 @@ func main() {
 			admin.POST("/items", handlers.CreateItem)
 			admin.PUT("/items/:id", handlers.UpdateItem)
 			admin.DELETE("/items/:id", handlers.DeleteItem)
+			admin.POST("/items/import", handlers.ImportItems) // ZIP 批量导入
+			admin.GET("/items/export", handlers.ExportItems)  // ZIP 导出
+			admin.GET("/item-ledger", handlers.GetItemLedger)
+			admin.GET("/item-effects", handlers.GetItemEffects)
+
+			// 商店和金币管理路由（仅管理员可访问）
+			admin.GET("/shop-listings", handlers.AdminGetShopListings)
+			admin.POST("/shop-listings", handlers.CreateShopListing)
+			admin.PUT("/shop-listings/:id", handlers.UpdateShopListing)
+			admin.DELETE("/shop-listings/:id", handlers.DeleteShopListing)
+			admin.POST("/coins/adjust", handlers.AdjustUserCoins)
+			admin.GET("/coins/:user_id/transactions", handlers.GetUserCoinTransactions)
+
+			// 成就规则管理路由（仅管理员可访问）
+			admin.GET("/achievement-rules", handlers.GetAchievementRules)
+			admin.POST("/achievement-rules", handlers.CreateAchievementRule)
+			admin.POST("/achievement-rules/dry-run", handlers.DryRunAchievementRule)
+			admin.PUT("/achievement-rules/:id", handlers.UpdateAchievementRule)
+			admin.DELETE("/achievement-rules/:id", handlers.DeleteAchievementRule)
+
+			// 挑战管理路由（仅管理员可访问）
+			admin.GET("/challenges", handlers.AdminGetChallenges)
+			admin.POST("/challenges", handlers.CreateChallenge)
+			admin.PUT("/challenges/:id", handlers.UpdateChallenge)
+			admin.DELETE("/challenges/:id", handlers.DeleteChallenge)
+			admin.POST("/challenges/:id/rewards/retry", handlers.AdminRetryChallengeRewards) // 重新发放失败的完成奖励
+
+			// 内容审核路由（仅管理员可访问）
+			admin.GET("/moderation/cases", handlers.AdminGetModerationCases)                 // 审核队列
+			admin.POST("/moderation/cases/:id/approve", handlers.AdminApproveModerationCase) // 审核通过
+			admin.POST("/moderation/cases/:id/remove", handlers.AdminRemoveModerationCase)   // 审核不通过并下架
+			admin.POST("/moderation/takedown", handlers.AdminTakeDownContent)                // 直接下架已发布的内容
+			admin.GET("/moderation/images/*filepath", handlers.AdminGetQuarantinedImage)     // 查看被隔离的图片
+			admin.GET("/moderation/keywords", handlers.AdminGetModerationKeywords)           // 关键词列表
+			admin.POST("/moderation/keywords", handlers.AdminCreateModerationKeyword)        // 添加关键词
+			admin.DELETE("/moderation/keywords/:id", handlers.AdminDeleteModerationKeyword)  // 删除关键词
+
+			// 成就回溯任务（仅管理员可访问）
+			admin.GET("/achievement-backfills", handlers.GetAchievementBackfills)
+			admin.POST("/achievement-backfills", handlers.CreateAchievementBackfill)
+			admin.GET("/achievement-backfills/:id", handlers.GetAchievementBackfill)
+			admin.POST("/achievement-backfills/:id/resume", handlers.ResumeAchievementBackfill)
 		}
 
 		// 应用更新相关路由
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AccountDeletion 账号注销申请，宽限期结束后彻底删除用户的所有数据
+type AccountDeletion struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	UserID      uint      `json:"user_id" gorm:"uniqueIndex;not null"` // 申请注销的用户ID
+	RequestedAt time.Time `json:"requested_at" gorm:"not null"`        // 申请时间
+	ScheduledAt time.Time `json:"scheduled_at" gorm:"index;not null"`  // 计划删除时间
+}
+
+// userOwnedModels 按 user_id 归属于用户的数据表，注销时全部物理删除
+// 新增用户数据表时需要同步加入此列表
+var userOwnedModels = []interface{}{
+	&FoodRecord{},
+	&UserHealthState{},
+	&CheckIn{},
+	&CheckInFoodRecord{},
+	&UserItem{},
+	&UserIdentity{},
+	&UserProfile{},
+	&AchievementProgress{},
+	&Notification{},
+	&ItemGrant{},
+	&ItemLedger{},
+	&BackfillMatch{},
+	&StreakFreeze{},
+	&CoinAccount{},
+	&CoinTransaction{},
+	&AnalysisCredit{},
+	&AnalysisUsage{},
+	&ModerationCase{},
+}
+
+// GetAccountDeletion 获取用户的注销申请
+func GetAccountDeletion(userID uint) (*AccountDeletion, error) {
+	var deletion AccountDeletion
+	err := DB.Where("user_id = ?", userID).First(&deletion).Error
+	if err != nil {
+		return nil, err
+	}
+	return &deletion, nil
+}
+
+// ScheduleAccountDeletion 申请注销账号，已有申请时返回原申请
+func ScheduleAccountDeletion(userID uint, gracePeriod time.Duration) (*AccountDeletion, error) {
+	existing, err := GetAccountDeletion(userID)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	now := time.Now()
+	deletion := &AccountDeletion{
+		UserID:      userID,
+		RequestedAt: now,
+		ScheduledAt: now.Add(gracePeriod),
+	}
+	if err := DB.Create(deletion).Error; err != nil {
+		return nil, err
+	}
+	return deletion, nil
+}
+
+// CancelAccountDeletion 撤销注销申请
+func CancelAccountDeletion(userID uint) error {
+	result := DB.Where("user_id = ?", userID).Delete(&AccountDeletion{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetDueAccountDeletions 获取已过宽限期、待删除的注销申请
+func GetDueAccountDeletions(now time.Time, limit int) ([]AccountDeletion, error) {
+	var deletions []AccountDeletion
+	err := DB.Where("scheduled_at <= ?", now).
+		Order("scheduled_at").
+		Limit(limit).
+		Find(&deletions).Error
+	return deletions, err
+}
+
+// PurgeUserData 在一个事务中物理删除用户的所有数据（包括软删除的记录）
+func PurgeUserData(userID uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		// 跳过钩子，物品流水等不可修改的记录在注销时也需要清除
+		purge := tx.Session(&gorm.Session{SkipHooks: true})
+		for _, model := range userOwnedModels {
+			if err := purge.Unscoped().Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		// 交易记录同时属于发起人和接收人，单独清理
+		if err := purgeTradeOffers(tx, userID); err != nil {
+			return err
+		}
+
+		// 帖子的图片、点赞和评论可能属于其他用户，单独清理
+		if err := purgePosts(purge, userID); err != nil {
+			return err
+		}
+
+		// 关注、好友和拉黑关系中用户可能是任一方
+		if err := purgeRelations(purge, userID); err != nil {
+			return err
+		}
+
+		// 挑战的参与人数需要随参与记录一起修正
+		if err := purgeChallengeParticipations(purge, userID); err != nil {
+			return err
+		}
+
+		var user User
+		err := tx.Unscoped().First(&user, userID).Error
+		if err == nil {
+			if err := tx.Unscoped().Where("email = ?", user.Email).Delete(&VerificationCode{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Delete(&user).Error; err != nil {
+				return err
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		return tx.Where("user_id = ?", userID).Delete(&AccountDeletion{}).Error
+	})
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// RuleState 成就规则的增量计算状态，日期均为用户时区下的 YYYY-MM-DD
+type RuleState struct {
+	Count            int             `json:"count,omitempty"`              // 匹配记录数
+	DayCounts        map[string]int  `json:"day_counts,omitempty"`         // 统计窗口内每天的匹配记录数
+	LastDay          string          `json:"last_day,omitempty"`           // 最近一条匹配记录所在日期
+	LastDayCount     int             `json:"last_day_count,omitempty"`     // 最近一天的匹配记录数
+	QualifiedDays    int             `json:"qualified_days,omitempty"`     // 达到每日最低记录数的天数
+	LastQualifiedDay string          `json:"last_qualified_day,omitempty"` // 最近一个达标日期
+	CurrentRun       int             `json:"current_run,omitempty"`        // 当前连续达标天数
+	LongestRun       int             `json:"longest_run,omitempty"`        // 最长连续达标天数
+	HadBreak         bool            `json:"had_break,omitempty"`          // 是否出现过中断
+	Values           map[string]bool `json:"values,omitempty"`             // 出现过的不同取值
+	FirstDay         string          `json:"first_day,omitempty"`          // 该来源第一条记录所在日期
+	LastViolationDay string          `json:"last_violation_day,omitempty"` // 最近一次出现匹配记录的日期
+}
+
+// AchievementProgress 用户在某条成就规则上的增量计算状态
+type AchievementProgress struct {
+	ID             uint      `json:"id" gorm:"primarykey"`
+	UserID         uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_user_rule"`
+	RuleID         uint      `json:"rule_id" gorm:"not null;uniqueIndex:idx_user_rule"`
+	DefinitionHash string    `json:"-" gorm:"size:64"`                   // 规则定义的哈希，规则修改后需要重新计算
+	State          RuleState `json:"-" gorm:"type:text;serializer:json"` // 增量计算状态
+	LastRecordID   uint      `json:"-"`                                  // 已计入状态的最大记录ID，避免重复计数
+	RecordCount    int64     `json:"-"`                                  // 已计入状态的记录数，与数据库不一致说明有事件丢失，需要重新计算
+	Current        int       `json:"current"`                            // 最近一次计算的进度
+	Target         int       `json:"target"`                             // 目标
+	Achieved       bool      `json:"achieved"`                           // 是否已达成
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// GetUserAchievementProgress 获取用户所有规则的计算状态，按规则ID索引
+func GetUserAchievementProgress(userID uint) (map[uint]*AchievementProgress, error) {
+	var rows []AchievementProgress
+	if err := DB.Where("user_id = ?", userID).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	progress := make(map[uint]*AchievementProgress, len(rows))
+	for i := range rows {
+		progress[rows[i].RuleID] = &rows[i]
+	}
+	return progress, nil
+}
+
+// CountUserSourceRecords 统计用户某个数据来源中ID不大于 maxID 的记录数
+func CountUserSourceRecords(userID uint, source string, maxID uint) (int64, error) {
+	var model interface{}
+	switch source {
+	case RuleSourceFoodRecord:
+		model = &FoodRecord{}
+	case RuleSourceCheckIn:
+		model = &CheckIn{}
+	case RuleSourceHealthState:
+		model = &UserHealthState{}
+	default:
+		return 0, fmt.Errorf("不支持的数据来源: %s", source)
+	}
+	var count int64
+	err := DB.Model(model).Where("user_id = ? AND id <= ?", userID, maxID).Count(&count).Error
+	return count, err
+}
+
+// SaveAchievementProgress 保存规则计算状态（按用户和规则唯一）
+func SaveAchievementProgress(progress *AchievementProgress) error {
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "rule_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"definition_hash", "state", "last_record_id", "record_count", "current", "target", "achieved", "updated_at"}),
+	}).Create(progress).Error
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// 成就规则类型
+const (
+	RuleTypeCount          = "count"           // 满足条件的记录数
+	RuleTypeDistinctDays   = "distinct_days"   // 满足条件的不同天数
+	RuleTypeStreak         = "streak"          // 最长连续天数
+	RuleTypeReboundStreak  = "rebound_streak"  // 中断后重新开始的连续天数
+	RuleTypeDistinctValues = "distinct_values" // 某字段不同取值的个数
+	RuleTypeAbsenceStreak  = "absence_streak"  // 截至今天连续没有出现满足条件记录的天数
+)
+
+// 成就规则数据来源
+const (
+	RuleSourceFoodRecord  = "food_record"
+	RuleSourceCheckIn     = "check_in"
+	RuleSourceHealthState = "health_state"
+)
+
+// RuleFilter 记录过滤条件，如 {"field": "protein", "op": ">=", "value": 20}
+type RuleFilter struct {
+	Field string      `json:"field"`
+	Op    string      `json:"op"`    // ==, !=, <, <=, >, >=, in, between
+	Value interface{} `json:"value"` // in 为数组，between 为 [最小值, 最大值]
+}
+
+// RuleDefinition 成就规则定义
+type RuleDefinition struct {
+	Type       string       `json:"type"`                  // 规则类型
+	Source     string       `json:"source"`                // 数据来源：food_record / check_in / health_state
+	Filters    []RuleFilter `json:"filters,omitempty"`     // 记录需要同时满足的条件
+	WindowDays int          `json:"window_days,omitempty"` // 只统计最近N天的记录，0 表示全部历史
+	MinPerDay  int          `json:"min_per_day,omitempty"` // 按天统计时，每天至少需要的记录数，默认1
+	Field      string       `json:"field,omitempty"`       // distinct_values 统计的字段
+	Target     int          `json:"target"`                // 达成目标
+}
+
+// AchievementRule 成就规则，达成后发放关联的物品
+type AchievementRule struct {
+	gorm.Model
+	ItemID      uint           `json:"item_id" gorm:"index;not null"`               // 达成后发放的物品ID
+	Name        string         `json:"name" gorm:"size:100;not null"`               // 规则名称
+	Description string         `json:"description" gorm:"type:text"`                // 获取条件说明
+	Definition  RuleDefinition `json:"definition" gorm:"type:text;serializer:json"` // 规则定义
+	Enabled     bool           `json:"enabled" gorm:"not null"`                     // 是否启用
+	CoinReward  int64          `json:"coin_reward" gorm:"not null;default:0"`       // 达成后奖励的金币
+	Item        *Item          `json:"item,omitempty" gorm:"foreignKey:ItemID"`     // 关联物品
+}
+
+// CreateAchievementRule 创建成就规则
+func CreateAchievementRule(rule *AchievementRule) error {
+	return DB.Create(rule).Error
+}
+
+// GetAchievementRuleByID 根据ID获取成就规则
+func GetAchievementRuleByID(ruleID uint) (*AchievementRule, error) {
+	var rule AchievementRule
+	err := DB.Preload("Item").First(&rule, ruleID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// GetAchievementRulesByIDs 根据ID列表获取成就规则
+func GetAchievementRulesByIDs(ruleIDs []uint) ([]AchievementRule, error) {
+	var rules []AchievementRule
+	err := DB.Preload("Item").Where("id IN ?", ruleIDs).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// GetAchievementRules 获取所有成就规则
+func GetAchievementRules() ([]AchievementRule, error) {
+	var rules []AchievementRule
+	err := DB.Preload("Item").Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// GetEnabledAchievementRules 获取所有启用的成就规则
+func GetEnabledAchievementRules() ([]AchievementRule, error) {
+	var rules []AchievementRule
+	err := DB.Preload("Item").Where("enabled = ?", true).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// UpdateAchievementRule 更新成就规则
+func UpdateAchievementRule(rule *AchievementRule) error {
+	return DB.Omit("Item").Save(rule).Error
+}
+
+// DeleteAchievementRule 删除成就规则
+func DeleteAchievementRule(ruleID uint) error {
+	return DB.Delete(&AchievementRule{}, ruleID).Error
+}
+
+// CountAchievementRulesByItem 统计引用指定物品的成就规则数量
+func CountAchievementRulesByItem(itemID uint) (int64, error) {
+	var count int64
+	err := DB.Unscoped().Model(&AchievementRule{}).Where("item_id = ?", itemID).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNoAnalysisCredits 今日免费次数已用完且没有剩余的分析次数
+var ErrNoAnalysisCredits = errors.New("今日免费分析次数已用完，可使用分析次数卡增加次数")
+
+// AnalysisCredit 用户额外的健康分析次数，通过使用分析次数卡获得
+type AnalysisCredit struct {
+	UserID    uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Credits   int       `json:"credits" gorm:"not null;default:0"` // 剩余次数
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AnalysisUsage 用户每天的健康分析使用情况
+type AnalysisUsage struct {
+	ID          uint   `json:"id" gorm:"primarykey"`
+	UserID      uint   `json:"user_id" gorm:"not null;uniqueIndex:idx_analysis_usage"`
+	Date        string `json:"date" gorm:"size:10;not null;uniqueIndex:idx_analysis_usage"` // 用户时区 YYYY-MM-DD
+	Count       int    `json:"count" gorm:"not null;default:0"`                             // 当天分析次数
+	CreditsUsed int    `json:"credits_used" gorm:"not null;default:0"`                      // 其中消耗的额外次数
+}
+
+// AnalysisQuota 用户当天的健康分析额度
+type AnalysisQuota struct {
+	Date          string `json:"date"`
+	Unlimited     bool   `json:"unlimited"`      // 未限制每天免费次数时为 true，此时其余额度字段无意义
+	FreeLimit     int    `json:"free_limit"`     // 每天免费次数
+	FreeRemaining int    `json:"free_remaining"` // 今日剩余免费次数
+	Credits       int    `json:"credits"`        // 剩余额外次数
+}
+
+// AddAnalysisCredits 在调用方的事务中增加用户的分析次数
+func AddAnalysisCredits(tx *gorm.DB, userID uint, credits int) (int, error) {
+	account, err := lockAnalysisCredit(tx, userID)
+	if err != nil {
+		return 0, err
+	}
+	account.Credits += credits
+	if err := tx.Model(account).Update("credits", account.Credits).Error; err != nil {
+		return 0, err
+	}
+	return account.Credits, nil
+}
+
+// GetAnalysisQuota 获取用户在某天的健康分析额度，freeLimit 为每天免费次数，不大于 0 表示不限制
+func GetAnalysisQuota(userID uint, date string, freeLimit int) (*AnalysisQuota, error) {
+	if freeLimit <= 0 {
+		return &AnalysisQuota{Date: date, Unlimited: true}, nil
+	}
+	var usage AnalysisUsage
+	if err := DB.Where("user_id = ? AND date = ?", userID, date).Limit(1).Find(&usage).Error; err != nil {
+		return nil, err
+	}
+	var account AnalysisCredit
+	if err := DB.Where("user_id = ?", userID).Limit(1).Find(&account).Error; err != nil {
+		return nil, err
+	}
+
+	quota := &AnalysisQuota{
+		Date:      date,
+		FreeLimit: freeLimit,
+		Credits:   account.Credits,
+	}
+	if free := usage.Count - usage.CreditsUsed; free < freeLimit {
+		quota.FreeRemaining = freeLimit - free
+	}
+	return quota, nil
+}
+
+// ReserveHealthAnalysis 占用一次健康分析额度，优先使用当天的 freeLimit 次免费次数，返回是否消耗了额外次数
+// 免费次数用完且没有额外次数时返回 ErrNoAnalysisCredits，调用方应在 freeLimit 不大于 0（不限制）时跳过
+func ReserveHealthAnalysis(userID uint, date string, freeLimit int) (usedCredit bool, err error) {
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		usage, err := lockAnalysisUsage(tx, userID, date)
+		if err != nil {
+			return err
+		}
+
+		if usage.Count-usage.CreditsUsed >= freeLimit {
+			account, err := lockAnalysisCredit(tx, userID)
+			if err != nil {
+				return err
+			}
+			if account.Credits < 1 {
+				return ErrNoAnalysisCredits
+			}
+			if err := tx.Model(account).Update("credits", account.Credits-1).Error; err != nil {
+				return err
+			}
+			usage.CreditsUsed++
+			usedCredit = true
+		}
+
+		usage.Count++
+		return tx.Model(usage).Updates(map[string]interface{}{
+			"count":        usage.Count,
+			"credits_used": usage.CreditsUsed,
+		}).Error
+	})
+	return usedCredit, err
+}
+
+// ReleaseHealthAnalysis 分析失败时归还 ReserveHealthAnalysis 占用的额度
+func ReleaseHealthAnalysis(userID uint, date string, usedCredit bool) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		usage, err := lockAnalysisUsage(tx, userID, date)
+		if err != nil {
+			return err
+		}
+		if usage.Count == 0 {
+			return nil
+		}
+
+		usage.Count--
+		if usedCredit && usage.CreditsUsed > 0 {
+			usage.CreditsUsed--
+			if _, err := AddAnalysisCredits(tx, userID, 1); err != nil {
+				return err
+			}
+		}
+		return tx.Model(usage).Updates(map[string]interface{}{
+			"count":        usage.Count,
+			"credits_used": usage.CreditsUsed,
+		}).Error
+	})
+}
+
+// lockAnalysisCredit 锁定用户的分析次数账户，不存在时创建
+func lockAnalysisCredit(tx *gorm.DB, userID uint) (*AnalysisCredit, error) {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&AnalysisCredit{UserID: userID}).Error; err != nil {
+		return nil, err
+	}
+	var account AnalysisCredit
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, "user_id = ?", userID).Error
+	return &account, err
+}
+
+// lockAnalysisUsage 锁定用户某天的分析使用记录，不存在时创建
+func lockAnalysisUsage(tx *gorm.DB, userID uint, date string) (*AnalysisUsage, error) {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&AnalysisUsage{UserID: userID, Date: date}).Error; err != nil {
+		return nil, err
+	}
+	var usage AnalysisUsage
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND date = ?", userID, date).
+		First(&usage).Error
+	return &usage, err
+}
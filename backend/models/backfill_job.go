@@ -0,0 +1,112 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 成就回溯任务状态
+const (
+	BackfillStatusPending   = "pending"
+	BackfillStatusRunning   = "running"
+	BackfillStatusCompleted = "completed"
+	BackfillStatusFailed    = "failed"
+)
+
+// BackfillJob 成就回溯任务，按用户ID分批重新计算指定规则，可从 LastUserID 处继续执行
+type BackfillJob struct {
+	gorm.Model
+	RuleIDs        []uint     `json:"rule_ids" gorm:"type:text;serializer:json"` // 需要回溯的规则
+	DryRun         bool       `json:"dry_run" gorm:"not null"`                   // 试运行，只记录结果不发放物品
+	BatchSize      int        `json:"batch_size"`                                // 每批处理的用户数
+	Status         string     `json:"status" gorm:"size:20;index"`               // 任务状态
+	LastUserID     uint       `json:"last_user_id"`                              // 已处理到的用户ID
+	TotalUsers     int64      `json:"total_users"`                               // 需要处理的用户总数
+	ProcessedUsers int64      `json:"processed_users"`                           // 已处理的用户数
+	Matched        int64      `json:"matched"`                                   // 达成（或试运行时将会达成）的次数
+	Error          string     `json:"error" gorm:"type:text"`                    // 失败原因
+	CreatedBy      *uint      `json:"created_by"`                                // 创建人
+	StartedAt      *time.Time `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at"`
+}
+
+// BackfillMatch 回溯任务中达成规则的用户
+type BackfillMatch struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	JobID     uint      `json:"job_id" gorm:"index;not null"`
+	UserID    uint      `json:"user_id" gorm:"index;not null"`
+	RuleID    uint      `json:"rule_id" gorm:"not null"`
+	ItemID    uint      `json:"item_id" gorm:"not null"`
+	Current   int       `json:"current"`
+	Target    int       `json:"target"`
+	Granted   bool      `json:"granted"` // 是否实际发放（试运行或已拥有时为 false）
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateBackfillJob 创建回溯任务
+func CreateBackfillJob(job *BackfillJob) error {
+	return DB.Create(job).Error
+}
+
+// GetBackfillJob 获取回溯任务
+func GetBackfillJob(jobID uint) (*BackfillJob, error) {
+	var job BackfillJob
+	if err := DB.First(&job, jobID).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetBackfillJobs 获取最近的回溯任务
+func GetBackfillJobs(limit int) ([]BackfillJob, error) {
+	var jobs []BackfillJob
+	err := DB.Order("id DESC").Limit(limit).Find(&jobs).Error
+	return jobs, err
+}
+
+// SaveBackfillJob 保存回溯任务进度
+func SaveBackfillJob(job *BackfillJob) error {
+	return DB.Save(job).Error
+}
+
+// CreateBackfillMatches 批量保存回溯结果
+func CreateBackfillMatches(matches []BackfillMatch) error {
+	if len(matches) == 0 {
+		return nil
+	}
+	return DB.Create(&matches).Error
+}
+
+// GetBackfillMatches 分页获取回溯任务的结果
+func GetBackfillMatches(jobID uint, page, pageSize int) ([]BackfillMatch, int64, error) {
+	var matches []BackfillMatch
+	var total int64
+
+	query := DB.Model(&BackfillMatch{}).Where("job_id = ?", jobID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("id").Offset(offset).Limit(pageSize).Find(&matches).Error
+	return matches, total, err
+}
+
+// GetUserIDsAfter 按ID升序获取 afterID 之后的一批用户ID
+func GetUserIDsAfter(afterID uint, limit int) ([]uint, error) {
+	var ids []uint
+	err := DB.Model(&User{}).
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// CountUsersAfter 统计 afterID 之后的用户数
+func CountUsersAfter(afterID uint) (int64, error) {
+	var count int64
+	err := DB.Model(&User{}).Where("id > ?", afterID).Count(&count).Error
+	return count, err
+}
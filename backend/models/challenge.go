@@ -0,0 +1,445 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 挑战目标类型
+const (
+	ChallengeGoalCount        = "count"         // 满足条件的记录数
+	ChallengeGoalDistinctDays = "distinct_days" // 满足条件的天数
+	ChallengeGoalStreak       = "streak"        // 满足条件的最长连续天数
+	ChallengeGoalTargetDays   = "target_days"   // 当天营养摄入满足个人目标的天数
+	ChallengeGoalTargetStreak = "target_streak" // 当天营养摄入满足个人目标的最长连续天数
+)
+
+// 营养目标的比较方向
+const (
+	TargetDirectionUnder = "under" // 不超过目标，如钠、糖
+	TargetDirectionReach = "reach" // 达到目标，如蛋白质、膳食纤维
+)
+
+// 挑战状态，由开始和结束时间决定
+const (
+	ChallengeStatusUpcoming = "upcoming"
+	ChallengeStatusActive   = "active"
+	ChallengeStatusEnded    = "ended"
+)
+
+var (
+	ErrChallengeEnded     = errors.New("挑战已结束")
+	ErrChallengeCompleted = errors.New("已完成的挑战不能退出")
+	ErrNotJoinedChallenge = errors.New("尚未加入该挑战")
+)
+
+// ChallengeGoal 挑战目标，只统计挑战期间内的记录
+// count、distinct_days、streak 与成就规则的含义相同；target_days、target_streak 按天汇总饮食记录，
+// 与用户的每日营养目标比较
+type ChallengeGoal struct {
+	Type      string       `json:"type"`                  // 目标类型
+	Source    string       `json:"source"`                // 数据来源：food_record / check_in，target_* 固定为 food_record
+	Filters   []RuleFilter `json:"filters,omitempty"`     // 记录需要同时满足的条件，target_* 不支持
+	MinPerDay int          `json:"min_per_day,omitempty"` // 按天统计时，每天至少需要的记录数，默认1
+	Nutrient  string       `json:"nutrient,omitempty"`    // target_* 比较的营养素，如 sodium
+	Direction string       `json:"direction,omitempty"`   // target_* 的比较方向：under / reach
+	Target    int          `json:"target"`                // 达成目标
+}
+
+// Challenge 限时挑战，用户加入后根据挑战期间的饮食和打卡记录计算进度
+type Challenge struct {
+	gorm.Model
+	Title            string                `json:"title" gorm:"size:100;not null"`                       // 挑战名称
+	Description      string                `json:"description" gorm:"type:text"`                         // 挑战说明
+	Goal             ChallengeGoal         `json:"goal" gorm:"type:text;serializer:json"`                // 挑战目标
+	StartAt          time.Time             `json:"start_at" gorm:"index;not null"`                       // 开始时间
+	EndAt            time.Time             `json:"end_at" gorm:"index;not null"`                         // 结束时间（不含）
+	RewardItemID     *uint                 `json:"reward_item_id" gorm:"index"`                          // 完成后发放的物品，为空表示没有物品奖励
+	RewardQuantity   int                   `json:"reward_quantity" gorm:"not null;default:1"`            // 发放的物品数量
+	ParticipantCount int                   `json:"participant_count" gorm:"not null;default:0"`          // 参与人数
+	CreatedBy        uint                  `json:"created_by"`                                           // 创建挑战的管理员
+	FinalizedAt      *time.Time            `json:"finalized_at,omitempty"`                               // 结束后完成最终结算的时间
+	RewardItem       *Item                 `json:"reward_item,omitempty" gorm:"foreignKey:RewardItemID"` // 奖励物品
+	Participation    *ChallengeParticipant `json:"participation,omitempty" gorm:"-"`                     // 当前用户的参与情况，由 LoadChallengeParticipations 填充
+}
+
+// Status 返回挑战在 now 时的状态
+func (c *Challenge) Status(now time.Time) string {
+	switch {
+	case now.Before(c.StartAt):
+		return ChallengeStatusUpcoming
+	case now.Before(c.EndAt):
+		return ChallengeStatusActive
+	}
+	return ChallengeStatusEnded
+}
+
+// ChallengeParticipant 挑战参与者及其进度
+type ChallengeParticipant struct {
+	ChallengeID    uint         `json:"challenge_id" gorm:"primaryKey;autoIncrement:false"`
+	UserID         uint         `json:"user_id" gorm:"primaryKey;autoIncrement:false;index"`
+	Progress       int          `json:"progress" gorm:"not null;default:0"` // 当前进度
+	Completed      bool         `json:"completed" gorm:"not null;default:false"`
+	CompletedAt    *time.Time   `json:"completed_at,omitempty"`
+	RewardPending  bool         `json:"reward_pending" gorm:"not null;default:false"` // 已完成但奖励尚未发放成功
+	RewardError    string       `json:"reward_error,omitempty" gorm:"type:text"`      // 最近一次发放奖励失败的原因
+	CreatedAt      time.Time    `json:"joined_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+	Rank           int          `json:"rank,omitempty" gorm:"column:ranking;->;-:migration"` // 排名，仅排行榜查询时填充
+	User           *UserSummary `json:"user,omitempty" gorm:"-"`
+	ProgressHidden bool         `json:"progress_hidden,omitempty" gorm:"-"` // 进度按参与者的可见范围对当前用户隐藏，仅排行榜查询时填充
+}
+
+// hideProgress 隐藏参与者的进度和完成情况，只保留排名和用户信息
+func (p *ChallengeParticipant) hideProgress() {
+	p.Progress = 0
+	p.Completed = false
+	p.CompletedAt = nil
+	p.ProgressHidden = true
+}
+
+// progressVisibility 返回挑战进度适用的可见范围，进度来自哪类记录就遵循哪类记录的设置
+func (g ChallengeGoal) progressVisibility(settings *PrivacySettings) string {
+	if g.Source == RuleSourceCheckIn {
+		return settings.CheckInVisibility
+	}
+	return settings.FoodRecordVisibility
+}
+
+// CreateChallenge 创建挑战
+func CreateChallenge(challenge *Challenge) error {
+	return DB.Omit("RewardItem").Create(challenge).Error
+}
+
+// UpdateChallenge 更新挑战，参与人数和结算时间不会被覆盖
+func UpdateChallenge(challenge *Challenge) error {
+	return DB.Omit("RewardItem", "participant_count", "finalized_at").Save(challenge).Error
+}
+
+// DeleteChallenge 删除挑战，参与记录保留，已发放的奖励不会收回
+func DeleteChallenge(challengeID uint) error {
+	return DB.Delete(&Challenge{}, challengeID).Error
+}
+
+// GetChallengeByID 根据ID获取挑战及奖励物品
+func GetChallengeByID(challengeID uint) (*Challenge, error) {
+	var challenge Challenge
+	if err := DB.Preload("RewardItem").First(&challenge, challengeID).Error; err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// GetChallengesPage 分页获取挑战，status 为空时返回全部，joinedBy 不为 0 时只返回该用户加入的挑战
+// 未开始的挑战按开始时间升序，进行中的按结束时间升序，已结束的按结束时间倒序
+func GetChallengesPage(status string, joinedBy uint, now time.Time, page, pageSize int) ([]Challenge, int64, error) {
+	var challenges []Challenge
+	var total int64
+
+	query := DB.Model(&Challenge{})
+	order := "start_at DESC, id DESC"
+	switch status {
+	case ChallengeStatusUpcoming:
+		query = query.Where("start_at > ?", now)
+		order = "start_at, id"
+	case ChallengeStatusActive:
+		query = query.Where("start_at <= ? AND end_at > ?", now, now)
+		order = "end_at, id"
+	case ChallengeStatusEnded:
+		query = query.Where("end_at <= ?", now)
+		order = "end_at DESC, id DESC"
+	}
+	if joinedBy != 0 {
+		query = query.Where("id IN (?)", DB.Model(&ChallengeParticipant{}).Select("challenge_id").Where("user_id = ?", joinedBy))
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Preload("RewardItem").Order(order).Offset(offset).Limit(pageSize).Find(&challenges).Error
+	return challenges, total, err
+}
+
+// LoadChallengeParticipations 为挑战填充 userID 的参与情况，未加入的挑战保持为空
+func LoadChallengeParticipations(challenges []Challenge, userID uint) error {
+	if len(challenges) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(challenges))
+	for i := range challenges {
+		ids[i] = challenges[i].ID
+	}
+
+	var participants []ChallengeParticipant
+	if err := DB.Where("user_id = ? AND challenge_id IN ?", userID, ids).Find(&participants).Error; err != nil {
+		return err
+	}
+	byChallenge := make(map[uint]*ChallengeParticipant, len(participants))
+	for i := range participants {
+		byChallenge[participants[i].ChallengeID] = &participants[i]
+	}
+	for i := range challenges {
+		challenges[i].Participation = byChallenge[challenges[i].ID]
+	}
+	return nil
+}
+
+// JoinChallenge 加入挑战，已加入时不做任何操作，返回是否新加入
+// 挑战不存在时返回 gorm.ErrRecordNotFound，已结束时返回 ErrChallengeEnded
+func JoinChallenge(challengeID, userID uint, now time.Time) (bool, error) {
+	joined := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		challenge, err := lockChallenge(tx, challengeID)
+		if err != nil {
+			return err
+		}
+		if !now.Before(challenge.EndAt) {
+			return ErrChallengeEnded
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&ChallengeParticipant{ChallengeID: challengeID, UserID: userID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		joined = true
+		return tx.Model(challenge).UpdateColumn("participant_count", gorm.Expr("participant_count + 1")).Error
+	})
+	return joined, err
+}
+
+// LeaveChallenge 退出挑战，已完成或已结束的挑战不能退出
+func LeaveChallenge(challengeID, userID uint, now time.Time) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		challenge, err := lockChallenge(tx, challengeID)
+		if err != nil {
+			return err
+		}
+		if !now.Before(challenge.EndAt) {
+			return ErrChallengeEnded
+		}
+
+		var participant ChallengeParticipant
+		err = tx.Where("challenge_id = ? AND user_id = ?", challengeID, userID).Take(&participant).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotJoinedChallenge
+		}
+		if err != nil {
+			return err
+		}
+		if participant.Completed {
+			return ErrChallengeCompleted
+		}
+
+		if err := tx.Where("challenge_id = ? AND user_id = ?", challengeID, userID).Delete(&ChallengeParticipant{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(challenge).UpdateColumn("participant_count", gorm.Expr("participant_count - 1")).Error
+	})
+}
+
+// GetChallengeParticipant 获取用户在挑战中的参与情况及排名，未加入时返回 gorm.ErrRecordNotFound
+func GetChallengeParticipant(challengeID, userID uint) (*ChallengeParticipant, error) {
+	var participant ChallengeParticipant
+	err := DB.Table("(?) AS ranked", rankedParticipants(challengeID)).
+		Where("user_id = ?", userID).
+		Take(&participant).Error
+	if err != nil {
+		return nil, err
+	}
+	return &participant, nil
+}
+
+// GetChallengeLeaderboard 分页获取挑战排行榜，排名按进度降序、完成时间和加入时间升序
+// 与 viewerID 存在拉黑关系的用户不会出现在结果中，但仍占用排名
+// 进度由饮食或打卡记录计算，viewerID 按参与者对应记录的可见范围无权查看时只返回排名，不返回进度
+func GetChallengeLeaderboard(challenge *Challenge, viewerID uint, page, pageSize int) ([]ChallengeParticipant, int64, error) {
+	var participants []ChallengeParticipant
+	var total int64
+
+	query := DB.Table("(?) AS ranked", rankedParticipants(challenge.ID)).
+		Scopes(notBlockedScope(viewerID, "ranked.user_id"))
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("ranking").Offset(offset).Limit(pageSize).Find(&participants).Error; err != nil {
+		return nil, 0, err
+	}
+
+	userIDs := make([]uint, len(participants))
+	for i := range participants {
+		userIDs[i] = participants[i].UserID
+	}
+	summaries, err := GetUserSummaries(userIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range participants {
+		participants[i].User = summaries[participants[i].UserID]
+
+		settings, err := GetPrivacySettings(participants[i].UserID)
+		if err != nil {
+			return nil, 0, err
+		}
+		visible, err := CanView(viewerID, participants[i].UserID, challenge.Goal.progressVisibility(settings))
+		if err != nil {
+			return nil, 0, err
+		}
+		if !visible {
+			participants[i].hideProgress()
+		}
+	}
+	return participants, total, nil
+}
+
+// rankedParticipants 带排名的挑战参与者子查询
+func rankedParticipants(challengeID uint) *gorm.DB {
+	return DB.Model(&ChallengeParticipant{}).
+		Select("challenge_participants.*, ROW_NUMBER() OVER (ORDER BY progress DESC, completed_at IS NULL, completed_at, created_at, user_id) AS ranking").
+		Where("challenge_id = ?", challengeID)
+}
+
+// GetJoinedRunningChallenges 获取用户加入的、已开始且尚未结算的挑战
+func GetJoinedRunningChallenges(userID uint, now time.Time) ([]Challenge, error) {
+	var challenges []Challenge
+	err := DB.Where("start_at <= ? AND finalized_at IS NULL", now).
+		Where("id IN (?)", DB.Model(&ChallengeParticipant{}).Select("challenge_id").Where("user_id = ?", userID)).
+		Find(&challenges).Error
+	return challenges, err
+}
+
+// GetUnfinalizedChallenges 获取已开始且尚未结算的挑战
+func GetUnfinalizedChallenges(now time.Time) ([]Challenge, error) {
+	var challenges []Challenge
+	err := DB.Where("start_at <= ? AND finalized_at IS NULL", now).Order("id").Find(&challenges).Error
+	return challenges, err
+}
+
+// GetChallengeParticipantIDs 获取挑战所有参与者的用户ID
+func GetChallengeParticipantIDs(challengeID uint) ([]uint, error) {
+	var userIDs []uint
+	err := DB.Model(&ChallengeParticipant{}).Where("challenge_id = ?", challengeID).Order("user_id").Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// UpdateChallengeProgress 保存用户在挑战中的进度，返回本次是否首次完成以及是否有待发放的奖励
+// 完成后即使相关记录被修改或删除也保持完成状态
+// hasReward 为 true 时首次完成会同时标记奖励待发放，由 GrantChallengeReward 发放，发放失败不影响完成状态
+func UpdateChallengeProgress(challengeID, userID uint, progress int, completed, hasReward bool, now time.Time) (bool, bool, error) {
+	newlyCompleted := false
+	rewardPending := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var participant ChallengeParticipant
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("challenge_id = ? AND user_id = ?", challengeID, userID).
+			Take(&participant).Error
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{"progress": progress}
+		rewardPending = participant.RewardPending
+		if completed && !participant.Completed {
+			updates["completed"] = true
+			updates["completed_at"] = now
+			updates["reward_pending"] = hasReward
+			newlyCompleted = true
+			rewardPending = hasReward
+		}
+		return tx.Model(&ChallengeParticipant{}).
+			Where("challenge_id = ? AND user_id = ?", challengeID, userID).
+			Updates(updates).Error
+	})
+	return newlyCompleted, rewardPending, err
+}
+
+// GrantChallengeReward 发放参与者待发放的挑战奖励，返回本次是否发放
+// 没有待发放的奖励时不做任何操作；发放失败时保留待发放状态并记录原因，等待下次刷新或管理员重试
+func GrantChallengeReward(challengeID, userID uint, reward GrantRequest) (bool, error) {
+	granted := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var participant ChallengeParticipant
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("challenge_id = ? AND user_id = ?", challengeID, userID).
+			Take(&participant).Error
+		if err != nil {
+			return err
+		}
+		if !participant.RewardPending {
+			return nil
+		}
+
+		if _, _, err := grantItemTx(tx, reward); err != nil {
+			return err
+		}
+		granted = true
+		return tx.Model(&ChallengeParticipant{}).
+			Where("challenge_id = ? AND user_id = ?", challengeID, userID).
+			Updates(map[string]interface{}{"reward_pending": false, "reward_error": ""}).Error
+	})
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		if saveErr := DB.Model(&ChallengeParticipant{}).
+			Where("challenge_id = ? AND user_id = ? AND reward_pending = ?", challengeID, userID, true).
+			Update("reward_error", err.Error()).Error; saveErr != nil {
+			return false, saveErr
+		}
+	}
+	return granted, err
+}
+
+// GetPendingRewardParticipantIDs 获取挑战中奖励尚未发放成功的参与者ID
+func GetPendingRewardParticipantIDs(challengeID uint) ([]uint, error) {
+	var userIDs []uint
+	err := DB.Model(&ChallengeParticipant{}).
+		Where("challenge_id = ? AND reward_pending = ?", challengeID, true).
+		Order("user_id").
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// MarkChallengeFinalized 标记挑战已完成最终结算
+func MarkChallengeFinalized(challengeID uint, now time.Time) error {
+	return DB.Model(&Challenge{}).Where("id = ?", challengeID).Update("finalized_at", now).Error
+}
+
+// GetUserChallengeParticipations 获取用户参与的所有挑战记录
+func GetUserChallengeParticipations(userID uint) ([]ChallengeParticipant, error) {
+	var participants []ChallengeParticipant
+	err := DB.Where("user_id = ?", userID).Order("created_at").Find(&participants).Error
+	return participants, err
+}
+
+// purgeChallengeParticipations 删除用户的挑战参与记录并修正参与人数
+func purgeChallengeParticipations(tx *gorm.DB, userID uint) error {
+	var challengeIDs []uint
+	if err := tx.Model(&ChallengeParticipant{}).Where("user_id = ?", userID).Pluck("challenge_id", &challengeIDs).Error; err != nil {
+		return err
+	}
+	if len(challengeIDs) == 0 {
+		return nil
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&ChallengeParticipant{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Model(&Challenge{}).Where("id IN ?", challengeIDs).UpdateColumn("participant_count",
+		gorm.Expr("(SELECT COUNT(*) FROM challenge_participants WHERE challenge_participants.challenge_id = challenges.id)"),
+	).Error
+}
+
+// lockChallenge 锁定未删除的挑战
+func lockChallenge(tx *gorm.DB, challengeID uint) (*Challenge, error) {
+	var challenge Challenge
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&challenge, challengeID).Error; err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
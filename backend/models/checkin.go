@@ -1,23 +1,14 @@
 package models
 
 import (
+	"backend/calendar"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// 定义中国时区
-var cst *time.Location
-
-func init() {
-	var err error
-	cst, err = time.LoadLocation("Asia/Shanghai")
-	if err != nil {
-		// 如果加载失败，手动设置UTC+8
-		cst = time.FixedZone("CST", 8*3600)
-	}
-}
-
 // CheckIn 用户打卡记录模型
 type CheckIn struct {
 	gorm.Model
@@ -25,8 +16,49 @@ type CheckIn struct {
 	CheckInAt time.Time `json:"check_in_at"`                   // 打卡时间
 	Content   string    `json:"content" gorm:"size:500"`       // 打卡内容
 	ImageURL  string    `json:"image_url" gorm:"size:255"`     // 打卡图片URL（可选）
+	MakeUp    bool      `json:"make_up" gorm:"default:false"`  // 是否为补签
+
+	// 打卡内容和图片的审核状态，未通过时其他用户看不到内容和图片
+	ModerationStatus string `json:"moderation_status" gorm:"size:20;not null;default:approved"`
+
+	// 打卡时的主观感受评分，取值 1-5，0 表示未填写
+	Mood   int `json:"mood" gorm:"default:0"`   // 心情
+	Energy int `json:"energy" gorm:"default:0"` // 精力
+	Hunger int `json:"hunger" gorm:"default:0"` // 饥饿感
+
+	FoodRecords []FoodRecord `json:"food_records,omitempty" gorm:"-"` // 关联的当天饮食记录，需通过 LoadCheckInFoodRecords 加载
+}
+
+// HideUnapprovedCheckInContent 清空未通过审核的打卡内容和图片，用于向其他用户展示
+func HideUnapprovedCheckInContent(checkIns []CheckIn) {
+	for i := range checkIns {
+		if checkIns[i].ModerationStatus != ModerationStatusApproved {
+			checkIns[i].Content = ""
+			checkIns[i].ImageURL = ""
+		}
+	}
+}
+
+// CheckInFoodRecord 打卡与当天饮食记录的关联
+type CheckInFoodRecord struct {
+	CheckInID    uint      `json:"check_in_id" gorm:"primaryKey"`
+	FoodRecordID uint      `json:"food_record_id" gorm:"primaryKey;index"`
+	UserID       uint      `json:"user_id" gorm:"index;not null"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
+// 打卡评分范围
+const (
+	MinCheckInRating = 1
+	MaxCheckInRating = 5
+)
+
+// ErrInvalidCheckInFoodRecord 关联的饮食记录不存在、不属于该用户或不在打卡当天
+var ErrInvalidCheckInFoodRecord = errors.New("关联的饮食记录无效，只能关联本人当天的饮食记录")
+
+// ErrAlreadyCheckedIn 用户当天已经打过卡
+var ErrAlreadyCheckedIn = errors.New("今日已打卡")
+
 // 获取用户最近的打卡记录
 func GetUserRecentCheckIns(userID uint, limit int) ([]CheckIn, error) {
 	var checkIns []CheckIn
@@ -37,12 +69,10 @@ func GetUserRecentCheckIns(userID uint, limit int) ([]CheckIn, error) {
 	return checkIns, result.Error
 }
 
-// 获取用户今日是否已打卡
-func HasUserCheckedInToday(userID uint) (bool, error) {
+// 获取用户今日（按用户时区）是否已打卡
+func HasUserCheckedInToday(userID uint, loc *time.Location) (bool, error) {
 	var count int64
-	now := time.Now().In(cst)
-	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, cst)
-	endOfDay := startOfDay.Add(24 * time.Hour)
+	startOfDay, endOfDay := calendar.DayRange(time.Now(), loc)
 
 	result := DB.Model(&CheckIn{}).
 		Where("user_id = ? AND check_in_at >= ? AND check_in_at < ?",
@@ -56,6 +86,102 @@ func CreateCheckIn(checkIn *CheckIn) error {
 	return DB.Create(checkIn).Error
 }
 
+// CreateCheckInWithFoodRecords 创建打卡记录并关联饮食记录，饮食记录必须属于该用户且在 [dayStart, dayEnd) 内
+// 锁定用户后重新检查当天是否已打卡，并发请求只有一个能成功，其余返回 ErrAlreadyCheckedIn
+// 成功后 checkIn.FoodRecords 为关联的饮食记录
+func CreateCheckInWithFoodRecords(checkIn *CheckIn, foodRecordIDs []uint, dayStart, dayEnd time.Time) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Take(&User{}, checkIn.UserID).Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&CheckIn{}).
+			Where("user_id = ? AND check_in_at >= ? AND check_in_at < ?", checkIn.UserID, dayStart, dayEnd).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAlreadyCheckedIn
+		}
+
+		var records []FoodRecord
+		if len(foodRecordIDs) > 0 {
+			err := tx.Where("id IN ? AND user_id = ? AND record_time >= ? AND record_time < ?",
+				foodRecordIDs, checkIn.UserID, dayStart, dayEnd).
+				Order("record_time").
+				Find(&records).Error
+			if err != nil {
+				return err
+			}
+			if len(records) != len(foodRecordIDs) {
+				return ErrInvalidCheckInFoodRecord
+			}
+		}
+
+		if err := tx.Create(checkIn).Error; err != nil {
+			return err
+		}
+
+		if len(records) > 0 {
+			links := make([]CheckInFoodRecord, len(records))
+			for i, record := range records {
+				links[i] = CheckInFoodRecord{CheckInID: checkIn.ID, FoodRecordID: record.ID, UserID: checkIn.UserID}
+			}
+			if err := tx.Create(&links).Error; err != nil {
+				return err
+			}
+		}
+		checkIn.FoodRecords = records
+		return nil
+	})
+}
+
+// LoadCheckInFoodRecords 为打卡记录加载关联的饮食记录（已删除的饮食记录不会返回）
+func LoadCheckInFoodRecords(checkIns []CheckIn) error {
+	if len(checkIns) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(checkIns))
+	for i, checkIn := range checkIns {
+		ids[i] = checkIn.ID
+	}
+
+	var links []CheckInFoodRecord
+	if err := DB.Where("check_in_id IN ?", ids).Find(&links).Error; err != nil {
+		return err
+	}
+	if len(links) == 0 {
+		return nil
+	}
+
+	recordIDs := make([]uint, len(links))
+	for i, link := range links {
+		recordIDs[i] = link.FoodRecordID
+	}
+	var records []FoodRecord
+	if err := DB.Where("id IN ?", recordIDs).Order("record_time").Find(&records).Error; err != nil {
+		return err
+	}
+
+	byCheckIn := make(map[uint]map[uint]bool)
+	for _, link := range links {
+		if byCheckIn[link.CheckInID] == nil {
+			byCheckIn[link.CheckInID] = make(map[uint]bool)
+		}
+		byCheckIn[link.CheckInID][link.FoodRecordID] = true
+	}
+	for i := range checkIns {
+		linked := byCheckIn[checkIns[i].ID]
+		for _, record := range records {
+			if linked[record.ID] {
+				checkIns[i].FoodRecords = append(checkIns[i].FoodRecords, record)
+			}
+		}
+	}
+	return nil
+}
+
 // GetUserCheckIns 获取用户所有打卡记录
 func GetUserCheckIns(userID uint) ([]CheckIn, error) {
 	var checkIns []CheckIn
@@ -64,3 +190,45 @@ func GetUserCheckIns(userID uint) ([]CheckIn, error) {
 		Find(&checkIns)
 	return checkIns, result.Error
 }
+
+// CountUserCheckIns 统计用户的打卡记录数
+func CountUserCheckIns(userID uint) (int64, error) {
+	var count int64
+	err := DB.Model(&CheckIn{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// GetUserCheckInsPage 按打卡时间倒序分页获取用户的打卡记录
+// cursor 不为空时返回游标之后的记录（忽略 offset），返回的游标为空表示没有更多数据
+func GetUserCheckInsPage(userID uint, cursor *Cursor, offset, limit int) ([]CheckIn, *Cursor, error) {
+	var checkIns []CheckIn
+	query := DB.Where("user_id = ?", userID)
+	if cursor != nil {
+		query = query.Where("check_in_at < ? OR (check_in_at = ? AND id < ?)", cursor.Time, cursor.Time, cursor.ID)
+	} else if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	// 多取一条用于判断是否还有下一页
+	err := query.Order("check_in_at DESC, id DESC").Limit(limit + 1).Find(&checkIns).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var next *Cursor
+	if len(checkIns) > limit {
+		checkIns = checkIns[:limit]
+		last := checkIns[len(checkIns)-1]
+		next = &Cursor{Time: last.CheckInAt, ID: last.ID}
+	}
+	return checkIns, next, nil
+}
+
+// GetUserCheckInsBetween 获取用户在时间范围 [start, end) 内的打卡记录
+func GetUserCheckInsBetween(userID uint, start, end time.Time) ([]CheckIn, error) {
+	var checkIns []CheckIn
+	err := DB.Where("user_id = ? AND check_in_at >= ? AND check_in_at < ?", userID, start, end).
+		Order("check_in_at").
+		Find(&checkIns).Error
+	return checkIns, err
+}
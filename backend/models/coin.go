@@ -0,0 +1,145 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 金币流水类型
+const (
+	CoinTxCheckIn     = "check_in"    // 打卡奖励
+	CoinTxAchievement = "achievement" // 成就奖励
+	CoinTxPurchase    = "purchase"    // 商店购买
+	CoinTxAdmin       = "admin"       // 管理员调整
+)
+
+var (
+	// ErrInsufficientCoins 金币余额不足
+	ErrInsufficientCoins = errors.New("金币余额不足")
+	// ErrCoinTransactionImmutable 金币流水写入后不允许修改或删除
+	ErrCoinTransactionImmutable = errors.New("金币流水不可修改")
+)
+
+// CoinAccount 用户金币账户
+type CoinAccount struct {
+	UserID    uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Balance   int64     `json:"balance" gorm:"not null;default:0"` // 当前余额
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CoinTransaction 金币流水，同一用户下 (类型, 来源标识) 唯一，保证奖励不会重复发放
+type CoinTransaction struct {
+	ID           uint      `json:"id" gorm:"primarykey"`
+	UserID       uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_coin_tx"`
+	Type         string    `json:"type" gorm:"size:30;not null;uniqueIndex:idx_coin_tx"`        // 流水类型
+	SourceRef    string    `json:"source_ref" gorm:"size:100;not null;uniqueIndex:idx_coin_tx"` // 来源标识，如打卡 check_in:12
+	Amount       int64     `json:"amount"`                                                      // 金币变化，收入为正、支出为负
+	BalanceAfter int64     `json:"balance_after"`                                               // 变化后的余额
+	ActorID      *uint     `json:"actor_id"`                                                    // 操作人，为空表示系统
+	Reason       string    `json:"reason" gorm:"size:255"`                                      // 原因
+	CreatedAt    time.Time `json:"created_at" gorm:"index"`
+}
+
+// BeforeUpdate 禁止修改金币流水
+func (CoinTransaction) BeforeUpdate(tx *gorm.DB) error {
+	return ErrCoinTransactionImmutable
+}
+
+// BeforeDelete 禁止删除金币流水（注销账号清理数据时会跳过钩子）
+func (CoinTransaction) BeforeDelete(tx *gorm.DB) error {
+	return ErrCoinTransactionImmutable
+}
+
+// CoinChange 金币变更请求
+type CoinChange struct {
+	UserID    uint
+	Amount    int64  // 正数为收入，负数为支出
+	Type      string // 流水类型
+	SourceRef string // 来源标识，为空时自动生成（不去重）
+	ActorID   *uint  // 操作人，为空表示系统
+	Reason    string
+}
+
+// ChangeCoins 在调用方的事务中变更金币余额并记录流水
+// 余额不足时返回 ErrInsufficientCoins；来源标识重复的变更不会生效，此时返回 nil, nil
+func ChangeCoins(tx *gorm.DB, change CoinChange) (*CoinTransaction, error) {
+	if change.SourceRef == "" {
+		change.SourceRef = uuid.New().String()
+	}
+
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&CoinAccount{UserID: change.UserID}).Error; err != nil {
+		return nil, err
+	}
+	var account CoinAccount
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, "user_id = ?", change.UserID).Error; err != nil {
+		return nil, err
+	}
+
+	balance := account.Balance + change.Amount
+	if balance < 0 {
+		return nil, ErrInsufficientCoins
+	}
+
+	transaction := &CoinTransaction{
+		UserID:       change.UserID,
+		Type:         change.Type,
+		SourceRef:    change.SourceRef,
+		Amount:       change.Amount,
+		BalanceAfter: balance,
+		ActorID:      change.ActorID,
+		Reason:       change.Reason,
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(transaction)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Model(&account).Update("balance", balance).Error; err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// CreditCoins 在独立事务中变更金币余额，规则同 ChangeCoins
+func CreditCoins(change CoinChange) (*CoinTransaction, error) {
+	var transaction *CoinTransaction
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		transaction, err = ChangeCoins(tx, change)
+		return err
+	})
+	return transaction, err
+}
+
+// GetCoinBalance 获取用户金币余额，没有账户时为 0
+func GetCoinBalance(userID uint) (int64, error) {
+	var account CoinAccount
+	err := DB.Where("user_id = ?", userID).Limit(1).Find(&account).Error
+	return account.Balance, err
+}
+
+// GetCoinTransactions 分页获取用户的金币流水，txType 为空时不过滤
+func GetCoinTransactions(userID uint, txType string, page, pageSize int) ([]CoinTransaction, int64, error) {
+	var transactions []CoinTransaction
+	var total int64
+
+	query := DB.Model(&CoinTransaction{}).Where("user_id = ?", userID)
+	if txType != "" {
+		query = query.Where("type = ?", txType)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&transactions).Error
+	return transactions, total, err
+}
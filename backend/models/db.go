@@ -42,22 +42,7 @@ func InitDB() *gorm.DB {
 	}
 
 	// 自动迁移数据库表
-	db.AutoMigrate(&User{}, &VerificationCode{}, &FoodRecord{}, &UserHealthState{}, &CheckIn{}, &AppUpdate{}, &Item{}, &UserItem{})
-
-	// 创建管理员账户（如果不存在）
-	var adminCount int64
-	db.Model(&User{}).Where("role = ?", "admin").Count(&adminCount)
-	if adminCount == 0 {
-		adminUser := User{
-			Name:     "超级管理员",
-			Email:    "admin@yourdomain.com",
-			Password: "admin888",
-			Role:     "admin",
-			Verified: true, // 管理员账户默认已验证
-		}
-		db.Create(&adminUser)
-		fmt.Println("默认管理员账户已创建：admin@yourdomain.com / admin888")
-	}
+	db.AutoMigrate(&User{}, &VerificationCode{}, &FoodRecord{}, &UserHealthState{}, &CheckIn{}, &AppUpdate{}, &Item{}, &UserItem{}, &UserIdentity{}, &UserProfile{}, &AccountDeletion{}, &AchievementRule{}, &AchievementProgress{}, &Notification{}, &ItemGrant{}, &ItemLedger{}, &BackfillJob{}, &BackfillMatch{}, &StreakFreeze{}, &CheckInFoodRecord{}, &CoinAccount{}, &CoinTransaction{}, &ShopListing{}, &AnalysisCredit{}, &AnalysisUsage{}, &TradeOffer{}, &TradeOfferItem{}, &SeedVersion{}, &Post{}, &PostImage{}, &PostLike{}, &PostComment{}, &Follow{}, &FriendRequest{}, &Friendship{}, &Block{}, &Challenge{}, &ChallengeParticipant{}, &ModerationKeyword{}, &ModerationCase{})
 
 	// 设置全局DB变量
 	DB = db
@@ -115,6 +115,21 @@ func GetAllUserFoodRecords(userID uint) ([]FoodRecord, error) {
 	return records, nil
 }
 
+// GetUserFoodRecordsPage 按记录时间倒序分页获取用户的饮食记录
+func GetUserFoodRecordsPage(userID uint, page, pageSize int) ([]FoodRecord, int64, error) {
+	var records []FoodRecord
+	var total int64
+
+	query := DB.Model(&FoodRecord{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("record_time DESC, id DESC").Offset(offset).Limit(pageSize).Find(&records).Error
+	return records, total, err
+}
+
 // 获取指定ID的食物记录
 func GetFoodRecordByID(recordID uint) (*FoodRecord, error) {
 	var record FoodRecord
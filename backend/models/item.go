@@ -1,18 +1,108 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"regexp"
+
 	"gorm.io/gorm"
 )
 
+// 物品稀有度
+const (
+	RarityCommon    = "common"    // 普通
+	RarityUncommon  = "uncommon"  // 优秀
+	RarityRare      = "rare"      // 稀有
+	RarityEpic      = "epic"      // 史诗
+	RarityLegendary = "legendary" // 传说
+)
+
+// 物品分类
+const (
+	CategoryCollectible = "collectible" // 收藏品，如成就徽章
+	CategoryConsumable  = "consumable"  // 消耗品，使用后触发效果
+	CategoryCosmetic    = "cosmetic"    // 装扮
+)
+
+// 消耗品使用效果
+const (
+	EffectStreakFreeze   = "streak_freeze"   // 冻结连续打卡
+	EffectAnalysisCredit = "analysis_credit" // 增加健康分析次数
+)
+
+// keyPattern 物品标识格式
+var keyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,99}$`)
+
+// Rarities 按从低到高排列的稀有度
+var Rarities = []string{RarityCommon, RarityUncommon, RarityRare, RarityEpic, RarityLegendary}
+
+// Categories 所有物品分类
+var Categories = []string{CategoryCollectible, CategoryConsumable, CategoryCosmetic}
+
 // Item 物品模型
 type Item struct {
 	gorm.Model             // 包含 ID、CreatedAt、UpdatedAt、DeletedAt
-	Name        string     `json:"name" gorm:"size:100;not null"`                 // 物品名称
-	Description string     `json:"description" gorm:"type:text"`                  // 物品介绍
-	Source      string     `json:"source" gorm:"size:100;not null"`               // 获取来源
-	IconURL     string     `json:"icon_url" gorm:"size:255;not null"`             // 图标路径
-	ImageURL    string     `json:"image_url" gorm:"size:255;not null"`            // 图片路径
-	UserItems   []UserItem `json:"user_items,omitempty" gorm:"foreignKey:ItemID"` // 关联用户物品
+	Key         *string    `json:"key" gorm:"column:item_key;size:100;uniqueIndex"`                                                // 稳定的物品标识，创建后不可修改
+	Name        string     `json:"name" gorm:"size:100;not null;index:idx_items_fulltext,class:FULLTEXT,option:WITH PARSER ngram"` // 物品名称
+	Description string     `json:"description" gorm:"type:text;index:idx_items_fulltext,class:FULLTEXT,option:WITH PARSER ngram"`  // 物品介绍
+	Source      string     `json:"source" gorm:"size:100;not null"`                                                                // 获取来源
+	IconURL     string     `json:"icon_url" gorm:"size:255;not null"`                                                              // 图标路径
+	ImageURL    string     `json:"image_url" gorm:"size:255;not null"`                                                             // 图片路径
+	Rarity      string     `json:"rarity" gorm:"size:20;not null;default:common"`                                                  // 稀有度
+	Category    string     `json:"category" gorm:"size:20;not null;default:collectible"`                                           // 分类
+	MaxStack    int        `json:"max_stack" gorm:"not null;default:0"`                                                            // 单个用户最多持有的数量，0 表示不限
+	Effect      string     `json:"effect" gorm:"size:50"`                                                                          // 消耗品使用后触发的效果
+	EffectValue int        `json:"effect_value" gorm:"not null;default:0"`                                                         // 效果参数，如每张分析次数卡增加的次数
+	Tradable    bool       `json:"tradable" gorm:"not null;default:false"`                                                         // 是否允许在用户之间赠送和交易
+	UserItems   []UserItem `json:"user_items,omitempty" gorm:"foreignKey:ItemID"`                                                  // 关联用户物品
+}
+
+// ValidateItem 校验物品的稀有度、分类和堆叠上限，空的稀有度和分类使用默认值
+func ValidateItem(item *Item) error {
+	if item.Rarity == "" {
+		item.Rarity = RarityCommon
+	}
+	if item.Category == "" {
+		item.Category = CategoryCollectible
+	}
+	if !containsString(Rarities, item.Rarity) {
+		return fmt.Errorf("无效的稀有度: %s", item.Rarity)
+	}
+	if !containsString(Categories, item.Category) {
+		return fmt.Errorf("无效的物品分类: %s", item.Category)
+	}
+	if item.Key != nil && *item.Key == "" {
+		item.Key = nil
+	}
+	if item.Key != nil && !keyPattern.MatchString(*item.Key) {
+		return fmt.Errorf("无效的物品标识: %s（只能包含小写字母、数字、- 和 _，最长100个字符）", *item.Key)
+	}
+	if item.MaxStack < 0 {
+		return errors.New("堆叠上限不能为负数")
+	}
+	if item.Category != CategoryConsumable && item.Effect != "" {
+		return errors.New("只有消耗品可以设置使用效果")
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// AfterCreate 未指定标识的物品使用 item-<ID> 作为标识
+func (i *Item) AfterCreate(tx *gorm.DB) error {
+	if i.Key != nil {
+		return nil
+	}
+	key := fmt.Sprintf("item-%d", i.ID)
+	i.Key = &key
+	return tx.Model(i).UpdateColumn("item_key", key).Error
 }
 
 // CreateItem 创建新物品
@@ -30,30 +120,9 @@ func GetItemByID(itemID uint) (*Item, error) {
 	return &item, nil
 }
 
-// GetAllItems 获取所有物品（支持分页）
-func GetAllItems(page, pageSize int) ([]Item, int64, error) {
-	var items []Item
-	var total int64
-
-	// 获取总数
-	err := DB.Model(&Item{}).Count(&total).Error
-	if err != nil {
-		return nil, 0, err
-	}
-
-	// 获取分页数据
-	offset := (page - 1) * pageSize
-	err = DB.Offset(offset).Limit(pageSize).Find(&items).Error
-	if err != nil {
-		return nil, 0, err
-	}
-
-	return items, total, nil
-}
-
-// UpdateItem 更新物品信息
+// UpdateItem 更新物品信息，物品标识创建后不会被修改
 func UpdateItem(item *Item) error {
-	return DB.Save(item).Error
+	return DB.Omit("item_key").Save(item).Error
 }
 
 // DeleteItem 删除物品
@@ -61,20 +130,6 @@ func DeleteItem(itemID uint) error {
 	return DB.Delete(&Item{}, itemID).Error
 }
 
-// GetItemsByName 根据名称搜索物品
-func GetItemsByName(name string) ([]Item, error) {
-	var items []Item
-	err := DB.Where("name LIKE ?", "%"+name+"%").Find(&items).Error
-	return items, err
-}
-
-// GetItemsBySource 根据来源搜索物品
-func GetItemsBySource(source string) ([]Item, error) {
-	var items []Item
-	err := DB.Where("source = ?", source).Find(&items).Error
-	return items, err
-}
-
 // GetItemSources 获取所有物品来源（用于筛选）
 func GetItemSources() ([]string, error) {
 	var sources []string
@@ -93,3 +148,149 @@ func ExistsItemByName(name string) (bool, error) {
 	err := DB.Model(&Item{}).Where("name = ?", name).Count(&count).Error
 	return count > 0, err
 }
+
+// GetItemByName 根据名称精确查找物品
+func GetItemByName(name string) (*Item, error) {
+	var item Item
+	err := DB.Where("name = ?", name).First(&item).Error
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// EnsureItem 确保系统物品存在，规则见 EnsureItemTx
+func EnsureItem(item Item) error {
+	return EnsureItemTx(DB, item)
+}
+
+// EnsureItemTx 在调用方的事务中确保系统物品存在，物品按标识匹配：
+// 不存在时认领同名且还没有正式标识的旧物品，否则创建；管理员删除过的物品不会被恢复。
+// 已存在的物品只补全缺失的图片，以及仍是默认分类（升级前创建）时的分类和效果，不覆盖管理员的修改
+func EnsureItemTx(tx *gorm.DB, item Item) error {
+	if item.Key == nil || *item.Key == "" {
+		return fmt.Errorf("系统物品 %s 缺少标识", item.Name)
+	}
+
+	var existing Item
+	if err := tx.Unscoped().Where("item_key = ?", *item.Key).Limit(1).Find(&existing).Error; err != nil {
+		return err
+	}
+	if existing.ID == 0 {
+		// 标识功能上线前按名称创建的物品，只有自动生成的 item-<ID> 标识
+		err := tx.Where("name = ?", item.Name).
+			Where("item_key IS NULL OR item_key = CONCAT('item-', id)").
+			Limit(1).Find(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing.ID == 0 {
+			return tx.Create(&item).Error
+		}
+		if err := tx.Model(&existing).UpdateColumn("item_key", *item.Key).Error; err != nil {
+			return err
+		}
+	}
+	if existing.DeletedAt.Valid {
+		return nil
+	}
+
+	updates := map[string]interface{}{}
+	if existing.IconURL == "" && item.IconURL != "" {
+		updates["icon_url"] = item.IconURL
+	}
+	if existing.ImageURL == "" && item.ImageURL != "" {
+		updates["image_url"] = item.ImageURL
+	}
+	if existing.Category == CategoryCollectible && item.Category != CategoryCollectible {
+		updates["rarity"] = item.Rarity
+		updates["category"] = item.Category
+		updates["max_stack"] = item.MaxStack
+		updates["effect"] = item.Effect
+		updates["effect_value"] = item.EffectValue
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return tx.Model(&existing).Updates(updates).Error
+}
+
+// ItemKey 返回物品标识的指针，便于在物品定义中使用常量标识
+func ItemKey(key string) *string {
+	return &key
+}
+
+// ExistsItemByKey 检查指定标识是否已被使用（包括已删除的物品）
+func ExistsItemByKey(key string) (bool, error) {
+	var count int64
+	err := DB.Unscoped().Model(&Item{}).Where("item_key = ?", key).Count(&count).Error
+	return count > 0, err
+}
+
+// GetItemByKey 根据标识获取物品
+func GetItemByKey(key string) (*Item, error) {
+	var item Item
+	err := DB.Where("item_key = ?", key).First(&item).Error
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// GetAllItemsForExport 获取所有未删除的物品，按ID排序
+func GetAllItemsForExport() ([]Item, error) {
+	var items []Item
+	err := DB.Order("id").Find(&items).Error
+	return items, err
+}
+
+// EnsureItemKeys 为没有标识的物品生成 item-<ID> 形式的标识
+func EnsureItemKeys() error {
+	return DB.Unscoped().Model(&Item{}).
+		Where("item_key IS NULL OR item_key = ''").
+		Update("item_key", gorm.Expr("CONCAT('item-', id)")).Error
+}
+
+// SaveItemsByKey 在一个事务中按标识创建或更新物品，已删除的同标识物品会被恢复
+func SaveItemsByKey(items []Item) (created, updated int, err error) {
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		for i := range items {
+			item := &items[i]
+			var existing Item
+			err := tx.Unscoped().Where("item_key = ?", *item.Key).Limit(1).Find(&existing).Error
+			if err != nil {
+				return err
+			}
+
+			var conflicts int64
+			query := tx.Model(&Item{}).Where("name = ?", item.Name)
+			if existing.ID != 0 {
+				query = query.Where("id <> ?", existing.ID)
+			}
+			if err := query.Count(&conflicts).Error; err != nil {
+				return err
+			}
+			if conflicts > 0 {
+				return fmt.Errorf("物品名称 %s 已被其他物品使用", item.Name)
+			}
+
+			if existing.ID == 0 {
+				if err := tx.Create(item).Error; err != nil {
+					return err
+				}
+				created++
+				continue
+			}
+
+			item.ID = existing.ID
+			item.CreatedAt = existing.CreatedAt
+			item.DeletedAt = gorm.DeletedAt{}
+			if err := tx.Unscoped().Save(item).Error; err != nil {
+				return err
+			}
+			updated++
+		}
+		return nil
+	})
+	return created, updated, err
+}
@@ -0,0 +1,322 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 物品流水操作类型
+const (
+	LedgerActionGrant          = "grant"           // 发放物品
+	LedgerActionQuantityChange = "quantity_change" // 修改数量
+	LedgerActionDelete         = "delete"          // 删除物品
+	LedgerActionConsume        = "consume"         // 使用消耗品
+	LedgerActionTransferOut    = "transfer_out"    // 赠送或交易给其他用户
+)
+
+var (
+	// ErrLedgerImmutable 物品流水写入后不允许修改或删除
+	ErrLedgerImmutable = errors.New("物品流水不可修改")
+	// ErrInsufficientItems 用户持有的物品数量不足
+	ErrInsufficientItems = errors.New("物品数量不足")
+	// ErrStackLimit 超过物品的堆叠上限
+	ErrStackLimit = errors.New("超过该物品的持有上限")
+)
+
+// ItemLedger 物品流水，记录用户物品的每一次发放、数量变化和删除
+type ItemLedger struct {
+	ID            uint      `json:"id" gorm:"primarykey"`
+	UserID        uint      `json:"user_id" gorm:"index;not null"`
+	ItemID        uint      `json:"item_id" gorm:"index;not null"`
+	Action        string    `json:"action" gorm:"size:30;not null"` // 操作类型
+	Delta         int       `json:"delta"`                          // 数量变化
+	QuantityAfter int       `json:"quantity_after"`                 // 操作后的数量
+	Source        string    `json:"source" gorm:"size:100"`         // 发放来源，如 achievement、admin
+	SourceRef     string    `json:"source_ref" gorm:"size:100"`     // 来源标识，如成就规则 rule:12
+	ActorID       *uint     `json:"actor_id"`                       // 操作人，为空表示系统
+	Reason        string    `json:"reason" gorm:"size:255"`         // 原因
+	CreatedAt     time.Time `json:"created_at" gorm:"index"`
+}
+
+// BeforeUpdate 禁止修改物品流水
+func (ItemLedger) BeforeUpdate(tx *gorm.DB) error {
+	return ErrLedgerImmutable
+}
+
+// BeforeDelete 禁止删除物品流水（注销账号清理数据时会跳过钩子）
+func (ItemLedger) BeforeDelete(tx *gorm.DB) error {
+	return ErrLedgerImmutable
+}
+
+// ItemGrant 带来源标识的物品发放记录，保证同一来源对同一用户的同一物品只发放一次
+type ItemGrant struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_item_grant"`
+	ItemID    uint      `json:"item_id" gorm:"not null;uniqueIndex:idx_item_grant"`
+	Source    string    `json:"source" gorm:"size:100;not null;uniqueIndex:idx_item_grant"`
+	SourceRef string    `json:"source_ref" gorm:"size:100;not null;uniqueIndex:idx_item_grant"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GrantRequest 物品发放请求
+type GrantRequest struct {
+	UserID    uint
+	ItemID    uint
+	Quantity  int
+	Source    string // 发放来源
+	SourceRef string // 来源标识，不为空时同一 (用户, 物品, 来源, 标识) 只会发放一次
+	ActorID   *uint  // 操作人，为空表示系统
+	Reason    string
+}
+
+// GrantItem 在事务中发放物品并记录流水
+// 用户已有该物品时累加数量；带来源标识的重复发放不会生效，此时返回的 granted 为 false
+func GrantItem(req GrantRequest) (userItem *UserItem, granted bool, err error) {
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		userItem, granted, err = grantItemTx(tx, req)
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return userItem, granted, nil
+}
+
+// grantItemTx 在调用方的事务中发放物品并记录流水，重复的来源标识返回 granted 为 false
+func grantItemTx(tx *gorm.DB, req GrantRequest) (*UserItem, bool, error) {
+	if req.Quantity <= 0 {
+		req.Quantity = 1
+	}
+
+	if req.SourceRef != "" {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&ItemGrant{
+			UserID:    req.UserID,
+			ItemID:    req.ItemID,
+			Source:    req.Source,
+			SourceRef: req.SourceRef,
+		})
+		if result.Error != nil {
+			return nil, false, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil, false, nil
+		}
+	}
+
+	rows, err := lockUserItems(tx, req.UserID, req.ItemID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := checkStackLimit(tx, req.ItemID, totalQuantity(rows)+req.Quantity); err != nil {
+		return nil, false, err
+	}
+
+	var userItem *UserItem
+	if len(rows) == 0 {
+		userItem = &UserItem{
+			UserID:       req.UserID,
+			ItemID:       req.ItemID,
+			Quantity:     req.Quantity,
+			ObtainedAt:   time.Now(),
+			ObtainedFrom: req.Source,
+		}
+		if err := tx.Create(userItem).Error; err != nil {
+			return nil, false, err
+		}
+	} else {
+		userItem, err = mergeUserItems(tx, rows, totalQuantity(rows)+req.Quantity)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	err = tx.Create(&ItemLedger{
+		UserID:        req.UserID,
+		ItemID:        req.ItemID,
+		Action:        LedgerActionGrant,
+		Delta:         req.Quantity,
+		QuantityAfter: userItem.Quantity,
+		Source:        req.Source,
+		SourceRef:     req.SourceRef,
+		ActorID:       req.ActorID,
+		Reason:        req.Reason,
+	}).Error
+	if err != nil {
+		return nil, false, err
+	}
+	return userItem, true, nil
+}
+
+// ChangeUserItemQuantity 在事务中修改用户物品数量并记录流水
+func ChangeUserItemQuantity(userID, itemID uint, quantity int, actorID *uint, reason string) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		rows, err := lockUserItems(tx, userID, itemID)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := checkStackLimit(tx, itemID, quantity); err != nil {
+			return err
+		}
+
+		before := totalQuantity(rows)
+		if _, err := mergeUserItems(tx, rows, quantity); err != nil {
+			return err
+		}
+
+		return tx.Create(&ItemLedger{
+			UserID:        userID,
+			ItemID:        itemID,
+			Action:        LedgerActionQuantityChange,
+			Delta:         quantity - before,
+			QuantityAfter: quantity,
+			ActorID:       actorID,
+			Reason:        reason,
+		}).Error
+	})
+}
+
+// RemoveUserItem 在事务中删除用户物品并记录流水
+func RemoveUserItem(userID, itemID uint, actorID *uint, reason string) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		rows, err := lockUserItems(tx, userID, itemID)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := tx.Where("user_id = ? AND item_id = ?", userID, itemID).Delete(&UserItem{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&ItemLedger{
+			UserID:        userID,
+			ItemID:        itemID,
+			Action:        LedgerActionDelete,
+			Delta:         -totalQuantity(rows),
+			QuantityAfter: 0,
+			ActorID:       actorID,
+			Reason:        reason,
+		}).Error
+	})
+}
+
+// ConsumeUserItem 在调用方的事务中扣减用户物品数量并记录流水，数量不足时返回 ErrInsufficientItems
+func ConsumeUserItem(tx *gorm.DB, userID, itemID uint, quantity int, source, reason string) error {
+	rows, err := lockUserItems(tx, userID, itemID)
+	if err != nil {
+		return err
+	}
+
+	before := totalQuantity(rows)
+	if len(rows) == 0 || before < quantity {
+		return ErrInsufficientItems
+	}
+	if _, err := mergeUserItems(tx, rows, before-quantity); err != nil {
+		return err
+	}
+
+	return tx.Create(&ItemLedger{
+		UserID:        userID,
+		ItemID:        itemID,
+		Action:        LedgerActionConsume,
+		Delta:         -quantity,
+		QuantityAfter: before - quantity,
+		Source:        source,
+		ActorID:       &userID,
+		Reason:        reason,
+	}).Error
+}
+
+// GetItemLedger 分页查询物品流水，userID 或 itemID 为 0 时不过滤
+func GetItemLedger(userID, itemID uint, page, pageSize int) ([]ItemLedger, int64, error) {
+	var entries []ItemLedger
+	var total int64
+
+	query := DB.Model(&ItemLedger{})
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	if itemID != 0 {
+		query = query.Where("item_id = ?", itemID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&entries).Error
+	return entries, total, err
+}
+
+// checkStackLimit 检查持有数量是否超过物品的堆叠上限
+func checkStackLimit(tx *gorm.DB, itemID uint, quantity int) error {
+	var item Item
+	if err := tx.Unscoped().Select("id", "max_stack").First(&item, itemID).Error; err != nil {
+		return err
+	}
+	if item.MaxStack > 0 && quantity > item.MaxStack {
+		return ErrStackLimit
+	}
+	return nil
+}
+
+// lockUserItems 锁定用户持有的某个物品的所有记录
+func lockUserItems(tx *gorm.DB, userID, itemID uint) ([]UserItem, error) {
+	var rows []UserItem
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND item_id = ?", userID, itemID).
+		Order("id").
+		Find(&rows).Error
+	return rows, err
+}
+
+// mergeUserItems 将同一物品的多条记录合并到最早的一条并设置数量
+// 数量为 0 时删除全部记录，避免用完或交易出去的物品仍被当作已拥有（展示、成就、物品列表）
+func mergeUserItems(tx *gorm.DB, rows []UserItem, quantity int) (*UserItem, error) {
+	userItem := rows[0]
+	if quantity <= 0 {
+		ids := make([]uint, 0, len(rows))
+		for _, row := range rows {
+			ids = append(ids, row.ID)
+		}
+		if err := tx.Delete(&UserItem{}, ids).Error; err != nil {
+			return nil, err
+		}
+		userItem.Quantity = 0
+		return &userItem, nil
+	}
+	if err := tx.Model(&userItem).Update("quantity", quantity).Error; err != nil {
+		return nil, err
+	}
+	userItem.Quantity = quantity
+
+	if len(rows) > 1 {
+		ids := make([]uint, 0, len(rows)-1)
+		for _, row := range rows[1:] {
+			ids = append(ids, row.ID)
+		}
+		if err := tx.Delete(&UserItem{}, ids).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &userItem, nil
+}
+
+func totalQuantity(rows []UserItem) int {
+	total := 0
+	for _, row := range rows {
+		total += row.Quantity
+	}
+	return total
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm/clause"
+)
+
+// 物品列表排序方式
+const (
+	ItemSortRelevance = "relevance" // 按匹配度，只在有关键词时有效
+	ItemSortNewest    = "newest"
+	ItemSortOldest    = "oldest"
+	ItemSortName      = "name"
+	ItemSortRarity    = "rarity" // 稀有度从高到低
+)
+
+// ItemSorts 支持的排序方式
+var ItemSorts = []string{ItemSortRelevance, ItemSortNewest, ItemSortOldest, ItemSortName, ItemSortRarity}
+
+// ngramTokenSize 与 MySQL ngram_token_size 一致，更短的关键词无法命中全文索引，改用 LIKE 匹配
+const ngramTokenSize = 2
+
+// ErrInvalidItemFilter 筛选条件中的稀有度、分类或排序方式无效
+var ErrInvalidItemFilter = errors.New("无效的筛选条件")
+
+// ItemFilter 物品列表的搜索和筛选条件，空字段表示不过滤
+type ItemFilter struct {
+	Keyword  string // 在名称和介绍中搜索
+	Source   string
+	Rarity   string
+	Category string
+	Sort     string // 为空时有关键词按匹配度、否则按ID排序
+}
+
+// SearchItems 按条件分页查询物品，关键词使用 ngram 全文索引匹配名称和介绍
+func SearchItems(filter ItemFilter, page, pageSize int) ([]Item, int64, error) {
+	if filter.Rarity != "" && !containsString(Rarities, filter.Rarity) {
+		return nil, 0, fmt.Errorf("%w：稀有度 %s", ErrInvalidItemFilter, filter.Rarity)
+	}
+	if filter.Category != "" && !containsString(Categories, filter.Category) {
+		return nil, 0, fmt.Errorf("%w：分类 %s", ErrInvalidItemFilter, filter.Category)
+	}
+	if filter.Sort != "" && !containsString(ItemSorts, filter.Sort) {
+		return nil, 0, fmt.Errorf("%w：排序方式 %s", ErrInvalidItemFilter, filter.Sort)
+	}
+
+	query := DB.Model(&Item{})
+	keyword := strings.TrimSpace(filter.Keyword)
+	fulltext := utf8.RuneCountInString(keyword) >= ngramTokenSize
+	if keyword != "" {
+		if fulltext {
+			query = query.Where("MATCH(name, description) AGAINST (? IN NATURAL LANGUAGE MODE)", keyword)
+		} else {
+			like := "%" + escapeLike(keyword) + "%"
+			query = query.Where("name LIKE ? OR description LIKE ?", like, like)
+		}
+	}
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if filter.Rarity != "" {
+		query = query.Where("rarity = ?", filter.Rarity)
+	}
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	switch filter.Sort {
+	case ItemSortNewest:
+		query = query.Order("created_at DESC").Order("id DESC")
+	case ItemSortOldest:
+		query = query.Order("created_at").Order("id")
+	case ItemSortName:
+		query = query.Order("name").Order("id")
+	case ItemSortRarity:
+		query = query.Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "FIELD(rarity, ?) DESC",
+			Vars:               []interface{}{Rarities},
+			WithoutParentheses: true,
+		}}).Order("id")
+	default:
+		if fulltext {
+			query = query.Clauses(clause.OrderBy{Expression: clause.Expr{
+				SQL:  "MATCH(name, description) AGAINST (? IN NATURAL LANGUAGE MODE) DESC",
+				Vars: []interface{}{keyword},
+			}})
+		}
+		query = query.Order("id")
+	}
+
+	var items []Item
+	offset := (page - 1) * pageSize
+	err := query.Offset(offset).Limit(pageSize).Find(&items).Error
+	return items, total, err
+}
+
+// escapeLike 转义 LIKE 中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
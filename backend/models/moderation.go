@@ -0,0 +1,292 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 内容的审核状态
+const (
+	ModerationStatusApproved = "approved" // 已通过，对其他用户可见
+	ModerationStatusPending  = "pending"  // 等待人工审核，只有作者和管理员可见
+	ModerationStatusRemoved  = "removed"  // 已下架，只有作者和管理员可见
+)
+
+// 可审核的内容类型
+const (
+	ModerationContentPost        = "post"
+	ModerationContentPostComment = "post_comment"
+	ModerationContentCheckIn     = "check_in"
+)
+
+// 审核记录的来源
+const (
+	ModerationSourceAuto  = "auto"  // 自动审核标记为需要复核
+	ModerationSourceAdmin = "admin" // 管理员直接下架
+)
+
+var (
+	ErrModerationCaseResolved  = errors.New("该审核记录已处理")
+	ErrInvalidModerationTarget = errors.New("不支持的内容类型")
+)
+
+// ModerationKeyword 审核关键词，Action 为命中后的处理方式（review 或 reject）
+type ModerationKeyword struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Word      string    `json:"word" gorm:"size:100;uniqueIndex;not null"`
+	Action    string    `json:"action" gorm:"size:20;not null"`
+	CreatedBy uint      `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ModerationCase 审核队列中的一条记录，对应一条需要人工复核或被管理员下架的内容
+type ModerationCase struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	ContentType string     `json:"content_type" gorm:"size:20;not null;index:idx_moderation_content"`
+	ContentID   uint       `json:"content_id" gorm:"not null;index:idx_moderation_content"`
+	UserID      uint       `json:"user_id" gorm:"index;not null"`               // 内容作者
+	Excerpt     string     `json:"excerpt" gorm:"type:text"`                    // 提交审核时的文字内容
+	ImageURLs   []string   `json:"image_urls" gorm:"type:text;serializer:json"` // 提交审核时的图片
+	Reasons     []string   `json:"reasons" gorm:"type:text;serializer:json"`    // 自动审核或管理员给出的原因
+	Source      string     `json:"source" gorm:"size:20;not null"`              // auto 或 admin
+	Status      string     `json:"status" gorm:"size:20;not null;index"`        // pending、approved、removed
+	ReviewerID  *uint      `json:"reviewer_id"`                                 // 处理人
+	ReviewNote  string     `json:"review_note" gorm:"size:500"`                 // 处理备注，下架时会通知作者
+	ReviewedAt  *time.Time `json:"reviewed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// IsValidModerationContentType 检查内容类型是否可审核
+func IsValidModerationContentType(contentType string) bool {
+	switch contentType {
+	case ModerationContentPost, ModerationContentPostComment, ModerationContentCheckIn:
+		return true
+	}
+	return false
+}
+
+// GetModerationKeywords 获取全部审核关键词
+func GetModerationKeywords() ([]ModerationKeyword, error) {
+	var keywords []ModerationKeyword
+	err := DB.Order("id").Find(&keywords).Error
+	return keywords, err
+}
+
+// SaveModerationKeyword 添加审核关键词，已存在时更新处理方式，返回保存后的关键词
+func SaveModerationKeyword(word, action string, createdBy uint) (*ModerationKeyword, error) {
+	keyword := ModerationKeyword{Word: word, Action: action, CreatedBy: createdBy}
+	err := DB.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"action"}),
+	}).Create(&keyword).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := DB.Where("word = ?", word).First(&keyword).Error; err != nil {
+		return nil, err
+	}
+	return &keyword, nil
+}
+
+// DeleteModerationKeyword 删除审核关键词
+func DeleteModerationKeyword(id uint) error {
+	result := DB.Delete(&ModerationKeyword{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CreateModerationCase 创建审核记录
+func CreateModerationCase(mc *ModerationCase) error {
+	return DB.Create(mc).Error
+}
+
+// GetModerationCaseByID 获取审核记录
+func GetModerationCaseByID(id uint) (*ModerationCase, error) {
+	var mc ModerationCase
+	if err := DB.First(&mc, id).Error; err != nil {
+		return nil, err
+	}
+	return &mc, nil
+}
+
+// GetModerationCasesPage 分页获取审核记录，status 和 contentType 为空表示不过滤
+// 待处理的记录按提交时间先后排列，其余按最近处理排列
+func GetModerationCasesPage(status, contentType string, page, pageSize int) ([]ModerationCase, int64, error) {
+	var cases []ModerationCase
+	var total int64
+
+	query := DB.Model(&ModerationCase{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if contentType != "" {
+		query = query.Where("content_type = ?", contentType)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order := "updated_at DESC, id DESC"
+	if status == ModerationStatusPending {
+		order = "id"
+	}
+	offset := (page - 1) * pageSize
+	err := query.Order(order).Offset(offset).Limit(pageSize).Find(&cases).Error
+	return cases, total, err
+}
+
+// ResolveModerationCase 处理待审核的记录，status 为 approved 或 removed，同时更新内容的审核状态
+// 内容已被作者删除时只更新审核记录
+func ResolveModerationCase(id uint, status string, reviewerID uint, note string, now time.Time) (*ModerationCase, error) {
+	var mc ModerationCase
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&mc, id).Error; err != nil {
+			return err
+		}
+		if mc.Status != ModerationStatusPending {
+			return ErrModerationCaseResolved
+		}
+		if err := setContentModerationStatus(tx, mc.ContentType, mc.ContentID, status); err != nil {
+			return err
+		}
+
+		mc.Status = status
+		mc.ReviewerID = &reviewerID
+		mc.ReviewNote = note
+		mc.ReviewedAt = &now
+		return tx.Save(&mc).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &mc, nil
+}
+
+// TakeDownContent 下架内容，内容已有待处理的审核记录时一并标记为已下架，否则新建一条管理员下架记录
+func TakeDownContent(contentType string, contentID, reviewerID uint, note string, now time.Time) (*ModerationCase, error) {
+	var mc ModerationCase
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		userID, excerpt, images, err := loadModerationContent(tx, contentType, contentID)
+		if err != nil {
+			return err
+		}
+		if err := setContentModerationStatus(tx, contentType, contentID, ModerationStatusRemoved); err != nil {
+			return err
+		}
+
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("content_type = ? AND content_id = ? AND status = ?", contentType, contentID, ModerationStatusPending).
+			Order("id DESC").First(&mc).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			mc = ModerationCase{
+				ContentType: contentType,
+				ContentID:   contentID,
+				UserID:      userID,
+				Excerpt:     excerpt,
+				ImageURLs:   images,
+				Source:      ModerationSourceAdmin,
+			}
+		} else if err != nil {
+			return err
+		}
+
+		mc.Status = ModerationStatusRemoved
+		mc.ReviewerID = &reviewerID
+		mc.ReviewNote = note
+		mc.ReviewedAt = &now
+		if err := tx.Save(&mc).Error; err != nil {
+			return err
+		}
+		// 同一内容的其他待处理记录不再需要审核
+		return tx.Model(&ModerationCase{}).
+			Where("content_type = ? AND content_id = ? AND status = ?", contentType, contentID, ModerationStatusPending).
+			Updates(map[string]interface{}{
+				"status":      ModerationStatusRemoved,
+				"reviewer_id": reviewerID,
+				"reviewed_at": now,
+			}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &mc, nil
+}
+
+// loadModerationContent 获取内容的作者、文字和图片，用于创建下架记录
+func loadModerationContent(tx *gorm.DB, contentType string, contentID uint) (uint, string, []string, error) {
+	switch contentType {
+	case ModerationContentPost:
+		var post Post
+		if err := tx.Preload("Images").First(&post, contentID).Error; err != nil {
+			return 0, "", nil, err
+		}
+		images := make([]string, len(post.Images))
+		for i, image := range post.Images {
+			images[i] = image.URL
+		}
+		excerpt := post.Content
+		if post.Title != "" {
+			excerpt = post.Title + "\n" + post.Content
+		}
+		return post.UserID, excerpt, images, nil
+	case ModerationContentPostComment:
+		var comment PostComment
+		if err := tx.First(&comment, contentID).Error; err != nil {
+			return 0, "", nil, err
+		}
+		return comment.UserID, comment.Content, nil, nil
+	case ModerationContentCheckIn:
+		var checkIn CheckIn
+		if err := tx.First(&checkIn, contentID).Error; err != nil {
+			return 0, "", nil, err
+		}
+		var images []string
+		if checkIn.ImageURL != "" {
+			images = []string{checkIn.ImageURL}
+		}
+		return checkIn.UserID, checkIn.Content, images, nil
+	}
+	return 0, "", nil, ErrInvalidModerationTarget
+}
+
+// setContentModerationStatus 更新内容的审核状态，评论状态变化后同时修正帖子的评论数
+func setContentModerationStatus(tx *gorm.DB, contentType string, contentID uint, status string) error {
+	switch contentType {
+	case ModerationContentPost:
+		return tx.Model(&Post{}).Where("id = ?", contentID).UpdateColumn("moderation_status", status).Error
+	case ModerationContentPostComment:
+		var comment PostComment
+		err := tx.First(&comment, contentID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := lockPost(tx, comment.PostID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := tx.Model(&comment).UpdateColumn("moderation_status", status).Error; err != nil {
+			return err
+		}
+		return recountPosts(tx, []uint{comment.PostID})
+	case ModerationContentCheckIn:
+		return tx.Model(&CheckIn{}).Where("id = ?", contentID).UpdateColumn("moderation_status", status).Error
+	}
+	return ErrInvalidModerationTarget
+}
+
+// moderationVisibleScope 只保留审核通过或由 viewerID 发布的内容
+func moderationVisibleScope(viewerID uint, table string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(table+".moderation_status = ? OR "+table+".user_id = ?", ModerationStatusApproved, viewerID)
+	}
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 通知类型
+const (
+	NotificationTypeAchievement        = "achievement_unlocked" // 解锁成就
+	NotificationTypeTradeOffer         = "trade_offer"          // 收到赠送或交易请求
+	NotificationTypeTradeResult        = "trade_result"         // 发出的交易被接受或拒绝
+	NotificationTypePostComment        = "post_comment"         // 帖子收到评论
+	NotificationTypeNewFollower        = "new_follower"         // 被新用户关注
+	NotificationTypeFriendRequest      = "friend_request"       // 收到好友请求
+	NotificationTypeFriendAccepted     = "friend_accepted"      // 发出的好友请求被接受
+	NotificationTypeChallengeCompleted = "challenge_completed"  // 完成挑战
+	NotificationTypeContentRemoved     = "content_removed"      // 发布的内容被下架
+)
+
+// Notification 用户站内通知
+type Notification struct {
+	gorm.Model
+	UserID  uint                   `json:"user_id" gorm:"index;not null"`
+	Type    string                 `json:"type" gorm:"size:50;not null"`          // 通知类型
+	Title   string                 `json:"title" gorm:"size:200;not null"`        // 标题
+	Content string                 `json:"content" gorm:"type:text"`              // 内容
+	Data    map[string]interface{} `json:"data" gorm:"type:text;serializer:json"` // 附加数据，如成就对应的物品ID
+	ReadAt  *time.Time             `json:"read_at"`                               // 阅读时间，为空表示未读
+}
+
+// CreateNotifications 批量创建通知
+func CreateNotifications(notifications []Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	return DB.Create(&notifications).Error
+}
+
+// GetUserNotifications 分页获取用户的通知，按时间倒序
+func GetUserNotifications(userID uint, unreadOnly bool, page, pageSize int) ([]Notification, int64, error) {
+	var notifications []Notification
+	var total int64
+
+	query := DB.Model(&Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&notifications).Error
+	return notifications, total, err
+}
+
+// CountUnreadNotifications 统计用户的未读通知数
+func CountUnreadNotifications(userID uint) (int64, error) {
+	var count int64
+	err := DB.Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// MarkNotificationsRead 将用户的通知标记为已读，ids 为空时标记全部
+func MarkNotificationsRead(userID uint, ids []uint) (int64, error) {
+	query := DB.Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	result := query.Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
@@ -1,18 +1,33 @@
 package models
 
 import (
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// Post 帖子主体结构
+// MaxPostImages 每个帖子最多的图片数量
+const MaxPostImages = 9
+
+// ErrInvalidPostFoodRecord 帖子关联的饮食记录不存在或不属于作者
+var ErrInvalidPostFoodRecord = errors.New("关联的饮食记录不存在")
+
+// Post 社区帖子
 type Post struct {
 	gorm.Model
-	Title   string      `json:"title" gorm:"size:100;not null"`    // 帖子标题
-	Content string      `json:"content" gorm:"type:text;not null"` // 帖子内容
-	Author  string      `json:"author" gorm:"size:50;not null"`    // 作者
-	Images  []PostImage `json:"images" gorm:"foreignKey:PostID"`   // 帖子图片
+	UserID           uint         `json:"user_id" gorm:"index;not null"`                                    // 作者ID
+	Title            string       `json:"title" gorm:"size:100"`                                            // 帖子标题（可选）
+	Content          string       `json:"content" gorm:"type:text;not null"`                                // 帖子内容
+	FoodRecordID     *uint        `json:"food_record_id" gorm:"index"`                                      // 关联的饮食记录（可选）
+	LikeCount        int          `json:"like_count" gorm:"not null;default:0"`                             // 点赞数
+	CommentCount     int          `json:"comment_count" gorm:"not null;default:0"`                          // 评论数（只统计审核通过的评论）
+	ModerationStatus string       `json:"moderation_status" gorm:"size:20;not null;default:approved;index"` // 审核状态，未通过时只有作者和管理员可见
+	Images           []PostImage  `json:"images" gorm:"foreignKey:PostID"`                                  // 帖子图片
+	FoodRecord       *FoodRecord  `json:"food_record,omitempty" gorm:"-"`                                   // 关联的饮食记录，由 LoadPostDetails 填充
+	Author           *UserSummary `json:"author,omitempty" gorm:"-"`                                        // 作者信息，由 LoadPostDetails 填充
+	Liked            bool         `json:"liked" gorm:"-"`                                                   // 当前用户是否已点赞，由 LoadPostDetails 填充
 }
 
 // PostImage 帖子图片结构
@@ -33,11 +48,24 @@ func (p *Post) BeforeDelete(tx *gorm.DB) error {
 	return tx.Delete(&PostImage{}, "post_id = ?", p.ID).Error
 }
 
-// CreatePostWithImages 创建帖子并添加图片
+// CreatePostWithImages 创建帖子并添加图片，关联的饮食记录必须属于作者
 func CreatePostWithImages(post *Post, imageURLs []string) error {
 	return DB.Transaction(func(tx *gorm.DB) error {
+		if post.FoodRecordID != nil {
+			var count int64
+			err := tx.Model(&FoodRecord{}).
+				Where("id = ? AND user_id = ?", *post.FoodRecordID, post.UserID).
+				Count(&count).Error
+			if err != nil {
+				return err
+			}
+			if count == 0 {
+				return ErrInvalidPostFoodRecord
+			}
+		}
+
 		// 创建帖子
-		if err := tx.Create(post).Error; err != nil {
+		if err := tx.Omit("Images").Create(post).Error; err != nil {
 			return err
 		}
 
@@ -53,6 +81,7 @@ func CreatePostWithImages(post *Post, imageURLs []string) error {
 			if err := tx.Create(&images).Error; err != nil {
 				return err
 			}
+			post.Images = images
 		}
 
 		return nil
@@ -62,7 +91,9 @@ func CreatePostWithImages(post *Post, imageURLs []string) error {
 // GetPostWithImages 获取帖子及其图片
 func GetPostWithImages(postID uint) (*Post, error) {
 	var post Post
-	err := DB.Preload("Images").First(&post, postID).Error
+	err := DB.Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).First(&post, postID).Error
 	if err != nil {
 		return nil, err
 	}
@@ -94,3 +125,173 @@ func UpdatePostImages(postID uint, newImageURLs []string) error {
 		return nil
 	})
 }
+
+// GetFeedPage 按发布时间倒序分页获取 viewerID 可见的帖子，authorID 不为空时只返回该用户的帖子
+// 与 viewerID 存在拉黑关系的用户的帖子，以及其他用户未通过审核的帖子不会返回
+// cursor 为上一页返回的游标，返回的游标为空表示没有更多数据
+func GetFeedPage(viewerID uint, authorID *uint, cursor *Cursor, limit int) ([]Post, *Cursor, error) {
+	var posts []Post
+	query := DB.Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Scopes(notBlockedScope(viewerID, "posts.user_id"), moderationVisibleScope(viewerID, "posts"))
+	if authorID != nil {
+		query = query.Where("user_id = ?", *authorID)
+	}
+	if cursor != nil {
+		query = query.Where("created_at < ? OR (created_at = ? AND id < ?)", cursor.Time, cursor.Time, cursor.ID)
+	}
+
+	// 多取一条用于判断是否还有下一页
+	err := query.Order("created_at DESC, id DESC").Limit(limit + 1).Find(&posts).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var next *Cursor
+	if len(posts) > limit {
+		posts = posts[:limit]
+		last := posts[len(posts)-1]
+		next = &Cursor{Time: last.CreatedAt, ID: last.ID}
+	}
+	return posts, next, nil
+}
+
+// DeletePost 删除帖子及其图片记录，点赞和评论随帖子一起隐藏
+func DeletePost(post *Post) error {
+	return DB.Delete(post).Error
+}
+
+// LoadPostDetails 为帖子填充作者、关联的饮食记录，以及 viewerID 是否已点赞
+// 关联的饮食记录遵循作者的饮食记录可见范围，viewerID 无权查看时不填充
+func LoadPostDetails(posts []Post, viewerID uint) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	postIDs := make([]uint, len(posts))
+	userIDs := make([]uint, 0, len(posts))
+	var foodRecordIDs []uint
+	for i, post := range posts {
+		postIDs[i] = post.ID
+		userIDs = append(userIDs, post.UserID)
+		if post.FoodRecordID != nil {
+			foodRecordIDs = append(foodRecordIDs, *post.FoodRecordID)
+		}
+	}
+
+	authors, err := GetUserSummaries(userIDs)
+	if err != nil {
+		return err
+	}
+
+	foodRecords := make(map[uint]*FoodRecord)
+	foodRecordVisible := make(map[uint]bool)
+	if len(foodRecordIDs) > 0 {
+		for _, post := range posts {
+			if post.FoodRecordID == nil {
+				continue
+			}
+			if _, checked := foodRecordVisible[post.UserID]; checked {
+				continue
+			}
+			settings, err := GetPrivacySettings(post.UserID)
+			if err != nil {
+				return err
+			}
+			visible, err := CanView(viewerID, post.UserID, settings.FoodRecordVisibility)
+			if err != nil {
+				return err
+			}
+			foodRecordVisible[post.UserID] = visible
+		}
+
+		var records []FoodRecord
+		if err := DB.Where("id IN ?", foodRecordIDs).Find(&records).Error; err != nil {
+			return err
+		}
+		for i := range records {
+			foodRecords[records[i].ID] = &records[i]
+		}
+	}
+
+	var likedIDs []uint
+	err = DB.Model(&PostLike{}).
+		Where("user_id = ? AND post_id IN ?", viewerID, postIDs).
+		Pluck("post_id", &likedIDs).Error
+	if err != nil {
+		return err
+	}
+	liked := make(map[uint]bool, len(likedIDs))
+	for _, id := range likedIDs {
+		liked[id] = true
+	}
+
+	for i := range posts {
+		posts[i].Author = authors[posts[i].UserID]
+		posts[i].Liked = liked[posts[i].ID]
+		if posts[i].FoodRecordID != nil && foodRecordVisible[posts[i].UserID] {
+			// 关联的饮食记录被删除后不再展示
+			posts[i].FoodRecord = foodRecords[*posts[i].FoodRecordID]
+		}
+	}
+	return nil
+}
+
+// purgePosts 删除用户的帖子、点赞和评论，并修正其他帖子的计数
+func purgePosts(tx *gorm.DB, userID uint) error {
+	posts := tx.Unscoped().Model(&Post{}).Select("id").Where("user_id = ?", userID)
+	if err := tx.Where("post_id IN (?)", posts).Delete(&PostImage{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("post_id IN (?)", posts).Delete(&PostLike{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("post_id IN (?)", posts).Delete(&PostComment{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&Post{}).Error; err != nil {
+		return err
+	}
+
+	// 用户在其他帖子下的点赞和评论
+	var affected []uint
+	err := tx.Model(&PostLike{}).Where("user_id = ?", userID).Distinct().Pluck("post_id", &affected).Error
+	if err != nil {
+		return err
+	}
+	var commented []uint
+	err = tx.Unscoped().Model(&PostComment{}).Where("user_id = ?", userID).Distinct().Pluck("post_id", &commented).Error
+	if err != nil {
+		return err
+	}
+	affected = append(affected, commented...)
+
+	if err := tx.Where("user_id = ?", userID).Delete(&PostLike{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&PostComment{}).Error; err != nil {
+		return err
+	}
+	if len(affected) == 0 {
+		return nil
+	}
+	return recountPosts(tx, affected)
+}
+
+// recountPosts 根据点赞和评论记录重新计算帖子的计数
+func recountPosts(tx *gorm.DB, postIDs []uint) error {
+	return tx.Unscoped().Model(&Post{}).Where("id IN ?", postIDs).Updates(map[string]interface{}{
+		"like_count":    gorm.Expr("(SELECT COUNT(*) FROM post_likes WHERE post_likes.post_id = posts.id)"),
+		"comment_count": gorm.Expr("(SELECT COUNT(*) FROM post_comments WHERE post_comments.post_id = posts.id AND post_comments.deleted_at IS NULL AND post_comments.moderation_status = ?)", ModerationStatusApproved),
+	}).Error
+}
+
+// lockPost 锁定未删除的帖子
+func lockPost(tx *gorm.DB, postID uint) (*Post, error) {
+	var post Post
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&post, postID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
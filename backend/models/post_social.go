@@ -0,0 +1,151 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxPostCommentLength 评论的最大长度（字符）
+const MaxPostCommentLength = 500
+
+// PostLike 帖子点赞，每个用户对同一帖子只能点赞一次
+type PostLike struct {
+	PostID    uint      `json:"post_id" gorm:"primaryKey;autoIncrement:false"`
+	UserID    uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PostComment 帖子评论
+type PostComment struct {
+	gorm.Model
+	PostID           uint         `json:"post_id" gorm:"index;not null"` // 帖子ID
+	UserID           uint         `json:"user_id" gorm:"index;not null"` // 评论人ID
+	Content          string       `json:"content" gorm:"type:text;not null"`
+	ModerationStatus string       `json:"moderation_status" gorm:"size:20;not null;default:approved"` // 审核状态，未通过时只有评论人和管理员可见
+	Author           *UserSummary `json:"author,omitempty" gorm:"-"`                                  // 评论人信息，由 LoadCommentAuthors 填充
+}
+
+// SetPostLike 点赞或取消点赞，返回帖子最新的点赞数；重复点赞或取消不会改变计数
+func SetPostLike(postID, userID uint, like bool) (int, error) {
+	var count int
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		post, err := lockPost(tx, postID)
+		if err != nil {
+			return err
+		}
+
+		var result *gorm.DB
+		delta := 1
+		if like {
+			result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&PostLike{PostID: postID, UserID: userID})
+		} else {
+			result = tx.Where("post_id = ? AND user_id = ?", postID, userID).Delete(&PostLike{})
+			delta = -1
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		count = post.LikeCount
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		count += delta
+		return tx.Model(post).UpdateColumn("like_count", count).Error
+	})
+	return count, err
+}
+
+// CreatePostComment 发表评论，审核通过的评论同时增加帖子的评论数
+func CreatePostComment(comment *PostComment) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		post, err := lockPost(tx, comment.PostID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+		if comment.ModerationStatus != ModerationStatusApproved {
+			return nil
+		}
+		return tx.Model(post).UpdateColumn("comment_count", post.CommentCount+1).Error
+	})
+}
+
+// GetPostComment 获取帖子下的一条评论
+func GetPostComment(postID, commentID uint) (*PostComment, error) {
+	var comment PostComment
+	err := DB.Where("post_id = ?", postID).First(&comment, commentID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// DeletePostComment 删除评论，审核通过的评论同时减少帖子的评论数
+func DeletePostComment(comment *PostComment) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		post, err := lockPost(tx, comment.PostID)
+		if err != nil {
+			return err
+		}
+		result := tx.Delete(comment)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 || post.CommentCount == 0 || comment.ModerationStatus != ModerationStatusApproved {
+			return nil
+		}
+		return tx.Model(post).UpdateColumn("comment_count", post.CommentCount-1).Error
+	})
+}
+
+// GetPostComments 按时间顺序分页获取帖子的评论，不返回与 viewerID 存在拉黑关系的用户的评论
+// 以及其他用户未通过审核的评论
+func GetPostComments(postID, viewerID uint, page, pageSize int) ([]PostComment, int64, error) {
+	var comments []PostComment
+	var total int64
+
+	query := DB.Model(&PostComment{}).Where("post_id = ?", postID).
+		Scopes(notBlockedScope(viewerID, "post_comments.user_id"), moderationVisibleScope(viewerID, "post_comments"))
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("id").Offset(offset).Limit(pageSize).Find(&comments).Error
+	return comments, total, err
+}
+
+// LoadCommentAuthors 为评论填充评论人信息
+func LoadCommentAuthors(comments []PostComment) error {
+	userIDs := make([]uint, len(comments))
+	for i, comment := range comments {
+		userIDs[i] = comment.UserID
+	}
+	authors, err := GetUserSummaries(userIDs)
+	if err != nil {
+		return err
+	}
+	for i := range comments {
+		comments[i].Author = authors[comments[i].UserID]
+	}
+	return nil
+}
+
+// GetUserPosts 获取用户发布的全部帖子（用于数据导出）
+func GetUserPosts(userID uint) ([]Post, error) {
+	var posts []Post
+	err := DB.Preload("Images").Where("user_id = ?", userID).Order("id").Find(&posts).Error
+	return posts, err
+}
+
+// GetUserPostComments 获取用户发表的全部评论（用于数据导出）
+func GetUserPostComments(userID uint) ([]PostComment, error) {
+	var comments []PostComment
+	err := DB.Where("user_id = ?", userID).Order("id").Find(&comments).Error
+	return comments, err
+}
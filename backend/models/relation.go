@@ -0,0 +1,365 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 内容可见范围
+const (
+	VisibilityPublic    = "public"    // 所有人可见
+	VisibilityFollowers = "followers" // 关注者和好友可见，关注无需对方同意，因此不用于饮食和打卡记录
+	VisibilityFriends   = "friends"   // 仅好友可见
+	VisibilityPrivate   = "private"   // 仅自己可见
+)
+
+// Visibilities 所有可见范围
+var Visibilities = []string{VisibilityPublic, VisibilityFollowers, VisibilityFriends, VisibilityPrivate}
+
+// IsValidVisibility 判断可见范围是否有效
+func IsValidVisibility(visibility string) bool {
+	return containsString(Visibilities, visibility)
+}
+
+// DietVisibilities 饮食和打卡记录可选的可见范围
+// 任何登录用户都能直接关注他人，followers 对这类数据几乎等同于公开，因此不提供
+var DietVisibilities = []string{VisibilityPublic, VisibilityFriends, VisibilityPrivate}
+
+// IsValidDietVisibility 判断饮食和打卡记录的可见范围是否有效
+func IsValidDietVisibility(visibility string) bool {
+	return containsString(DietVisibilities, visibility)
+}
+
+// Follow 关注关系，关注无需对方同意
+type Follow struct {
+	FollowerID uint      `json:"follower_id" gorm:"primaryKey;autoIncrement:false"`       // 关注者
+	FolloweeID uint      `json:"followee_id" gorm:"primaryKey;autoIncrement:false;index"` // 被关注者
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// FriendRequest 待处理的好友请求，接受或拒绝后删除
+type FriendRequest struct {
+	SenderID   uint         `json:"sender_id" gorm:"primaryKey;autoIncrement:false"`
+	ReceiverID uint         `json:"receiver_id" gorm:"primaryKey;autoIncrement:false;index"`
+	Message    string       `json:"message" gorm:"size:255"`
+	CreatedAt  time.Time    `json:"created_at"`
+	User       *UserSummary `json:"user,omitempty" gorm:"-"` // 请求的另一方，由调用方填充
+}
+
+// Friendship 好友关系，每对好友保存双向两条记录
+type Friendship struct {
+	UserID    uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	FriendID  uint      `json:"friend_id" gorm:"primaryKey;autoIncrement:false;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Block 拉黑关系，双方互相不可见，且不能关注、加好友、评论或交易
+type Block struct {
+	BlockerID uint      `json:"blocker_id" gorm:"primaryKey;autoIncrement:false"`
+	BlockedID uint      `json:"blocked_id" gorm:"primaryKey;autoIncrement:false;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Relation 当前用户与另一个用户的关系
+type Relation struct {
+	Following       bool `json:"following"`        // 我关注了对方
+	FollowedBy      bool `json:"followed_by"`      // 对方关注了我
+	Friends         bool `json:"friends"`          // 互为好友
+	RequestSent     bool `json:"request_sent"`     // 我发出了好友请求
+	RequestReceived bool `json:"request_received"` // 对方向我发出了好友请求
+	Blocking        bool `json:"blocking"`         // 我拉黑了对方
+	BlockedBy       bool `json:"blocked_by"`       // 对方拉黑了我
+}
+
+// GetRelation 获取 viewerID 与 otherID 之间的关系
+func GetRelation(viewerID, otherID uint) (*Relation, error) {
+	relation := &Relation{}
+	checks := []struct {
+		dest  *bool
+		model interface{}
+		query string
+		args  []interface{}
+	}{
+		{&relation.Following, &Follow{}, "follower_id = ? AND followee_id = ?", []interface{}{viewerID, otherID}},
+		{&relation.FollowedBy, &Follow{}, "follower_id = ? AND followee_id = ?", []interface{}{otherID, viewerID}},
+		{&relation.Friends, &Friendship{}, "user_id = ? AND friend_id = ?", []interface{}{viewerID, otherID}},
+		{&relation.RequestSent, &FriendRequest{}, "sender_id = ? AND receiver_id = ?", []interface{}{viewerID, otherID}},
+		{&relation.RequestReceived, &FriendRequest{}, "sender_id = ? AND receiver_id = ?", []interface{}{otherID, viewerID}},
+		{&relation.Blocking, &Block{}, "blocker_id = ? AND blocked_id = ?", []interface{}{viewerID, otherID}},
+		{&relation.BlockedBy, &Block{}, "blocker_id = ? AND blocked_id = ?", []interface{}{otherID, viewerID}},
+	}
+	for _, check := range checks {
+		var count int64
+		if err := DB.Model(check.model).Where(check.query, check.args...).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		*check.dest = count > 0
+	}
+	return relation, nil
+}
+
+// IsBlockedEither 判断两个用户之间是否存在任一方向的拉黑
+func IsBlockedEither(a, b uint) (bool, error) {
+	var count int64
+	err := DB.Model(&Block{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", a, b, b, a).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CanView 判断 viewerID 能否查看 ownerID 设置为 visibility 的内容，viewerID 为 0 表示未登录
+func CanView(viewerID, ownerID uint, visibility string) (bool, error) {
+	if viewerID != 0 && viewerID == ownerID {
+		return true, nil
+	}
+	if viewerID != 0 {
+		blocked, err := IsBlockedEither(viewerID, ownerID)
+		if err != nil || blocked {
+			return false, err
+		}
+	}
+
+	switch visibility {
+	case VisibilityPublic:
+		return true, nil
+	case VisibilityFollowers, VisibilityFriends:
+		if viewerID == 0 {
+			return false, nil
+		}
+		relation, err := GetRelation(viewerID, ownerID)
+		if err != nil {
+			return false, err
+		}
+		if visibility == VisibilityFollowers {
+			return relation.Following || relation.Friends, nil
+		}
+		return relation.Friends, nil
+	}
+	return false, nil
+}
+
+// notBlockedScope 在查询中排除 viewerID 与 ownerColumn 所指用户之间存在任一方向拉黑的记录
+func notBlockedScope(viewerID uint, ownerColumn string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewerID == 0 {
+			return db
+		}
+		return db.Where(
+			"NOT EXISTS (SELECT 1 FROM blocks WHERE (blocks.blocker_id = ? AND blocks.blocked_id = "+ownerColumn+") OR (blocks.blocker_id = "+ownerColumn+" AND blocks.blocked_id = ?))",
+			viewerID, viewerID,
+		)
+	}
+}
+
+// PrivacySettings 用户各类内容的可见范围
+type PrivacySettings struct {
+	FoodRecordVisibility string `json:"food_record_visibility"`
+	CheckInVisibility    string `json:"check_in_visibility"`
+	ShowcaseVisibility   string `json:"showcase_visibility"`
+}
+
+// GetPrivacySettings 获取用户的可见范围设置，未创建资料的用户使用默认设置
+func GetPrivacySettings(userID uint) (*PrivacySettings, error) {
+	defaults := NewDefaultUserProfile(userID)
+	settings := &PrivacySettings{
+		FoodRecordVisibility: defaults.FoodRecordVisibility,
+		CheckInVisibility:    defaults.CheckInVisibility,
+		ShowcaseVisibility:   defaults.ShowcaseVisibility,
+	}
+
+	var profile UserProfile
+	err := DB.Select("food_record_visibility", "check_in_visibility", "showcase_visibility").
+		Where("user_id = ?", userID).
+		Take(&profile).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, setting := range []struct {
+		value string
+		dest  *string
+	}{
+		{profile.FoodRecordVisibility, &settings.FoodRecordVisibility},
+		{profile.CheckInVisibility, &settings.CheckInVisibility},
+		{profile.ShowcaseVisibility, &settings.ShowcaseVisibility},
+	} {
+		if IsValidVisibility(setting.value) {
+			*setting.dest = setting.value
+		}
+	}
+	return settings, nil
+}
+
+// CreateFollow 关注用户，已关注时不做任何操作，返回是否新建了关注
+func CreateFollow(followerID, followeeID uint) (bool, error) {
+	result := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&Follow{FollowerID: followerID, FolloweeID: followeeID})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteFollow 取消关注
+func DeleteFollow(followerID, followeeID uint) error {
+	return DB.Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Delete(&Follow{}).Error
+}
+
+// GetFollowers 分页获取关注 userID 的用户
+func GetFollowers(userID uint, page, pageSize int) ([]UserSummary, int64, error) {
+	return pageRelatedUsers(DB.Model(&Follow{}).Where("followee_id = ?", userID), "follower_id", page, pageSize)
+}
+
+// GetFollowing 分页获取 userID 关注的用户
+func GetFollowing(userID uint, page, pageSize int) ([]UserSummary, int64, error) {
+	return pageRelatedUsers(DB.Model(&Follow{}).Where("follower_id = ?", userID), "followee_id", page, pageSize)
+}
+
+// GetFriends 分页获取用户的好友
+func GetFriends(userID uint, page, pageSize int) ([]UserSummary, int64, error) {
+	return pageRelatedUsers(DB.Model(&Friendship{}).Where("user_id = ?", userID), "friend_id", page, pageSize)
+}
+
+// GetBlockedUsers 分页获取用户拉黑的用户
+func GetBlockedUsers(userID uint, page, pageSize int) ([]UserSummary, int64, error) {
+	return pageRelatedUsers(DB.Model(&Block{}).Where("blocker_id = ?", userID), "blocked_id", page, pageSize)
+}
+
+// GetFriendRequests 分页获取收到（incoming 为 true）或发出的好友请求
+func GetFriendRequests(userID uint, incoming bool, page, pageSize int) ([]FriendRequest, int64, error) {
+	var requests []FriendRequest
+	var total int64
+
+	query := DB.Model(&FriendRequest{})
+	if incoming {
+		query = query.Where("receiver_id = ?", userID)
+	} else {
+		query = query.Where("sender_id = ?", userID)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&requests).Error
+	return requests, total, err
+}
+
+// CountRelations 统计用户的关注者、关注和好友数量
+func CountRelations(userID uint) (followers, following, friends int64, err error) {
+	if err = DB.Model(&Follow{}).Where("followee_id = ?", userID).Count(&followers).Error; err != nil {
+		return
+	}
+	if err = DB.Model(&Follow{}).Where("follower_id = ?", userID).Count(&following).Error; err != nil {
+		return
+	}
+	err = DB.Model(&Friendship{}).Where("user_id = ?", userID).Count(&friends).Error
+	return
+}
+
+// CreateFriendRequest 发出好友请求，已有待处理的请求时不做任何操作
+func CreateFriendRequest(request *FriendRequest) error {
+	return DB.Clauses(clause.OnConflict{DoNothing: true}).Create(request).Error
+}
+
+// DeleteFriendRequest 删除待处理的好友请求，返回是否存在该请求
+func DeleteFriendRequest(senderID, receiverID uint) (bool, error) {
+	result := DB.Where("sender_id = ? AND receiver_id = ?", senderID, receiverID).Delete(&FriendRequest{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// AcceptFriendRequest 接受好友请求并建立双向好友关系，请求不存在时返回 gorm.ErrRecordNotFound
+func AcceptFriendRequest(senderID, receiverID uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("sender_id = ? AND receiver_id = ?", senderID, receiverID).Delete(&FriendRequest{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		// 对方可能也向自己发出了请求，一并清除
+		if err := tx.Where("sender_id = ? AND receiver_id = ?", receiverID, senderID).Delete(&FriendRequest{}).Error; err != nil {
+			return err
+		}
+		friendships := []Friendship{
+			{UserID: senderID, FriendID: receiverID},
+			{UserID: receiverID, FriendID: senderID},
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&friendships).Error
+	})
+}
+
+// DeleteFriendship 解除好友关系
+func DeleteFriendship(a, b uint) error {
+	return DB.Where("(user_id = ? AND friend_id = ?) OR (user_id = ? AND friend_id = ?)", a, b, b, a).
+		Delete(&Friendship{}).Error
+}
+
+// CreateBlock 拉黑用户，同时解除双方的关注、好友关系和好友请求
+func CreateBlock(blockerID, blockedID uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&Block{BlockerID: blockerID, BlockedID: blockedID}).Error; err != nil {
+			return err
+		}
+		return deleteRelationsBetween(tx, blockerID, blockedID)
+	})
+}
+
+// DeleteBlock 取消拉黑
+func DeleteBlock(blockerID, blockedID uint) error {
+	return DB.Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).Delete(&Block{}).Error
+}
+
+// deleteRelationsBetween 删除两个用户之间的关注、好友关系和好友请求
+func deleteRelationsBetween(tx *gorm.DB, a, b uint) error {
+	if err := tx.Where("(follower_id = ? AND followee_id = ?) OR (follower_id = ? AND followee_id = ?)", a, b, b, a).Delete(&Follow{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("(user_id = ? AND friend_id = ?) OR (user_id = ? AND friend_id = ?)", a, b, b, a).Delete(&Friendship{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)", a, b, b, a).Delete(&FriendRequest{}).Error
+}
+
+// purgeRelations 删除用户作为任一方的所有关系
+func purgeRelations(tx *gorm.DB, userID uint) error {
+	if err := tx.Where("follower_id = ? OR followee_id = ?", userID, userID).Delete(&Follow{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ? OR friend_id = ?", userID, userID).Delete(&Friendship{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("sender_id = ? OR receiver_id = ?", userID, userID).Delete(&FriendRequest{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("blocker_id = ? OR blocked_id = ?", userID, userID).Delete(&Block{}).Error
+}
+
+// pageRelatedUsers 分页获取关系表中 userColumn 指向的用户信息，按建立关系的时间倒序
+func pageRelatedUsers(query *gorm.DB, userColumn string, page, pageSize int) ([]UserSummary, int64, error) {
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var userIDs []uint
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Pluck(userColumn, &userIDs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	summaries, err := GetUserSummaries(userIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	users := make([]UserSummary, 0, len(userIDs))
+	for _, id := range userIDs {
+		if summary, ok := summaries[id]; ok {
+			users = append(users, *summary)
+		}
+	}
+	return users, total, nil
+}
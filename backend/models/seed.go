@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SeedVersion 已执行的数据初始化版本，每个版本只会执行一次
+type SeedVersion struct {
+	Version   int       `json:"version" gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `json:"name" gorm:"size:100;not null"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// ApplySeed 在一个事务中执行指定版本的数据初始化并记录版本，已执行过的版本直接跳过
+// 多个实例同时启动时，版本记录的主键保证只有一个实例执行
+func ApplySeed(version int, name string, apply func(tx *gorm.DB) error) (applied bool, err error) {
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&SeedVersion{
+			Version:   version,
+			Name:      name,
+			AppliedAt: time.Now(),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		applied = true
+		return apply(tx)
+	})
+	if err != nil {
+		applied = false
+	}
+	return applied, err
+}
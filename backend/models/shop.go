@@ -0,0 +1,151 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrListingUnavailable 商品不存在或已下架
+	ErrListingUnavailable = errors.New("商品不存在或已下架")
+	// ErrOutOfStock 商品库存不足
+	ErrOutOfStock = errors.New("商品库存不足")
+)
+
+// MaxPurchaseQuantity 单次购买的最大份数
+const MaxPurchaseQuantity = 99
+
+// ShopListing 商店上架的商品，使用金币购买物品
+type ShopListing struct {
+	gorm.Model
+	ItemID  uint  `json:"item_id" gorm:"index;not null"`           // 出售的物品ID
+	Price   int64 `json:"price" gorm:"not null"`                   // 单价（金币）
+	Stock   *int  `json:"stock"`                                   // 剩余库存，为空表示不限量
+	Enabled bool  `json:"enabled" gorm:"not null"`                 // 是否上架
+	Item    *Item `json:"item,omitempty" gorm:"foreignKey:ItemID"` // 关联物品
+}
+
+// ShopPurchase 购买结果
+type ShopPurchase struct {
+	Listing     *ShopListing     `json:"listing"`
+	Quantity    int              `json:"quantity"`
+	Transaction *CoinTransaction `json:"transaction"`
+	UserItem    *UserItem        `json:"user_item"`
+}
+
+// CreateShopListing 创建商品
+func CreateShopListing(listing *ShopListing) error {
+	return DB.Create(listing).Error
+}
+
+// GetShopListingByID 根据ID获取商品
+func GetShopListingByID(listingID uint) (*ShopListing, error) {
+	var listing ShopListing
+	err := DB.Preload("Item").First(&listing, listingID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &listing, nil
+}
+
+// GetShopListings 分页获取商品，enabledOnly 为 true 时只返回上架中的商品
+func GetShopListings(enabledOnly bool, page, pageSize int) ([]ShopListing, int64, error) {
+	var listings []ShopListing
+	var total int64
+
+	query := DB.Model(&ShopListing{})
+	if enabledOnly {
+		query = query.Where("enabled = ?", true)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Preload("Item").Order("id").Offset(offset).Limit(pageSize).Find(&listings).Error
+	return listings, total, err
+}
+
+// UpdateShopListing 更新商品
+func UpdateShopListing(listing *ShopListing) error {
+	return DB.Omit("Item").Save(listing).Error
+}
+
+// DeleteShopListing 删除商品
+func DeleteShopListing(listingID uint) error {
+	return DB.Delete(&ShopListing{}, listingID).Error
+}
+
+// PurchaseShopListing 在一个事务中扣减库存、扣除金币并发放物品
+func PurchaseShopListing(userID, listingID uint, quantity int) (*ShopPurchase, error) {
+	purchase := &ShopPurchase{Quantity: quantity}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var listing ShopListing
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("enabled = ?", true).
+			First(&listing, listingID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrListingUnavailable
+		}
+		if err != nil {
+			return err
+		}
+
+		var item Item
+		if err := tx.First(&item, listing.ItemID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrListingUnavailable
+			}
+			return err
+		}
+		listing.Item = &item
+
+		if listing.Stock != nil {
+			if *listing.Stock < quantity {
+				return ErrOutOfStock
+			}
+			stock := *listing.Stock - quantity
+			if err := tx.Model(&listing).Update("stock", stock).Error; err != nil {
+				return err
+			}
+			listing.Stock = &stock
+		}
+
+		// 每次购买使用独立的来源标识，金币流水和物品流水通过它关联
+		ref := fmt.Sprintf("listing:%d:%s", listing.ID, uuid.New().String())
+		reason := fmt.Sprintf("购买 %s x%d", item.Name, quantity)
+		purchase.Transaction, err = ChangeCoins(tx, CoinChange{
+			UserID:    userID,
+			Amount:    -listing.Price * int64(quantity),
+			Type:      CoinTxPurchase,
+			SourceRef: ref,
+			ActorID:   &userID,
+			Reason:    reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		purchase.UserItem, _, err = grantItemTx(tx, GrantRequest{
+			UserID:    userID,
+			ItemID:    listing.ItemID,
+			Quantity:  quantity,
+			Source:    "shop",
+			SourceRef: ref,
+			ActorID:   &userID,
+			Reason:    reason,
+		})
+		purchase.Listing = &listing
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return purchase, nil
+}
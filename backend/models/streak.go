@@ -0,0 +1,120 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrStreakDayTaken 该日期已经打卡或已使用冻结卡
+var ErrStreakDayTaken = errors.New("该日期已打卡或已冻结")
+
+// ErrStreakFreezeLimit 本月使用的冻结卡已达上限
+var ErrStreakFreezeLimit = errors.New("本月冻结卡使用次数已达上限")
+
+// StreakFreezeLimit 冻结卡的使用上限：Since 之后最多使用 Max 张
+type StreakFreezeLimit struct {
+	Since time.Time
+	Max   int64
+}
+
+// StreakFreeze 连续打卡冻结记录，被冻结的日期在计算连续打卡时视为已打卡
+type StreakFreeze struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_user_freeze_date"`
+	Date      string    `json:"date" gorm:"size:10;not null;uniqueIndex:idx_user_freeze_date"` // 被冻结的日期（用户时区 YYYY-MM-DD）
+	ItemID    uint      `json:"item_id"`                                                       // 消耗的物品
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetUserStreakFreezes 获取用户所有冻结记录
+func GetUserStreakFreezes(userID uint) ([]StreakFreeze, error) {
+	var freezes []StreakFreeze
+	err := DB.Where("user_id = ?", userID).Order("date").Find(&freezes).Error
+	return freezes, err
+}
+
+// countStreakFreezesUsedSince 在调用方的事务中统计用户在 since 之后使用的冻结卡数量
+// 按使用时间而不是被冻结的日期统计，月初冻结上个月的日期同样计入本月
+func countStreakFreezesUsedSince(tx *gorm.DB, userID uint, since time.Time) (int64, error) {
+	var count int64
+	err := tx.Model(&StreakFreeze{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Count(&count).Error
+	return count, err
+}
+
+// HasUserCheckInBetween 检查用户在时间范围 [start, end) 内是否有打卡记录
+func HasUserCheckInBetween(userID uint, start, end time.Time) (bool, error) {
+	var count int64
+	err := DB.Model(&CheckIn{}).
+		Where("user_id = ? AND check_in_at >= ? AND check_in_at < ?", userID, start, end).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// UseStreakFreeze 在一个事务中消耗一张冻结卡并冻结指定日期
+func UseStreakFreeze(freeze *StreakFreeze, dayStart, dayEnd time.Time, limit StreakFreezeLimit) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := CreateStreakFreeze(tx, freeze, dayStart, dayEnd, limit); err != nil {
+			return err
+		}
+		return ConsumeUserItem(tx, freeze.UserID, freeze.ItemID, 1, "streak_freeze", "冻结连续打卡："+freeze.Date)
+	})
+}
+
+// CreateStreakFreeze 在调用方的事务中冻结指定日期，不消耗物品
+// 锁定用户后再检查使用上限，并发冻结不同日期时不会超过上限，超过时返回 ErrStreakFreezeLimit
+func CreateStreakFreeze(tx *gorm.DB, freeze *StreakFreeze, dayStart, dayEnd time.Time, limit StreakFreezeLimit) error {
+	if err := ensureStreakDayFree(tx, freeze.UserID, freeze.Date, dayStart, dayEnd); err != nil {
+		return err
+	}
+	used, err := countStreakFreezesUsedSince(tx, freeze.UserID, limit.Since)
+	if err != nil {
+		return err
+	}
+	if used >= limit.Max {
+		return ErrStreakFreezeLimit
+	}
+	return tx.Create(freeze).Error
+}
+
+// CreateMakeUpCheckIn 在一个事务中消耗一张补签卡并为指定日期补签
+func CreateMakeUpCheckIn(checkIn *CheckIn, itemID uint, date string, dayStart, dayEnd time.Time) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := ensureStreakDayFree(tx, checkIn.UserID, date, dayStart, dayEnd); err != nil {
+			return err
+		}
+		if err := ConsumeUserItem(tx, checkIn.UserID, itemID, 1, "make_up_check_in", "补签："+date); err != nil {
+			return err
+		}
+		checkIn.MakeUp = true
+		return tx.Create(checkIn).Error
+	})
+}
+
+// ensureStreakDayFree 确认该日期既没有打卡也没有被冻结
+// 先锁定用户再用加锁读检查，同一用户并发的补签和冻结会依次执行，不会为同一天重复消耗物品
+func ensureStreakDayFree(tx *gorm.DB, userID uint, date string, dayStart, dayEnd time.Time) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Take(&User{}, userID).Error; err != nil {
+		return err
+	}
+
+	var checkIns, freezes int64
+	if err := tx.Model(&CheckIn{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND check_in_at >= ? AND check_in_at < ?", userID, dayStart, dayEnd).
+		Count(&checkIns).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&StreakFreeze{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND date = ?", userID, date).
+		Count(&freezes).Error; err != nil {
+		return err
+	}
+	if checkIns > 0 || freezes > 0 {
+		return ErrStreakDayTaken
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 交易状态
+const (
+	TradeStatusPending   = "pending"   // 等待对方处理
+	TradeStatusAccepted  = "accepted"  // 已接受，物品已转移
+	TradeStatusDeclined  = "declined"  // 已拒绝
+	TradeStatusCancelled = "cancelled" // 发起人已取消
+	TradeStatusExpired   = "expired"   // 已过期
+)
+
+// 交易物品所属的一方
+const (
+	TradeSideOffer   = "offer"   // 发起人给出的物品
+	TradeSideRequest = "request" // 发起人向对方索要的物品
+)
+
+var (
+	// ErrTradeNotPending 交易已处理、已取消或已过期
+	ErrTradeNotPending = errors.New("交易已处理或已过期")
+	// ErrItemNotTradable 物品不允许交易
+	ErrItemNotTradable = errors.New("物品不允许赠送或交易")
+)
+
+// TradeOffer 用户之间的赠送或交易请求，没有索要物品时即为赠送
+type TradeOffer struct {
+	ID          uint             `json:"id" gorm:"primarykey"`
+	SenderID    uint             `json:"sender_id" gorm:"index;not null"`      // 发起人
+	ReceiverID  uint             `json:"receiver_id" gorm:"index;not null"`    // 接收人
+	Status      string           `json:"status" gorm:"size:20;index;not null"` // 状态
+	Message     string           `json:"message" gorm:"size:255"`              // 附言
+	ExpiresAt   time.Time        `json:"expires_at" gorm:"index"`              // 过期时间
+	RespondedAt *time.Time       `json:"responded_at"`                         // 处理时间
+	Items       []TradeOfferItem `json:"items" gorm:"foreignKey:OfferID"`      // 交易物品
+	CreatedAt   time.Time        `json:"created_at" gorm:"index"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// TradeOfferItem 交易中的一项物品
+type TradeOfferItem struct {
+	ID       uint   `json:"id" gorm:"primarykey"`
+	OfferID  uint   `json:"offer_id" gorm:"index;not null"`
+	ItemID   uint   `json:"item_id" gorm:"not null"`
+	Quantity int    `json:"quantity" gorm:"not null"`
+	Side     string `json:"side" gorm:"size:10;not null"` // offer 或 request
+	Item     *Item  `json:"item,omitempty" gorm:"foreignKey:ItemID"`
+}
+
+// IsGift 没有索要物品的交易即为赠送
+func (o *TradeOffer) IsGift() bool {
+	for _, item := range o.Items {
+		if item.Side == TradeSideRequest {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateTradeOffer 创建交易请求及其物品
+func CreateTradeOffer(offer *TradeOffer) error {
+	return DB.Create(offer).Error
+}
+
+// GetTradeOffer 根据ID获取交易请求
+func GetTradeOffer(offerID uint) (*TradeOffer, error) {
+	var offer TradeOffer
+	err := DB.Preload("Items.Item").First(&offer, offerID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+// GetUserTradeOffers 分页获取用户收到（incoming）或发出（outgoing）的交易请求，status 为空时不过滤
+func GetUserTradeOffers(userID uint, incoming bool, status string, page, pageSize int) ([]TradeOffer, int64, error) {
+	var offers []TradeOffer
+	var total int64
+
+	query := DB.Model(&TradeOffer{})
+	if incoming {
+		query = query.Where("receiver_id = ?", userID)
+	} else {
+		query = query.Where("sender_id = ?", userID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Preload("Items.Item").Order("id DESC").Offset(offset).Limit(pageSize).Find(&offers).Error
+	return offers, total, err
+}
+
+// CountPendingTradeOffers 统计用户发出（或收到）的待处理交易数量
+func CountPendingTradeOffers(userID uint, incoming bool) (int64, error) {
+	column := "sender_id"
+	if incoming {
+		column = "receiver_id"
+	}
+	var count int64
+	err := DB.Model(&TradeOffer{}).
+		Where(column+" = ? AND status = ?", userID, TradeStatusPending).
+		Count(&count).Error
+	return count, err
+}
+
+// CountTradeOffersSince 统计用户在某个时间之后发出的交易数量
+func CountTradeOffersSince(senderID uint, since time.Time) (int64, error) {
+	var count int64
+	err := DB.Model(&TradeOffer{}).Where("sender_id = ? AND created_at >= ?", senderID, since).Count(&count).Error
+	return count, err
+}
+
+// ExpireTradeOffers 将已过期的待处理交易标记为过期
+func ExpireTradeOffers(now time.Time) (int64, error) {
+	result := DB.Model(&TradeOffer{}).
+		Where("status = ? AND expires_at <= ?", TradeStatusPending, now).
+		Updates(map[string]interface{}{"status": TradeStatusExpired, "responded_at": now})
+	return result.RowsAffected, result.Error
+}
+
+// RespondTradeOffer 接收人接受或拒绝交易，接受时在同一事务中转移双方的物品
+func RespondTradeOffer(offerID, receiverID uint, accept bool, now time.Time) (*TradeOffer, error) {
+	status := TradeStatusDeclined
+	if accept {
+		status = TradeStatusAccepted
+	}
+
+	isReceiver := func(offer *TradeOffer) bool { return offer.ReceiverID == receiverID }
+	return finishTradeOffer(offerID, now, status, isReceiver, func(tx *gorm.DB, offer *TradeOffer) error {
+		if !accept {
+			return nil
+		}
+		return transferTradeItems(tx, offer)
+	})
+}
+
+// CancelTradeOffer 发起人取消待处理的交易
+func CancelTradeOffer(offerID, senderID uint, now time.Time) (*TradeOffer, error) {
+	isSender := func(offer *TradeOffer) bool { return offer.SenderID == senderID }
+	return finishTradeOffer(offerID, now, TradeStatusCancelled, isSender, nil)
+}
+
+// finishTradeOffer 锁定待处理的交易，执行 apply 后更新为最终状态
+// 交易不属于当前用户时返回 gorm.ErrRecordNotFound
+func finishTradeOffer(offerID uint, now time.Time, status string, isParty func(*TradeOffer) bool, apply func(tx *gorm.DB, offer *TradeOffer) error) (*TradeOffer, error) {
+	var offer TradeOffer
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items.Item").First(&offer, offerID).Error; err != nil {
+			return err
+		}
+		if !isParty(&offer) {
+			return gorm.ErrRecordNotFound
+		}
+		if offer.Status != TradeStatusPending || !offer.ExpiresAt.After(now) {
+			return ErrTradeNotPending
+		}
+		if apply != nil {
+			if err := apply(tx, &offer); err != nil {
+				return err
+			}
+		}
+
+		offer.Status = status
+		offer.RespondedAt = &now
+		return tx.Model(&offer).Updates(map[string]interface{}{"status": status, "responded_at": now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+// tradeTransfer 一次物品转移
+type tradeTransfer struct {
+	fromID, toID, itemID uint
+	quantity             int
+}
+
+// transferTradeItems 转移交易双方的物品，按用户和物品排序加锁以减少死锁
+func transferTradeItems(tx *gorm.DB, offer *TradeOffer) error {
+	transfers := make([]tradeTransfer, 0, len(offer.Items))
+	for _, item := range offer.Items {
+		if item.Item == nil || !item.Item.Tradable {
+			return ErrItemNotTradable
+		}
+		t := tradeTransfer{fromID: offer.SenderID, toID: offer.ReceiverID, itemID: item.ItemID, quantity: item.Quantity}
+		if item.Side == TradeSideRequest {
+			t.fromID, t.toID = offer.ReceiverID, offer.SenderID
+		}
+		transfers = append(transfers, t)
+	}
+	sort.Slice(transfers, func(i, j int) bool {
+		if transfers[i].fromID != transfers[j].fromID {
+			return transfers[i].fromID < transfers[j].fromID
+		}
+		return transfers[i].itemID < transfers[j].itemID
+	})
+
+	ref := fmt.Sprintf("offer:%d", offer.ID)
+	for _, t := range transfers {
+		if err := transferUserItem(tx, t, ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transferUserItem 在事务中从一个用户扣减物品并发放给另一个用户，双方都记录流水
+func transferUserItem(tx *gorm.DB, t tradeTransfer, ref string) error {
+	rows, err := lockUserItems(tx, t.fromID, t.itemID)
+	if err != nil {
+		return err
+	}
+
+	before := totalQuantity(rows)
+	if len(rows) == 0 || before < t.quantity {
+		return ErrInsufficientItems
+	}
+	if _, err := mergeUserItems(tx, rows, before-t.quantity); err != nil {
+		return err
+	}
+
+	reason := fmt.Sprintf("交易 %s：用户 %d -> 用户 %d", ref, t.fromID, t.toID)
+	err = tx.Create(&ItemLedger{
+		UserID:        t.fromID,
+		ItemID:        t.itemID,
+		Action:        LedgerActionTransferOut,
+		Delta:         -t.quantity,
+		QuantityAfter: before - t.quantity,
+		Source:        "trade",
+		SourceRef:     ref,
+		ActorID:       &t.fromID,
+		Reason:        reason,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	_, _, err = grantItemTx(tx, GrantRequest{
+		UserID:    t.toID,
+		ItemID:    t.itemID,
+		Quantity:  t.quantity,
+		Source:    "trade",
+		SourceRef: ref,
+		ActorID:   &t.fromID,
+		Reason:    reason,
+	})
+	return err
+}
+
+// purgeTradeOffers 删除用户发出或收到的所有交易记录
+func purgeTradeOffers(tx *gorm.DB, userID uint) error {
+	offers := tx.Model(&TradeOffer{}).Select("id").Where("sender_id = ? OR receiver_id = ?", userID, userID)
+	if err := tx.Where("offer_id IN (?)", offers).Delete(&TradeOfferItem{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("sender_id = ? OR receiver_id = ?", userID, userID).Delete(&TradeOffer{}).Error
+}
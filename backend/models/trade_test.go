@@ -0,0 +1,167 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var (
+	testDBOnce  sync.Once
+	testUserSeq int64
+)
+
+// setupTestDB 连接 TEST_DB_NAME 指定的 MySQL 测试库（连接参数与 DB_HOST 等配置相同），未设置时跳过测试
+// 测试会写入用户、物品和交易，不要指向正式数据库
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	name := os.Getenv("TEST_DB_NAME")
+	if name == "" {
+		t.Skip("未设置 TEST_DB_NAME，跳过需要数据库的测试")
+	}
+	testDBOnce.Do(func() {
+		os.Setenv("DB_NAME", name)
+		InitDB()
+	})
+}
+
+func createTradeTestUser(t *testing.T) *User {
+	t.Helper()
+	user := &User{Name: "测试用户", Email: fmt.Sprintf("trade_%d_%d@example.com", time.Now().UnixNano(), atomic.AddInt64(&testUserSeq, 1)), Role: RoleUser, Verified: true}
+	if err := DB.Create(user).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	return user
+}
+
+func createTradeTestItem(t *testing.T, tradable bool, maxStack int) *Item {
+	t.Helper()
+	item := &Item{Name: "测试物品", Source: "test", IconURL: "/icon.png", ImageURL: "/image.png", Tradable: tradable, MaxStack: maxStack}
+	if err := CreateItem(item); err != nil {
+		t.Fatalf("创建物品失败: %v", err)
+	}
+	return item
+}
+
+func giveTradeTestItem(t *testing.T, userID, itemID uint, quantity int) {
+	t.Helper()
+	if _, _, err := GrantItem(GrantRequest{UserID: userID, ItemID: itemID, Quantity: quantity, Source: "test"}); err != nil {
+		t.Fatalf("发放物品失败: %v", err)
+	}
+}
+
+func createTradeTestOffer(t *testing.T, senderID, receiverID uint, now time.Time, items ...TradeOfferItem) *TradeOffer {
+	t.Helper()
+	offer := &TradeOffer{SenderID: senderID, ReceiverID: receiverID, Status: TradeStatusPending, ExpiresAt: now.Add(time.Hour), Items: items}
+	if err := CreateTradeOffer(offer); err != nil {
+		t.Fatalf("创建交易失败: %v", err)
+	}
+	return offer
+}
+
+func assertQuantity(t *testing.T, userID, itemID uint, want int) {
+	t.Helper()
+	got, err := GetUserItemQuantity(userID, itemID)
+	if err != nil {
+		t.Fatalf("查询用户 %d 的物品 %d 失败: %v", userID, itemID, err)
+	}
+	if got != want {
+		t.Errorf("用户 %d 的物品 %d 数量 = %d, want %d", userID, itemID, got, want)
+	}
+}
+
+func assertOfferStatus(t *testing.T, offerID uint, want string) {
+	t.Helper()
+	offer, err := GetTradeOffer(offerID)
+	if err != nil {
+		t.Fatalf("查询交易 %d 失败: %v", offerID, err)
+	}
+	if offer.Status != want {
+		t.Errorf("交易状态 = %s, want %s", offer.Status, want)
+	}
+}
+
+func TestRespondTradeOffer(t *testing.T) {
+	setupTestDB(t)
+	now := time.Now()
+
+	t.Run("发起人数量不足时不转移", func(t *testing.T) {
+		sender, receiver := createTradeTestUser(t), createTradeTestUser(t)
+		item := createTradeTestItem(t, true, 0)
+		giveTradeTestItem(t, sender.ID, item.ID, 1)
+		offer := createTradeTestOffer(t, sender.ID, receiver.ID, now, TradeOfferItem{ItemID: item.ID, Quantity: 2, Side: TradeSideOffer})
+
+		if _, err := RespondTradeOffer(offer.ID, receiver.ID, true, now); !errors.Is(err, ErrInsufficientItems) {
+			t.Fatalf("err = %v, want %v", err, ErrInsufficientItems)
+		}
+		assertQuantity(t, sender.ID, item.ID, 1)
+		assertQuantity(t, receiver.ID, item.ID, 0)
+		assertOfferStatus(t, offer.ID, TradeStatusPending)
+	})
+
+	t.Run("物品在发起后改为不可交易", func(t *testing.T) {
+		sender, receiver := createTradeTestUser(t), createTradeTestUser(t)
+		item := createTradeTestItem(t, true, 0)
+		giveTradeTestItem(t, sender.ID, item.ID, 1)
+		offer := createTradeTestOffer(t, sender.ID, receiver.ID, now, TradeOfferItem{ItemID: item.ID, Quantity: 1, Side: TradeSideOffer})
+		if err := DB.Model(item).Update("tradable", false).Error; err != nil {
+			t.Fatalf("更新物品失败: %v", err)
+		}
+
+		if _, err := RespondTradeOffer(offer.ID, receiver.ID, true, now); !errors.Is(err, ErrItemNotTradable) {
+			t.Fatalf("err = %v, want %v", err, ErrItemNotTradable)
+		}
+		assertQuantity(t, sender.ID, item.ID, 1)
+		assertQuantity(t, receiver.ID, item.ID, 0)
+	})
+
+	t.Run("超过堆叠上限时整笔交易回滚", func(t *testing.T) {
+		sender, receiver := createTradeTestUser(t), createTradeTestUser(t)
+		offered := createTradeTestItem(t, true, 0)
+		requested := createTradeTestItem(t, true, 1)
+		giveTradeTestItem(t, sender.ID, offered.ID, 2)
+		giveTradeTestItem(t, sender.ID, requested.ID, 1)
+		giveTradeTestItem(t, receiver.ID, requested.ID, 1)
+		// 发起人已持有 1 个上限为 1 的物品，索要的那一个无法入账，已转出的物品也不能留在接收人那里
+		offer := createTradeTestOffer(t, sender.ID, receiver.ID, now,
+			TradeOfferItem{ItemID: offered.ID, Quantity: 2, Side: TradeSideOffer},
+			TradeOfferItem{ItemID: requested.ID, Quantity: 1, Side: TradeSideRequest},
+		)
+
+		if _, err := RespondTradeOffer(offer.ID, receiver.ID, true, now); !errors.Is(err, ErrStackLimit) {
+			t.Fatalf("err = %v, want %v", err, ErrStackLimit)
+		}
+		assertQuantity(t, sender.ID, offered.ID, 2)
+		assertQuantity(t, receiver.ID, offered.ID, 0)
+		assertQuantity(t, sender.ID, requested.ID, 1)
+		assertQuantity(t, receiver.ID, requested.ID, 1)
+		assertOfferStatus(t, offer.ID, TradeStatusPending)
+
+		var ledgers int64
+		DB.Model(&ItemLedger{}).Where("source = ? AND source_ref = ?", "trade", fmt.Sprintf("offer:%d", offer.ID)).Count(&ledgers)
+		if ledgers != 0 {
+			t.Errorf("回滚后仍有 %d 条交易流水", ledgers)
+		}
+	})
+
+	t.Run("重复接受只转移一次", func(t *testing.T) {
+		sender, receiver := createTradeTestUser(t), createTradeTestUser(t)
+		item := createTradeTestItem(t, true, 0)
+		giveTradeTestItem(t, sender.ID, item.ID, 3)
+		offer := createTradeTestOffer(t, sender.ID, receiver.ID, now, TradeOfferItem{ItemID: item.ID, Quantity: 2, Side: TradeSideOffer})
+
+		if _, err := RespondTradeOffer(offer.ID, receiver.ID, true, now); err != nil {
+			t.Fatalf("第一次接受失败: %v", err)
+		}
+		if _, err := RespondTradeOffer(offer.ID, receiver.ID, true, now); !errors.Is(err, ErrTradeNotPending) {
+			t.Fatalf("第二次接受 err = %v, want %v", err, ErrTradeNotPending)
+		}
+		assertQuantity(t, sender.ID, item.ID, 1)
+		assertQuantity(t, receiver.ID, item.ID, 2)
+		assertOfferStatus(t, offer.ID, TradeStatusAccepted)
+	})
+}
@@ -67,6 +67,21 @@ func GetUserHealthStates(userID uint, startTime, endTime time.Time) ([]UserHealt
 	return records, nil
 }
 
+// 获取用户的所有健康状态记录
+func GetAllUserHealthStates(userID uint) ([]UserHealthState, error) {
+	var records []UserHealthState
+
+	result := DB.Where("user_id = ?", userID).
+		Order("record_time DESC").
+		Find(&records)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return records, nil
+}
+
 // 根据ID获取用户健康状态记录
 func GetUserHealthStateByID(recordID uint) (*UserHealthState, error) {
 	var record UserHealthState
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserIdentity 用户关联的第三方登录身份
+type UserIdentity struct {
+	gorm.Model
+	UserID      uint      `json:"user_id" gorm:"index;not null"`                                     // 关联的用户ID
+	Provider    string    `json:"provider" gorm:"size:50;not null;uniqueIndex:idx_provider_subject"` // 身份提供方，如 wechat_mp、oidc
+	Subject     string    `json:"subject" gorm:"size:191;not null;uniqueIndex:idx_provider_subject"` // 提供方内唯一的用户标识
+	UnionID     string    `json:"union_id,omitempty" gorm:"size:191;index"`                          // 微信 unionid（可选）
+	Email       string    `json:"email,omitempty" gorm:"size:191"`                                   // 提供方返回的邮箱
+	LastLoginAt time.Time `json:"last_login_at"`                                                     // 最近一次通过该身份登录的时间
+}
+
+// GetUserIdentity 根据提供方和标识查找第三方身份
+func GetUserIdentity(provider, subject string) (*UserIdentity, error) {
+	var identity UserIdentity
+	err := DB.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// GetUserIdentities 获取用户关联的所有第三方身份
+func GetUserIdentities(userID uint) ([]UserIdentity, error) {
+	var identities []UserIdentity
+	err := DB.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error
+	return identities, err
+}
+
+// CreateUserIdentity 创建第三方身份关联
+func CreateUserIdentity(identity *UserIdentity) error {
+	return DB.Create(identity).Error
+}
+
+// TouchUserIdentity 更新第三方身份的最近登录时间
+func TouchUserIdentity(identity *UserIdentity) error {
+	identity.LastLoginAt = time.Now()
+	return DB.Model(identity).Update("last_login_at", identity.LastLoginAt).Error
+}
+
+// DeleteUserIdentity 解除用户与某个提供方的关联
+func DeleteUserIdentity(userID uint, provider string) error {
+	return DB.Unscoped().Where("user_id = ? AND provider = ?", userID, provider).
+		Delete(&UserIdentity{}).Error
+}
@@ -14,12 +14,18 @@ type UserItem struct {
 	Quantity     int       `json:"quantity" gorm:"not null;default:1"` // 数量
 	ObtainedAt   time.Time `json:"obtained_at" gorm:"not null"`        // 获得时间
 	ObtainedFrom string    `json:"obtained_from" gorm:"size:100"`      // 具体获得方式
+	Showcased    bool      `json:"showcased" gorm:"default:false"`     // 是否在个人主页公开展示
 	Item         Item      `json:"item" gorm:"foreignKey:ItemID"`      // 关联物品信息
 }
 
-// CreateUserItem 创建新的用户物品记录
-func CreateUserItem(userItem *UserItem) error {
-	return DB.Create(userItem).Error
+// ShowcaseItem 公开展示的物品信息（不包含数量、来源等私有数据）
+type ShowcaseItem struct {
+	ItemID      uint      `json:"item_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	IconURL     string    `json:"icon_url"`
+	ImageURL    string    `json:"image_url"`
+	ObtainedAt  time.Time `json:"obtained_at"`
 }
 
 // GetUserItems 获取用户的所有物品（根据物品和获得来源聚合数量）
@@ -40,7 +46,7 @@ func GetUserItems(userID uint) ([]map[string]interface{}, error) {
 			SUM(user_items.quantity) as total_quantity
 		`).
 		Joins("LEFT JOIN items ON items.id = user_items.item_id").
-		Where("user_items.user_id = ? AND user_items.deleted_at IS NULL", userID).
+		Where("user_items.user_id = ? AND user_items.quantity > 0 AND user_items.deleted_at IS NULL", userID).
 		Group("items.id, user_items.obtained_from").
 		Order("items.name, user_items.obtained_from").
 		Scan(&results).Error
@@ -60,24 +66,11 @@ func GetUserItemDetails(userID, itemID uint) (*UserItem, error) {
 	return &userItem, nil
 }
 
-// UpdateUserItemQuantity 更新用户物品数量
-func UpdateUserItemQuantity(userID, itemID uint, quantity int) error {
-	return DB.Model(&UserItem{}).
-		Where("user_id = ? AND item_id = ?", userID, itemID).
-		Update("quantity", quantity).Error
-}
-
-// DeleteUserItem 删除用户物品
-func DeleteUserItem(userID, itemID uint) error {
-	return DB.Where("user_id = ? AND item_id = ?", userID, itemID).
-		Delete(&UserItem{}).Error
-}
-
 // HasUserItem 检查用户是否拥有特定物品
 func HasUserItem(userID, itemID uint) (bool, error) {
 	var count int64
 	err := DB.Model(&UserItem{}).
-		Where("user_id = ? AND item_id = ?", userID, itemID).
+		Where("user_id = ? AND item_id = ? AND quantity > 0", userID, itemID).
 		Count(&count).Error
 	return count > 0, err
 }
@@ -92,6 +85,16 @@ func GetUserItemsBySource(userID uint, source string) ([]UserItem, error) {
 	return userItems, err
 }
 
+// GetUserItemQuantity 获取用户持有某个物品的总数量
+func GetUserItemQuantity(userID, itemID uint) (int, error) {
+	var total int
+	err := DB.Model(&UserItem{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("user_id = ? AND item_id = ?", userID, itemID).
+		Scan(&total).Error
+	return total, err
+}
+
 // GetUserItemCount 获取用户物品总数
 func GetUserItemCount(userID uint) (int64, error) {
 	var count int64
@@ -100,3 +103,55 @@ func GetUserItemCount(userID uint) (int64, error) {
 		Count(&count).Error
 	return count, err
 }
+
+// SetUserItemShowcased 设置用户物品是否公开展示
+func SetUserItemShowcased(userID, itemID uint, showcased bool) error {
+	return DB.Model(&UserItem{}).
+		Where("user_id = ? AND item_id = ?", userID, itemID).
+		Update("showcased", showcased).Error
+}
+
+// GetUserShowcaseItems 获取用户选择公开展示的物品
+func GetUserShowcaseItems(userID uint) ([]ShowcaseItem, error) {
+	var items []ShowcaseItem
+	err := DB.Table("user_items").
+		Select("items.id AS item_id, items.name, items.description, items.icon_url, items.image_url, MIN(user_items.obtained_at) AS obtained_at").
+		Joins("JOIN items ON items.id = user_items.item_id AND items.deleted_at IS NULL").
+		Where("user_items.user_id = ? AND user_items.showcased = ? AND user_items.quantity > 0 AND user_items.deleted_at IS NULL", userID, true).
+		Group("items.id").
+		Order("obtained_at").
+		Scan(&items).Error
+	return items, err
+}
+
+// GetUserItemIDs 获取用户已拥有的物品ID
+func GetUserItemIDs(userID uint) ([]uint, error) {
+	var itemIDs []uint
+	err := DB.Model(&UserItem{}).
+		Where("user_id = ? AND quantity > 0", userID).
+		Distinct().
+		Pluck("item_id", &itemIDs).Error
+	return itemIDs, err
+}
+
+// GetUserItemObtainedTimes 获取用户每个物品最早的获得时间，按物品ID索引
+func GetUserItemObtainedTimes(userID uint) (map[uint]time.Time, error) {
+	var rows []struct {
+		ItemID     uint
+		ObtainedAt time.Time
+	}
+	err := DB.Model(&UserItem{}).
+		Select("item_id, MIN(obtained_at) AS obtained_at").
+		Where("user_id = ?", userID).
+		Group("item_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	times := make(map[uint]time.Time, len(rows))
+	for _, row := range rows {
+		times[row.ItemID] = row.ObtainedAt
+	}
+	return times, nil
+}
@@ -0,0 +1,220 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 性别
+const (
+	SexMale   = "male"
+	SexFemale = "female"
+	SexOther  = "other"
+)
+
+// 活动水平
+const (
+	ActivitySedentary  = "sedentary"   // 久坐
+	ActivityLight      = "light"       // 轻度活动
+	ActivityModerate   = "moderate"    // 中度活动
+	ActivityActive     = "active"      // 高度活动
+	ActivityVeryActive = "very_active" // 极高强度活动
+)
+
+// 单位制
+const (
+	UnitMetric   = "metric"
+	UnitImperial = "imperial"
+)
+
+// 默认时区
+const DefaultTimezone = "Asia/Shanghai"
+
+// activityFactors 活动水平对应的能量消耗系数
+var activityFactors = map[string]float64{
+	ActivitySedentary:  1.2,
+	ActivityLight:      1.375,
+	ActivityModerate:   1.55,
+	ActivityActive:     1.725,
+	ActivityVeryActive: 1.9,
+}
+
+// DietaryPreferences 支持的饮食偏好
+var DietaryPreferences = map[string]string{
+	"vegetarian":   "素食",
+	"vegan":        "纯素",
+	"halal":        "清真",
+	"kosher":       "犹太洁食",
+	"gluten_free":  "无麸质",
+	"lactose_free": "无乳糖",
+	"low_sodium":   "低钠",
+	"low_sugar":    "低糖",
+}
+
+// UserProfile 用户资料与偏好
+type UserProfile struct {
+	gorm.Model
+	UserID             uint       `json:"user_id" gorm:"uniqueIndex;not null"`                  // 用户ID
+	BirthDate          *time.Time `json:"birth_date"`                                           // 出生日期
+	Sex                string     `json:"sex" gorm:"size:10"`                                   // 性别：male/female/other
+	ActivityLevel      string     `json:"activity_level" gorm:"size:20;default:sedentary"`      // 活动水平
+	DietaryPreferences []string   `json:"dietary_preferences" gorm:"type:text;serializer:json"` // 饮食偏好，如 vegetarian、halal
+	Allergies          []string   `json:"allergies" gorm:"type:text;serializer:json"`           // 过敏源
+	Timezone           string     `json:"timezone" gorm:"size:64;default:Asia/Shanghai"`        // 时区（IANA名称）
+	UnitSystem         string     `json:"unit_system" gorm:"size:10;default:metric"`            // 单位制：metric/imperial
+	AvatarURL          string     `json:"avatar_url" gorm:"size:255"`                           // 头像
+
+	// 其他用户查看各类内容的可见范围，见 Visibilities，饮食和打卡记录只能使用 DietVisibilities
+	FoodRecordVisibility string `json:"food_record_visibility" gorm:"size:20;default:private"` // 饮食记录
+	CheckInVisibility    string `json:"check_in_visibility" gorm:"size:20;default:friends"`    // 打卡记录
+	ShowcaseVisibility   string `json:"showcase_visibility" gorm:"size:20;default:public"`     // 物品展示
+}
+
+// NewDefaultUserProfile 返回带默认值的用户资料（尚未保存）
+func NewDefaultUserProfile(userID uint) *UserProfile {
+	return &UserProfile{
+		UserID:             userID,
+		ActivityLevel:      ActivitySedentary,
+		DietaryPreferences: []string{},
+		Allergies:          []string{},
+		Timezone:           DefaultTimezone,
+		UnitSystem:         UnitMetric,
+
+		FoodRecordVisibility: VisibilityPrivate,
+		CheckInVisibility:    VisibilityFriends,
+		ShowcaseVisibility:   VisibilityPublic,
+	}
+}
+
+// GetUserProfile 获取用户资料，不存在时返回默认资料
+func GetUserProfile(userID uint) (*UserProfile, error) {
+	var profile UserProfile
+	err := DB.Where("user_id = ?", userID).First(&profile).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return NewDefaultUserProfile(userID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// SaveUserProfile 保存用户资料（不存在则创建）
+func SaveUserProfile(profile *UserProfile) error {
+	return DB.Save(profile).Error
+}
+
+// Location 返回用户时区，无效时回退到默认时区
+func (p *UserProfile) Location() *time.Location {
+	if p != nil && p.Timezone != "" {
+		if loc, err := time.LoadLocation(p.Timezone); err == nil {
+			return loc
+		}
+	}
+	loc, err := time.LoadLocation(DefaultTimezone)
+	if err != nil {
+		return time.FixedZone("CST", 8*3600)
+	}
+	return loc
+}
+
+// GetUserLocation 获取用户配置的时区，读取失败时回退到默认时区
+func GetUserLocation(userID uint) *time.Location {
+	profile, err := GetUserProfile(userID)
+	if err != nil {
+		return (*UserProfile)(nil).Location()
+	}
+	return profile.Location()
+}
+
+// Age 计算用户在指定时间的周岁年龄，未设置出生日期时返回0
+func (p *UserProfile) Age(now time.Time) int {
+	if p == nil || p.BirthDate == nil {
+		return 0
+	}
+	birth := p.BirthDate.In(now.Location())
+	age := now.Year() - birth.Year()
+	if now.Month() < birth.Month() || (now.Month() == birth.Month() && now.Day() < birth.Day()) {
+		age--
+	}
+	return age
+}
+
+// NutritionTarget 每日营养摄入目标
+type NutritionTarget struct {
+	Calories      float64 `json:"calories"`      // 热量（千卡）
+	Protein       float64 `json:"protein"`       // 蛋白质（克）
+	TotalFat      float64 `json:"total_fat"`     // 总脂肪（克）
+	Carbohydrates float64 `json:"carbohydrates"` // 碳水化合物（克）
+	Fiber         float64 `json:"fiber"`         // 膳食纤维（克）
+	Sugar         float64 `json:"sugar"`         // 添加糖上限（克）
+	Sodium        float64 `json:"sodium"`        // 钠上限（mg）
+	Estimated     bool    `json:"estimated"`     // 缺少身高体重等数据时为 true，使用通用默认值
+}
+
+// GetUserNutritionTargets 读取用户资料与最新健康状态并计算营养目标
+func GetUserNutritionTargets(userID uint) (*NutritionTarget, error) {
+	profile, err := GetUserProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := GetLatestUserHealthState(userID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		state = nil
+	}
+
+	return CalculateNutritionTargets(profile, state, time.Now().In(profile.Location())), nil
+}
+
+// CalculateNutritionTargets 根据用户资料和最新健康状态计算每日营养目标
+// 热量使用 Mifflin-St Jeor 公式估算基础代谢，再乘以活动系数；
+// 三大营养素按 20% 蛋白质、30% 脂肪、50% 碳水分配
+func CalculateNutritionTargets(profile *UserProfile, state *UserHealthState, now time.Time) *NutritionTarget {
+	target := &NutritionTarget{
+		Calories: 2000,
+		Fiber:    25,
+		Sodium:   2000,
+	}
+
+	age := profile.Age(now)
+	if state != nil && state.Weight > 0 && state.Height > 0 && age > 0 && profile.Sex != "" {
+		bmr := 10*state.Weight + 6.25*state.Height - 5*float64(age)
+		switch profile.Sex {
+		case SexMale:
+			bmr += 5
+		case SexFemale:
+			bmr -= 161
+		default:
+			bmr -= 78
+		}
+		factor, ok := activityFactors[profile.ActivityLevel]
+		if !ok {
+			factor = activityFactors[ActivitySedentary]
+		}
+		target.Calories = bmr * factor
+	} else {
+		target.Estimated = true
+	}
+
+	target.Protein = target.Calories * 0.20 / 4
+	target.TotalFat = target.Calories * 0.30 / 9
+	target.Carbohydrates = target.Calories * 0.50 / 4
+	target.Sugar = target.Calories * 0.10 / 4
+
+	for _, pref := range profile.DietaryPreferences {
+		if pref == "low_sodium" {
+			target.Sodium = 1500
+		}
+		if pref == "low_sugar" {
+			target.Sugar = target.Calories * 0.05 / 4
+		}
+	}
+
+	return target
+}
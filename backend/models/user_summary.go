@@ -0,0 +1,30 @@
+package models
+
+// UserSummary 对其他用户展示的公开信息
+type UserSummary struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// GetUserSummaries 批量获取用户的公开信息，返回以用户ID为键的映射，不存在的用户不会出现在结果中
+func GetUserSummaries(userIDs []uint) (map[uint]*UserSummary, error) {
+	summaries := make(map[uint]*UserSummary, len(userIDs))
+	if len(userIDs) == 0 {
+		return summaries, nil
+	}
+
+	var rows []UserSummary
+	err := DB.Table("users").
+		Select("users.id, users.name, COALESCE(user_profiles.avatar_url, '') AS avatar_url").
+		Joins("LEFT JOIN user_profiles ON user_profiles.user_id = users.id AND user_profiles.deleted_at IS NULL").
+		Where("users.id IN ? AND users.deleted_at IS NULL", userIDs).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		summaries[rows[i].ID] = &rows[i]
+	}
+	return summaries, nil
+}
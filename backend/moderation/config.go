@@ -0,0 +1,35 @@
+package moderation
+
+import (
+	"log"
+	"os"
+)
+
+// ConfigureFromEnv 根据环境变量选择内容分类器，关键词列表始终生效
+//
+//	MODERATION_CLASSIFIER=openai   使用 OpenAI 内容审核接口（OPENAI_API_KEY，模型可通过 MODERATION_OPENAI_MODEL 指定）
+//	MODERATION_CLASSIFIER=fake     本地调试用的假分类器（仅 debug 模式生效）
+//	未设置时只使用关键词列表
+func ConfigureFromEnv(debug bool) {
+	switch name := os.Getenv("MODERATION_CLASSIFIER"); name {
+	case "":
+		log.Println("未配置内容分类器，只使用关键词审核")
+	case "openai":
+		apiKey := os.Getenv("OPENAI_API_KEY")
+		if apiKey == "" {
+			log.Println("警告：OPENAI_API_KEY 未设置，未启用 OpenAI 内容审核")
+			return
+		}
+		SetClassifier(NewOpenAIClassifier(apiKey, os.Getenv("MODERATION_OPENAI_MODEL")))
+		log.Println("已启用 OpenAI 内容审核")
+	case "fake":
+		if !debug {
+			log.Println("警告：fake 内容分类器仅在 debug 模式下生效")
+			return
+		}
+		SetClassifier(FakeClassifier{})
+		log.Println("警告：已启用 fake 内容分类器，仅用于本地调试")
+	default:
+		log.Printf("警告：不支持的内容分类器 %s，只使用关键词审核", name)
+	}
+}
@@ -0,0 +1,50 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FakeClassifier 本地调试和测试用的分类器，不访问任何外部服务
+// 文字或图片文件内容包含 "[fake:reject]" 时拒绝，包含 "[fake:review]" 时需要复核，其余直接通过
+type FakeClassifier struct{}
+
+// 触发 FakeClassifier 的标记
+const (
+	FakeRejectMarker = "[fake:reject]"
+	FakeReviewMarker = "[fake:review]"
+)
+
+// Name 返回分类器名称
+func (FakeClassifier) Name() string {
+	return "fake"
+}
+
+// ClassifyText 根据文字中的标记返回结论
+func (FakeClassifier) ClassifyText(_ context.Context, text string) (*Result, error) {
+	switch {
+	case strings.Contains(text, FakeRejectMarker):
+		return &Result{Verdict: VerdictReject, Reasons: []string{"文字包含拒绝标记"}}, nil
+	case strings.Contains(text, FakeReviewMarker):
+		return &Result{Verdict: VerdictReview, Reasons: []string{"文字包含复核标记"}}, nil
+	}
+	return Allowed(), nil
+}
+
+// ClassifyImage 根据图片文件内容中的标记返回结论，可在图片末尾追加标记来测试
+func (FakeClassifier) ClassifyImage(_ context.Context, path string) (*Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取图片失败: %v", err)
+	}
+	switch {
+	case bytes.Contains(data, []byte(FakeRejectMarker)):
+		return &Result{Verdict: VerdictReject, Reasons: []string{"图片包含拒绝标记"}}, nil
+	case bytes.Contains(data, []byte(FakeReviewMarker)):
+		return &Result{Verdict: VerdictReview, Reasons: []string{"图片包含复核标记"}}, nil
+	}
+	return Allowed(), nil
+}
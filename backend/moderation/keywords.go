@@ -0,0 +1,86 @@
+package moderation
+
+import (
+	"backend/models"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// 关键词列表缓存，管理员修改关键词后调用 ReloadKeywords 使其失效
+var (
+	keywordsMu     sync.RWMutex
+	keywords       []models.ModerationKeyword
+	keywordsLoaded bool
+)
+
+// IsValidKeywordAction 检查关键词的处理方式是否有效
+func IsValidKeywordAction(action string) bool {
+	return action == VerdictReview || action == VerdictReject
+}
+
+// ReloadKeywords 使关键词缓存失效，下次审核时重新从数据库加载
+func ReloadKeywords() {
+	keywordsMu.Lock()
+	defer keywordsMu.Unlock()
+	keywords = nil
+	keywordsLoaded = false
+}
+
+// loadKeywords 返回缓存的关键词列表，未加载时从数据库加载
+func loadKeywords() ([]models.ModerationKeyword, error) {
+	keywordsMu.RLock()
+	if keywordsLoaded {
+		defer keywordsMu.RUnlock()
+		return keywords, nil
+	}
+	keywordsMu.RUnlock()
+
+	keywordsMu.Lock()
+	defer keywordsMu.Unlock()
+	if keywordsLoaded {
+		return keywords, nil
+	}
+	loaded, err := models.GetModerationKeywords()
+	if err != nil {
+		return nil, err
+	}
+	for i := range loaded {
+		loaded[i].Word = normalize(loaded[i].Word)
+	}
+	keywords = loaded
+	keywordsLoaded = true
+	return keywords, nil
+}
+
+// matchKeywords 检查文字是否包含关键词，忽略大小写以及空白和标点，避免用空格、符号隔开绕过
+func matchKeywords(text string) (*Result, error) {
+	list, err := loadKeywords()
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := normalize(text)
+	result := Allowed()
+	for _, keyword := range list {
+		if keyword.Word == "" || !strings.Contains(normalized, keyword.Word) {
+			continue
+		}
+		result.Merge(&Result{
+			Verdict: keyword.Action,
+			Reasons: []string{fmt.Sprintf("命中关键词：%s", keyword.Word)},
+		})
+	}
+	return result, nil
+}
+
+// normalize 转为小写并去掉空白和标点
+func normalize(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, text)
+}
@@ -0,0 +1,163 @@
+// Package moderation 审核用户发布的文字和图片：先匹配管理员维护的关键词列表，再交给可替换的分类器判断
+// 需要人工复核的内容进入审核队列，由管理员通过或下架
+package moderation
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 审核结论
+const (
+	VerdictAllow  = "allow"  // 直接发布
+	VerdictReview = "review" // 发布后只有作者可见，等待人工复核
+	VerdictReject = "reject" // 拒绝发布
+)
+
+// checkTimeout 单次调用分类器的超时时间，超时按需要复核处理
+const checkTimeout = 10 * time.Second
+
+var severity = map[string]int{
+	VerdictAllow:  0,
+	VerdictReview: 1,
+	VerdictReject: 2,
+}
+
+// Result 审核结果
+type Result struct {
+	Verdict string   `json:"verdict"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// Allowed 返回直接发布的审核结果
+func Allowed() *Result {
+	return &Result{Verdict: VerdictAllow}
+}
+
+// Merge 合并另一个审核结果，结论取更严格的一方，原因全部保留
+func (r *Result) Merge(other *Result) {
+	if other == nil {
+		return
+	}
+	if severity[other.Verdict] > severity[r.Verdict] {
+		r.Verdict = other.Verdict
+	}
+	r.Reasons = append(r.Reasons, other.Reasons...)
+}
+
+// Rejected 是否拒绝发布
+func (r *Result) Rejected() bool {
+	return r.Verdict == VerdictReject
+}
+
+// NeedsReview 是否需要人工复核
+func (r *Result) NeedsReview() bool {
+	return r.Verdict == VerdictReview
+}
+
+// Status 返回内容发布时应使用的审核状态
+func (r *Result) Status() string {
+	if r.NeedsReview() {
+		return models.ModerationStatusPending
+	}
+	return models.ModerationStatusApproved
+}
+
+// Classifier 基于模型的内容分类器
+type Classifier interface {
+	// Name 返回分类器名称，用于记录审核原因
+	Name() string
+	// ClassifyText 判断一段文字
+	ClassifyText(ctx context.Context, text string) (*Result, error)
+	// ClassifyImage 判断本地保存的图片
+	ClassifyImage(ctx context.Context, path string) (*Result, error)
+}
+
+var (
+	classifierMu sync.RWMutex
+	classifier   Classifier
+)
+
+// SetClassifier 设置使用的分类器，传 nil 表示只使用关键词列表
+func SetClassifier(c Classifier) {
+	classifierMu.Lock()
+	defer classifierMu.Unlock()
+	classifier = c
+}
+
+// currentClassifier 返回当前使用的分类器，未设置时为 nil
+func currentClassifier() Classifier {
+	classifierMu.RLock()
+	defer classifierMu.RUnlock()
+	return classifier
+}
+
+// CheckText 审核文字内容，多段文字合并后一起审核，空文字直接通过
+// 关键词列表或分类器出错时按需要复核处理，不会阻止发布
+func CheckText(ctx context.Context, texts ...string) *Result {
+	parts := make([]string, 0, len(texts))
+	for _, text := range texts {
+		if text = strings.TrimSpace(text); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	result := Allowed()
+	if len(parts) == 0 {
+		return result
+	}
+	text := strings.Join(parts, "\n")
+
+	matched, err := matchKeywords(text)
+	if err != nil {
+		fmt.Printf("加载审核关键词失败: %v\n", err)
+		result.Merge(&Result{Verdict: VerdictReview, Reasons: []string{"关键词检查失败"}})
+	} else {
+		result.Merge(matched)
+	}
+	if result.Rejected() {
+		return result
+	}
+
+	if c := currentClassifier(); c != nil {
+		ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+		defer cancel()
+		classified, err := c.ClassifyText(ctx, text)
+		result.Merge(classifierResult(c, classified, err))
+	}
+	return result
+}
+
+// CheckImage 审核本地保存的图片，未设置分类器时直接通过
+func CheckImage(ctx context.Context, path string) *Result {
+	result := Allowed()
+	c := currentClassifier()
+	if c == nil {
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	classified, err := c.ClassifyImage(ctx, path)
+	result.Merge(classifierResult(c, classified, err))
+	return result
+}
+
+// classifierResult 为分类器结果的原因加上分类器名称，出错时按需要复核处理
+func classifierResult(c Classifier, result *Result, err error) *Result {
+	if err != nil {
+		fmt.Printf("内容分类器 %s 调用失败: %v\n", c.Name(), err)
+		return &Result{Verdict: VerdictReview, Reasons: []string{c.Name() + ": 分类器调用失败"}}
+	}
+	if result == nil {
+		return nil
+	}
+	reasons := make([]string, len(result.Reasons))
+	for i, reason := range result.Reasons {
+		reasons[i] = c.Name() + ": " + reason
+	}
+	return &Result{Verdict: result.Verdict, Reasons: reasons}
+}
@@ -0,0 +1,183 @@
+package moderation
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setKeywords 预置关键词缓存，避免测试访问数据库，测试结束后清空缓存
+func setKeywords(t *testing.T, list ...models.ModerationKeyword) {
+	t.Helper()
+	for i := range list {
+		list[i].Word = normalize(list[i].Word)
+	}
+	keywordsMu.Lock()
+	keywords = list
+	keywordsLoaded = true
+	keywordsMu.Unlock()
+	t.Cleanup(ReloadKeywords)
+}
+
+// setClassifier 设置测试用的分类器，测试结束后恢复为只使用关键词
+func setClassifier(t *testing.T, c Classifier) {
+	t.Helper()
+	SetClassifier(c)
+	t.Cleanup(func() { SetClassifier(nil) })
+}
+
+// failingClassifier 每次调用都返回错误的分类器
+type failingClassifier struct{}
+
+func (failingClassifier) Name() string { return "failing" }
+
+func (failingClassifier) ClassifyText(context.Context, string) (*Result, error) {
+	return nil, errors.New("服务不可用")
+}
+
+func (failingClassifier) ClassifyImage(context.Context, string) (*Result, error) {
+	return nil, errors.New("服务不可用")
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"大小写", "SpAm", "spam"},
+		{"空白", "s p\ta\nm", "spam"},
+		{"标点", "s.p-a_m!", "spam"},
+		{"符号", "s$p+a|m", "spam"},
+		{"全角标点", "广，告。", "广告"},
+		{"中文空格", "广　告", "广告"},
+		{"保留数字", "vx 123", "vx123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalize(tt.in); got != tt.want {
+				t.Errorf("normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchKeywords(t *testing.T) {
+	setKeywords(t,
+		models.ModerationKeyword{Word: "代购", Action: VerdictReview},
+		models.ModerationKeyword{Word: "Free Money", Action: VerdictReject},
+	)
+
+	tests := []struct {
+		name        string
+		text        string
+		wantVerdict string
+		wantReasons []string
+	}{
+		{"未命中", "今天吃了沙拉", VerdictAllow, nil},
+		{"直接命中", "找代购", VerdictReview, []string{"命中关键词：代购"}},
+		{"空格隔开", "找 代 购", VerdictReview, []string{"命中关键词：代购"}},
+		{"标点隔开", "代.购", VerdictReview, []string{"命中关键词：代购"}},
+		{"符号隔开", "代*购", VerdictReview, []string{"命中关键词：代购"}},
+		{"大小写和空格", "FREE money here", VerdictReject, []string{"命中关键词：freemoney"}},
+		{"词内插入符号", "fr-ee mo.ney", VerdictReject, []string{"命中关键词：freemoney"}},
+		{"多个关键词取更严格的", "代购 free money", VerdictReject, []string{"命中关键词：代购", "命中关键词：freemoney"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := matchKeywords(tt.text)
+			if err != nil {
+				t.Fatalf("matchKeywords 返回错误: %v", err)
+			}
+			if result.Verdict != tt.wantVerdict {
+				t.Errorf("verdict = %s, want %s", result.Verdict, tt.wantVerdict)
+			}
+			if !reflect.DeepEqual(result.Reasons, tt.wantReasons) {
+				t.Errorf("reasons = %v, want %v", result.Reasons, tt.wantReasons)
+			}
+		})
+	}
+}
+
+func TestResultMerge(t *testing.T) {
+	tests := []struct {
+		name  string
+		base  string
+		other *Result
+		want  string
+	}{
+		{"nil 不改变结论", VerdictReview, nil, VerdictReview},
+		{"通过+通过", VerdictAllow, &Result{Verdict: VerdictAllow}, VerdictAllow},
+		{"通过+复核", VerdictAllow, &Result{Verdict: VerdictReview}, VerdictReview},
+		{"通过+拒绝", VerdictAllow, &Result{Verdict: VerdictReject}, VerdictReject},
+		{"复核+通过", VerdictReview, &Result{Verdict: VerdictAllow}, VerdictReview},
+		{"复核+拒绝", VerdictReview, &Result{Verdict: VerdictReject}, VerdictReject},
+		{"拒绝+通过", VerdictReject, &Result{Verdict: VerdictAllow}, VerdictReject},
+		{"拒绝+复核", VerdictReject, &Result{Verdict: VerdictReview}, VerdictReject},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &Result{Verdict: tt.base, Reasons: []string{"base"}}
+			result.Merge(tt.other)
+			if result.Verdict != tt.want {
+				t.Errorf("verdict = %s, want %s", result.Verdict, tt.want)
+			}
+			wantReasons := []string{"base"}
+			if tt.other != nil {
+				wantReasons = append(wantReasons, tt.other.Reasons...)
+			}
+			if !reflect.DeepEqual(result.Reasons, wantReasons) {
+				t.Errorf("reasons = %v, want %v", result.Reasons, wantReasons)
+			}
+		})
+	}
+}
+
+func TestCheckText(t *testing.T) {
+	tests := []struct {
+		name        string
+		classifier  Classifier
+		texts       []string
+		wantVerdict string
+		wantReason  string
+	}{
+		{"空文字直接通过", failingClassifier{}, []string{"", "  "}, VerdictAllow, ""},
+		{"无分类器只用关键词", nil, []string{"正常内容"}, VerdictAllow, ""},
+		{"分类器失败按复核处理", failingClassifier{}, []string{"正常内容"}, VerdictReview, "failing: 分类器调用失败"},
+		{"关键词拒绝时不调用分类器", failingClassifier{}, []string{"垃圾广告"}, VerdictReject, "命中关键词：垃圾广告"},
+		{"关键词复核时分类器失败仍为复核", failingClassifier{}, []string{"找代购"}, VerdictReview, "failing: 分类器调用失败"},
+		{"分类器拒绝", FakeClassifier{}, []string{"标题", "正文 " + FakeRejectMarker}, VerdictReject, "fake: 文字包含拒绝标记"},
+		{"分类器复核", FakeClassifier{}, []string{FakeReviewMarker}, VerdictReview, "fake: 文字包含复核标记"},
+		{"分类器通过", FakeClassifier{}, []string{"正常内容"}, VerdictAllow, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setKeywords(t,
+				models.ModerationKeyword{Word: "代购", Action: VerdictReview},
+				models.ModerationKeyword{Word: "垃圾广告", Action: VerdictReject},
+			)
+			setClassifier(t, tt.classifier)
+
+			result := CheckText(context.Background(), tt.texts...)
+			if result.Verdict != tt.wantVerdict {
+				t.Errorf("verdict = %s, want %s (reasons %v)", result.Verdict, tt.wantVerdict, result.Reasons)
+			}
+			if tt.wantReason == "" {
+				if len(result.Reasons) != 0 {
+					t.Errorf("reasons = %v, want none", result.Reasons)
+				}
+				return
+			}
+			if !strings.Contains(strings.Join(result.Reasons, "\n"), tt.wantReason) {
+				t.Errorf("reasons = %v, want to contain %q", result.Reasons, tt.wantReason)
+			}
+		})
+	}
+}
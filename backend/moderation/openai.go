@@ -0,0 +1,116 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+)
+
+// OpenAIClassifier 使用 OpenAI 内容审核接口的分类器
+// 被标记的内容需要人工复核，任一类别得分达到 RejectThreshold 时直接拒绝
+type OpenAIClassifier struct {
+	APIKey          string
+	Model           string
+	Endpoint        string
+	RejectThreshold float64
+	Client          *http.Client
+}
+
+// NewOpenAIClassifier 创建 OpenAI 分类器，model 为空时使用 omni-moderation-latest（支持图片）
+func NewOpenAIClassifier(apiKey, model string) *OpenAIClassifier {
+	if model == "" {
+		model = "omni-moderation-latest"
+	}
+	return &OpenAIClassifier{
+		APIKey:          apiKey,
+		Model:           model,
+		Endpoint:        "https://api.openai.com/v1/moderations",
+		RejectThreshold: 0.9,
+		Client:          &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Name 返回分类器名称
+func (o *OpenAIClassifier) Name() string {
+	return "openai"
+}
+
+// ClassifyText 审核文字
+func (o *OpenAIClassifier) ClassifyText(ctx context.Context, text string) (*Result, error) {
+	return o.classify(ctx, text)
+}
+
+// ClassifyImage 以 data URL 的形式提交图片
+func (o *OpenAIClassifier) ClassifyImage(ctx context.Context, path string) (*Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取图片失败: %v", err)
+	}
+	url := fmt.Sprintf("data:%s;base64,%s", http.DetectContentType(data), base64.StdEncoding.EncodeToString(data))
+	input := []map[string]interface{}{
+		{"type": "image_url", "image_url": map[string]string{"url": url}},
+	}
+	return o.classify(ctx, input)
+}
+
+// classify 调用审核接口并把结果转换为审核结论
+func (o *OpenAIClassifier) classify(ctx context.Context, input interface{}) (*Result, error) {
+	body, err := json.Marshal(map[string]interface{}{"model": o.Model, "input": input})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建审核请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+o.APIKey)
+
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求审核接口失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("审核接口返回状态码 %d", resp.StatusCode)
+	}
+
+	var payload struct {
+		Results []struct {
+			Flagged        bool               `json:"flagged"`
+			Categories     map[string]bool    `json:"categories"`
+			CategoryScores map[string]float64 `json:"category_scores"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("解析审核结果失败: %v", err)
+	}
+
+	result := Allowed()
+	for _, r := range payload.Results {
+		if !r.Flagged {
+			continue
+		}
+		verdict := VerdictReview
+		var reasons []string
+		for category, flagged := range r.Categories {
+			if !flagged {
+				continue
+			}
+			score := r.CategoryScores[category]
+			if score >= o.RejectThreshold {
+				verdict = VerdictReject
+			}
+			reasons = append(reasons, fmt.Sprintf("%s（%.2f）", category, score))
+		}
+		sort.Strings(reasons)
+		result.Merge(&Result{Verdict: verdict, Reasons: reasons})
+	}
+	return result, nil
+}
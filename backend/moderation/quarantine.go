@@ -0,0 +1,65 @@
+package moderation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// QuarantineDir 隔离目录，待审核和已下架内容的图片从 static 移到这里，不再通过 /static 公开访问
+// 文件在隔离目录中保持相同的相对路径，例如 static/posts/a.jpg 对应 quarantine/static/posts/a.jpg
+const QuarantineDir = "quarantine"
+
+// staticPath 将 /static/... 形式的URL转换为本地文件路径，非本地文件返回 false
+func staticPath(url string) (string, bool) {
+	cleaned := filepath.Clean(strings.TrimPrefix(url, "/"))
+	if !strings.HasPrefix(cleaned, "static"+string(filepath.Separator)) {
+		return "", false
+	}
+	return cleaned, true
+}
+
+// QuarantinedPath 返回 static 下的文件在隔离目录中的路径
+func QuarantinedPath(staticPath string) string {
+	return filepath.Join(QuarantineDir, staticPath)
+}
+
+// Quarantine 将图片移到隔离目录，已在隔离目录或不存在的文件会被跳过
+func Quarantine(urls []string) error {
+	for _, url := range urls {
+		path, ok := staticPath(url)
+		if !ok {
+			continue
+		}
+		if err := moveFile(path, QuarantinedPath(path)); err != nil {
+			return fmt.Errorf("隔离图片 %s 失败: %v", url, err)
+		}
+	}
+	return nil
+}
+
+// Restore 将审核通过内容的图片从隔离目录移回 static
+func Restore(urls []string) error {
+	for _, url := range urls {
+		path, ok := staticPath(url)
+		if !ok {
+			continue
+		}
+		if err := moveFile(QuarantinedPath(path), path); err != nil {
+			return fmt.Errorf("恢复图片 %s 失败: %v", url, err)
+		}
+	}
+	return nil
+}
+
+// moveFile 移动文件，源文件不存在时不做任何操作
+func moveFile(from, to string) error {
+	if _, err := os.Stat(from); os.IsNotExist(err) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	return os.Rename(from, to)
+}
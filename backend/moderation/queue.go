@@ -0,0 +1,97 @@
+package moderation
+
+import (
+	"backend/models"
+	"fmt"
+	"time"
+)
+
+// contentNames 内容类型在通知中的名称
+var contentNames = map[string]string{
+	models.ModerationContentPost:        "帖子",
+	models.ModerationContentPostComment: "评论",
+	models.ModerationContentCheckIn:     "打卡",
+}
+
+// Submit 内容需要人工复核时隔离图片并加入审核队列，失败只记录日志
+func Submit(contentType string, contentID, userID uint, excerpt string, images []string, result *Result) {
+	if !result.NeedsReview() {
+		return
+	}
+	if err := Quarantine(images); err != nil {
+		fmt.Printf("隔离 %s %d 的图片失败: %v\n", contentType, contentID, err)
+	}
+	mc := &models.ModerationCase{
+		ContentType: contentType,
+		ContentID:   contentID,
+		UserID:      userID,
+		Excerpt:     excerpt,
+		ImageURLs:   images,
+		Reasons:     result.Reasons,
+		Source:      models.ModerationSourceAuto,
+		Status:      models.ModerationStatusPending,
+	}
+	if err := models.CreateModerationCase(mc); err != nil {
+		fmt.Printf("创建 %s %d 的审核记录失败: %v\n", contentType, contentID, err)
+	}
+}
+
+// Resolve 处理审核队列中的记录，approve 为 true 时恢复公开图片，否则下架内容并通知作者
+func Resolve(caseID uint, approve bool, reviewerID uint, note string) (*models.ModerationCase, error) {
+	status := models.ModerationStatusApproved
+	if !approve {
+		status = models.ModerationStatusRemoved
+	}
+	mc, err := models.ResolveModerationCase(caseID, status, reviewerID, note, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if approve {
+		if err := Restore(mc.ImageURLs); err != nil {
+			fmt.Printf("恢复审核记录 %d 的图片失败: %v\n", mc.ID, err)
+		}
+		return mc, nil
+	}
+	removed(mc)
+	return mc, nil
+}
+
+// TakeDown 管理员直接下架内容，隔离其图片并通知作者
+func TakeDown(contentType string, contentID, reviewerID uint, note string) (*models.ModerationCase, error) {
+	mc, err := models.TakeDownContent(contentType, contentID, reviewerID, note, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	removed(mc)
+	return mc, nil
+}
+
+// removed 隔离已下架内容的图片并通知作者
+func removed(mc *models.ModerationCase) {
+	if err := Quarantine(mc.ImageURLs); err != nil {
+		fmt.Printf("隔离审核记录 %d 的图片失败: %v\n", mc.ID, err)
+	}
+	notifyRemoved(mc)
+}
+
+// notifyRemoved 通知作者内容已被下架，失败只记录日志
+func notifyRemoved(mc *models.ModerationCase) {
+	content := mc.ReviewNote
+	if content == "" {
+		content = "内容违反社区规范"
+	}
+	notification := models.Notification{
+		UserID:  mc.UserID,
+		Type:    models.NotificationTypeContentRemoved,
+		Title:   fmt.Sprintf("你发布的%s已被下架", contentNames[mc.ContentType]),
+		Content: content,
+		Data: map[string]interface{}{
+			"content_type": mc.ContentType,
+			"content_id":   mc.ContentID,
+			"case_id":      mc.ID,
+		},
+	}
+	if err := models.CreateNotifications([]models.Notification{notification}); err != nil {
+		fmt.Printf("保存用户 %d 的下架通知失败: %v\n", mc.UserID, err)
+	}
+}
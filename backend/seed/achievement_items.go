@@ -0,0 +1,138 @@
+package seed
+
+import "backend/models"
+
+// achievementItemsV1 第1版成就物品目录，对应 doc/成就.md，图标和图片启动时由 CopyItemAssets 从 doc/item 复制到 static/items/achievements
+// 已发布的版本不再修改，调整目录请新增版本
+var achievementItemsV1 = []models.Item{
+	{
+		Key:         models.ItemKey("rice-ball-cat-gift"),
+		Name:        "饭团猫的礼物",
+		Description: "哼～总算开始记录了？我等得花儿都谢了！不过能迈出第一步已经很不错了啦～给你这只饭团当见面礼，它会陪你一起记录更多美食的。别误会，我才不是专门准备的礼物呢，只是...刚好做多了一个而已！",
+		Source:      "成就",
+		Rarity:      models.RarityCommon,
+		Category:    models.CategoryCollectible,
+		IconURL:     "/static/items/achievements/rice-ball-cat-gift-icon.png",
+		ImageURL:    "/static/items/achievements/rice-ball-cat-gift-image.png",
+	},
+	{
+		Key:         models.ItemKey("seventh-day-spoon"),
+		Name:        "第七天的饭勺",
+		Description: "哼～累计记录七天就想要奖励？也不是不行啦～毕竟坚持这么久的也不多见，勉为其难奖励你这把闪亮勺子～",
+		Source:      "成就",
+		Rarity:      models.RarityUncommon,
+		Category:    models.CategoryCollectible,
+		IconURL:     "/static/items/achievements/seventh-day-spoon-icon.png",
+		ImageURL:    "/static/items/achievements/seventh-day-spoon-image.png",
+	},
+	{
+		Key:         models.ItemKey("superhero-cape"),
+		Name:        "超人披风",
+		Description: "别以为我不知道你偷懒的日子！不过能坚持满21天，勉强算个小超人吧～我才不是特意做这件披风的呢，刚好多的布料罢了，穿上...其实还挺帅的啦！",
+		Source:      "成就",
+		Rarity:      models.RarityRare,
+		Category:    models.CategoryCollectible,
+		IconURL:     "/static/items/achievements/superhero-cape-icon.png",
+		ImageURL:    "/static/items/achievements/superhero-cape-image.png",
+	},
+	{
+		Key:         models.ItemKey("tumbler-plate"),
+		Name:        "不倒翁餐盘",
+		Description: "呵～居然会中断打卡？弱爆了好吗！不过看你又重新振作的样子，有点像...我奶奶的不倒翁？",
+		Source:      "成就",
+		Rarity:      models.RarityUncommon,
+		Category:    models.CategoryCollectible,
+		IconURL:     "/static/items/achievements/tumbler-plate-icon.png",
+		ImageURL:    "/static/items/achievements/tumbler-plate-image.png",
+	},
+	{
+		Key:         models.ItemKey("sunflower"),
+		Name:        "太阳花",
+		Description: "噫～居然能在9点前起床？你是外星人吗？这朵太阳花送你了，它可是睡眼朦胧中做的，不要嫌弃它造型奇怪哦～对了，早餐记得吃点好的，你那些奇怪料理我都看不下去了！",
+		Source:      "成就",
+		Rarity:      models.RarityCommon,
+		Category:    models.CategoryCollectible,
+		IconURL:     "/static/items/achievements/sunflower-icon.png",
+		ImageURL:    "/static/items/achievements/sunflower-image.png",
+	},
+	{
+		Key:         models.ItemKey("strange-egg"),
+		Name:        "奇怪的鸡蛋",
+		Description: "20克蛋白质？你是要变成肌肉怪物吗？...虽然我也没说这样不好啦！这个蛋白质小人就送你吧，它比你强壮多了，或许能教你两招～",
+		Source:      "成就",
+		Rarity:      models.RarityCommon,
+		Category:    models.CategoryCollectible,
+		IconURL:     "/static/items/achievements/strange-egg-icon.png",
+		ImageURL:    "/static/items/achievements/strange-egg-image.png",
+	},
+	{
+		Key:         models.ItemKey("just-right-bowl"),
+		Name:        "大小适中的碗",
+		Description: "哎哟喂～看不出来你还挺会吃的嘛！这顿饭营养均衡、热量刚好，碗里放的居然都是人吃的东西！给你这个特制笑脸碗，别搞错了，我才不是夸你有品位，只是...刚好合格而已啦！",
+		Source:      "成就",
+		Rarity:      models.RarityUncommon,
+		Category:    models.CategoryCollectible,
+		IconURL:     "/static/items/achievements/just-right-bowl-icon.png",
+		ImageURL:    "/static/items/achievements/just-right-bowl-image.png",
+	},
+	{
+		Key:         models.ItemKey("sugar-molecules"),
+		Name:        "几个糖分子",
+		Description: "什么？你居然能抵抗糖分的诱惑？啧啧，这不像正常人类能做到的事～给你这枚勇士勋章吧，从糖分大军中逃脱的英雄！...其实我也想尝试低糖饮食啦，只是暂时还没做好准备而已！",
+		Source:      "成就",
+		Rarity:      models.RarityCommon,
+		Category:    models.CategoryCollectible,
+		IconURL:     "/static/items/achievements/sugar-molecules-icon.png",
+		ImageURL:    "/static/items/achievements/sugar-molecules-image.png",
+	},
+	{
+		Key:         models.ItemKey("food-journal"),
+		Name:        "美食记录本",
+		Description: "哟～功课做得挺勤快嘛！连续三天记录这么多餐，你是要成为美食作家吗？给，这本日记本送你了～记得把你那些黑暗料理也好好记上，或许未来能出本《奇怪食谱大全》呢～",
+		Source:      "成就",
+		Rarity:      models.RarityUncommon,
+		Category:    models.CategoryCollectible,
+		IconURL:     "/static/items/achievements/food-journal-icon.png",
+		ImageURL:    "/static/items/achievements/food-journal-image.png",
+	},
+	{
+		Key:         models.ItemKey("balanced-scale"),
+		Name:        "正常的天秤",
+		Description: "哎哟，看来你的天秤座属性暴露无遗啊～蛋白质、脂肪、碳水三兄弟被你安排得明明白白。给你这个天秤，我才不是因为你做得好才给的呢，只是...勉强算及格了吧！下次争取七次都平衡如何？",
+		Source:      "成就",
+		Rarity:      models.RarityRare,
+		Category:    models.CategoryCollectible,
+		IconURL:     "/static/items/achievements/balanced-scale-icon.png",
+		ImageURL:    "/static/items/achievements/balanced-scale-image.png",
+	},
+	{
+		Key:         models.ItemKey("woodpecker-alarm-clock"),
+		Name:        "啄木鸟闹钟",
+		Description: "不得不说，你这种能连续四天不赖床的生物还真是稀有～这枚勋章是给早起鸟专门定制的！喂，别误会，我这是在表扬你啦...",
+		Source:      "成就",
+		Rarity:      models.RarityRare,
+		Category:    models.CategoryCollectible,
+		IconURL:     "/static/items/achievements/woodpecker-alarm-clock-icon.png",
+		ImageURL:    "/static/items/achievements/woodpecker-alarm-clock-image.png",
+	},
+	{
+		Key:         models.ItemKey("sleepy-night-light"),
+		Name:        "安眠小夜灯",
+		Description: "什么？你七天没有午夜觅食？是失恋了吗？还是冰箱被你吃空了？...咳咳，其实这样挺好的啦！你的胃应该感谢我，要不是我做的这个小夜灯提醒你，你肯定半夜还在跟零食约会！谢谢不用说，哼～",
+		Source:      "成就",
+		Rarity:      models.RarityEpic,
+		Category:    models.CategoryCollectible,
+		IconURL:     "/static/items/achievements/sleepy-night-light-icon.png",
+		ImageURL:    "/static/items/achievements/sleepy-night-light-image.png",
+	},
+	{
+		Key:         models.ItemKey("food-explorer-backpack"),
+		Name:        "美食探险背包",
+		Description: "什么什么什么？你居然尝试了20种新食物？我正想问你是不是饿昏头了...不过，这种冒险精神，还挺...特别的！这个探险背包给你，下次可以装更多奇怪食物，说不定能发现什么不得了的黑暗料理呢～",
+		Source:      "成就",
+		Rarity:      models.RarityEpic,
+		Category:    models.CategoryCollectible,
+		IconURL:     "/static/items/achievements/food-explorer-backpack-icon.png",
+		ImageURL:    "/static/items/achievements/food-explorer-backpack-image.png",
+	},
+}
@@ -0,0 +1,78 @@
+package seed
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultItemAssetsDir 设计稿中的物品图标和图片目录，图标位于 icon/<物品名>.png，图片位于 pic/<物品名>.png
+// 可通过 ITEM_ASSETS_DIR 配置，路径相对于后端的运行目录
+const defaultItemAssetsDir = "../doc/item"
+
+// CopyItemAssets 将成就物品的图标和图片从设计稿目录复制到 static 下对应的位置，已存在的文件不会覆盖
+// 图片不再随后端代码提交，部署时需要保证设计稿目录可访问，或预先放好 static/items/achievements
+// 某个文件缺失或复制失败时逐个打印并继续复制其余文件，最后返回失败的文件数
+func CopyItemAssets() error {
+	dir := os.Getenv("ITEM_ASSETS_DIR")
+	if dir == "" {
+		dir = defaultItemAssetsDir
+	}
+
+	failed := 0
+	for _, item := range achievementItemsV1 {
+		for _, asset := range []struct {
+			kind string
+			src  string
+			url  string
+		}{
+			{"图标", filepath.Join(dir, "icon", item.Name+".png"), item.IconURL},
+			{"图片", filepath.Join(dir, "pic", item.Name+".png"), item.ImageURL},
+		} {
+			if err := copyAsset(asset.src, asset.url); err != nil {
+				fmt.Printf("复制物品 %s 的%s失败，%s 将无法访问: %v\n", item.Name, asset.kind, asset.url, err)
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 个物品图标或图片复制失败，请检查 ITEM_ASSETS_DIR（当前为 %s）", failed, dir)
+	}
+	return nil
+}
+
+// copyAsset 将 src 复制到 URL 对应的本地文件，目标已存在时跳过
+func copyAsset(src, url string) error {
+	dst := filepath.Clean(strings.TrimPrefix(url, "/"))
+	if _, err := os.Stat(dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	// 先写入临时文件再重命名，避免中断后留下不完整的图片
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
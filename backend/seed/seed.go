@@ -0,0 +1,48 @@
+// Package seed 在启动时执行带版本号的数据初始化，每个版本只执行一次
+package seed
+
+import (
+	"backend/models"
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// Migration 一个版本的数据初始化，Apply 在事务中执行，需要能在已有数据上安全重复执行
+type Migration struct {
+	Version int
+	Name    string
+	Apply   func(tx *gorm.DB) error
+}
+
+// migrations 按版本号递增排列，已发布的版本不能修改或删除
+var migrations = []Migration{
+	{Version: 1, Name: "achievement items", Apply: ensureItems(achievementItemsV1)},
+}
+
+// Run 按顺序执行尚未执行过的数据初始化版本
+func Run() error {
+	for _, m := range migrations {
+		applied, err := models.ApplySeed(m.Version, m.Name, m.Apply)
+		if err != nil {
+			return fmt.Errorf("执行数据初始化版本 %d（%s）失败: %v", m.Version, m.Name, err)
+		}
+		if applied {
+			log.Printf("已执行数据初始化版本 %d：%s", m.Version, m.Name)
+		}
+	}
+	return nil
+}
+
+// ensureItems 按标识创建物品，已存在的物品只补全缺失的信息
+func ensureItems(items []models.Item) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, item := range items {
+			if err := models.EnsureItemTx(tx, item); err != nil {
+				return fmt.Errorf("创建物品 %s 失败: %v", item.Name, err)
+			}
+		}
+		return nil
+	}
+}
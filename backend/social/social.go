@@ -0,0 +1,187 @@
+// Package social 处理用户之间的关注、好友请求和拉黑，包括关系校验和通知
+package social
+
+import (
+	"backend/models"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// MaxRequestMessageLength 好友请求附言的最大长度
+const MaxRequestMessageLength = 100
+
+var (
+	ErrSelf            = errors.New("不能对自己进行该操作")
+	ErrUserNotFound    = errors.New("用户不存在")
+	ErrBlocked         = errors.New("你与对方存在拉黑关系，无法进行该操作")
+	ErrAlreadyFriends  = errors.New("你们已经是好友了")
+	ErrRequestNotFound = errors.New("好友请求不存在")
+	ErrMessageTooLong  = fmt.Errorf("附言不能超过%d个字符", MaxRequestMessageLength)
+)
+
+// Follow 关注用户，首次关注时通知对方
+func Follow(followerID, followeeID uint) error {
+	if err := checkTarget(followerID, followeeID); err != nil {
+		return err
+	}
+	created, err := models.CreateFollow(followerID, followeeID)
+	if err != nil {
+		return err
+	}
+	if created {
+		notify(followeeID, followerID, models.NotificationTypeNewFollower, "关注了你", "")
+	}
+	return nil
+}
+
+// Unfollow 取消关注
+func Unfollow(followerID, followeeID uint) error {
+	return models.DeleteFollow(followerID, followeeID)
+}
+
+// SendFriendRequest 向对方发出好友请求，返回是否已成为好友
+// 对方已向自己发出请求时直接成为好友
+func SendFriendRequest(senderID, receiverID uint, message string) (bool, error) {
+	message = strings.TrimSpace(message)
+	if utf8.RuneCountInString(message) > MaxRequestMessageLength {
+		return false, ErrMessageTooLong
+	}
+	if err := checkTarget(senderID, receiverID); err != nil {
+		return false, err
+	}
+
+	relation, err := models.GetRelation(senderID, receiverID)
+	if err != nil {
+		return false, err
+	}
+	if relation.Friends {
+		return false, ErrAlreadyFriends
+	}
+	if relation.RequestReceived {
+		if err := Accept(senderID, receiverID); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	if relation.RequestSent {
+		return false, nil
+	}
+
+	request := &models.FriendRequest{SenderID: senderID, ReceiverID: receiverID, Message: message}
+	if err := models.CreateFriendRequest(request); err != nil {
+		return false, err
+	}
+	notify(receiverID, senderID, models.NotificationTypeFriendRequest, "请求添加你为好友", message)
+	return false, nil
+}
+
+// Accept 接受 senderID 发给 receiverID 的好友请求，并通知发出人
+func Accept(receiverID, senderID uint) error {
+	err := models.AcceptFriendRequest(senderID, receiverID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrRequestNotFound
+	}
+	if err != nil {
+		return err
+	}
+	notify(senderID, receiverID, models.NotificationTypeFriendAccepted, "接受了你的好友请求", "")
+	return nil
+}
+
+// Decline 拒绝 senderID 发给 receiverID 的好友请求，不通知发出人
+func Decline(receiverID, senderID uint) error {
+	return deleteRequest(senderID, receiverID)
+}
+
+// Cancel 撤回发出的好友请求
+func Cancel(senderID, receiverID uint) error {
+	return deleteRequest(senderID, receiverID)
+}
+
+// RemoveFriend 解除好友关系，双方的关注关系保持不变
+func RemoveFriend(userID, friendID uint) error {
+	return models.DeleteFriendship(userID, friendID)
+}
+
+// Block 拉黑用户，同时解除双方的关注、好友关系和好友请求
+func Block(blockerID, blockedID uint) error {
+	if blockerID == blockedID {
+		return ErrSelf
+	}
+	if err := checkUserExists(blockedID); err != nil {
+		return err
+	}
+	return models.CreateBlock(blockerID, blockedID)
+}
+
+// Unblock 取消拉黑，之前解除的关系不会恢复
+func Unblock(blockerID, blockedID uint) error {
+	return models.DeleteBlock(blockerID, blockedID)
+}
+
+func deleteRequest(senderID, receiverID uint) error {
+	found, err := models.DeleteFriendRequest(senderID, receiverID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrRequestNotFound
+	}
+	return nil
+}
+
+// checkTarget 校验对方存在且双方之间没有拉黑关系
+func checkTarget(userID, targetID uint) error {
+	if userID == targetID {
+		return ErrSelf
+	}
+	if err := checkUserExists(targetID); err != nil {
+		return err
+	}
+	blocked, err := models.IsBlockedEither(userID, targetID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrBlocked
+	}
+	return nil
+}
+
+func checkUserExists(userID uint) error {
+	var user models.User
+	if err := models.DB.Select("id").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// notify 通知 userID 来自 actorID 的社交动态，标题以对方昵称开头，失败只记录日志
+func notify(userID, actorID uint, notificationType, action, content string) {
+	name := "有人"
+	if summaries, err := models.GetUserSummaries([]uint{actorID}); err == nil {
+		if summary, ok := summaries[actorID]; ok && summary.Name != "" {
+			name = summary.Name + " "
+		}
+	}
+
+	notification := models.Notification{
+		UserID:  userID,
+		Type:    notificationType,
+		Title:   name + action,
+		Content: content,
+		Data: map[string]interface{}{
+			"user_id": actorID,
+		},
+	}
+	if err := models.CreateNotifications([]models.Notification{notification}); err != nil {
+		fmt.Printf("保存用户 %d 的社交通知失败: %v\n", userID, err)
+	}
+}
@@ -0,0 +1,274 @@
+// Package streak 计算用户的连续打卡天数，并处理冻结卡和补签卡
+package streak
+
+import (
+	"backend/calendar"
+	"backend/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 冻结卡和补签卡对应的物品标识和默认名称
+const (
+	FreezeItemKey  = "streak-freeze"
+	MakeUpItemKey  = "streak-make-up"
+	FreezeItemName = "打卡冻结卡"
+	MakeUpItemName = "补签卡"
+)
+
+const (
+	// MaxFreezesPerMonth 每个自然月最多使用的冻结卡数量
+	MaxFreezesPerMonth = 2
+	// FreezeLookbackDays 冻结卡最多可以冻结多少天之前的日期
+	FreezeLookbackDays = 7
+)
+
+var (
+	ErrInvalidFreezeDate = errors.New("只能冻结最近7天内未打卡的日期")
+	ErrFreezeLimit       = fmt.Errorf("每月最多使用%d张冻结卡", MaxFreezesPerMonth)
+	ErrItemUnavailable   = errors.New("物品未配置")
+)
+
+// defaultItems 冻结卡和补签卡的默认物品信息
+var defaultItems = []models.Item{
+	{
+		Key:         models.ItemKey(FreezeItemKey),
+		Name:        FreezeItemName,
+		Description: "冻结一个未打卡的日期，连续打卡不会因此中断。每月最多使用2张。",
+		Source:      "系统",
+		Rarity:      models.RarityUncommon,
+		Category:    models.CategoryConsumable,
+		Effect:      models.EffectStreakFreeze,
+	},
+	{
+		Key:         models.ItemKey(MakeUpItemKey),
+		Name:        MakeUpItemName,
+		Description: "为昨天补签一次打卡。",
+		Source:      "系统",
+		Rarity:      models.RarityRare,
+		Category:    models.CategoryConsumable,
+	},
+}
+
+// Summary 用户的连续打卡状态
+type Summary struct {
+	Today                string   `json:"today"`                   // 用户时区的今天
+	Current              int      `json:"current"`                 // 当前连续打卡天数
+	Longest              int      `json:"longest"`                 // 最长连续打卡天数
+	CheckedInToday       bool     `json:"checked_in_today"`        // 今天是否已打卡
+	LastCheckInDate      string   `json:"last_check_in_date"`      // 最近一次打卡日期
+	FrozenDays           []string `json:"frozen_days"`             // 已冻结的日期
+	FreezesAvailable     int      `json:"freezes_available"`       // 持有的冻结卡数量
+	FreezesUsedThisMonth int      `json:"freezes_used_this_month"` // 本月已使用的冻结卡数量
+	FreezeMonthlyLimit   int      `json:"freeze_monthly_limit"`    // 每月冻结卡上限
+	MakeUpAvailable      int      `json:"make_up_available"`       // 持有的补签卡数量
+	CanMakeUp            bool     `json:"can_make_up"`             // 昨天是否可以补签
+}
+
+// EnsureItems 确保冻结卡和补签卡物品存在
+func EnsureItems() error {
+	for _, item := range defaultItems {
+		if err := models.EnsureItem(item); err != nil {
+			return fmt.Errorf("创建物品 %s 失败: %v", item.Name, err)
+		}
+	}
+	return nil
+}
+
+// GetSummary 计算用户截至 now 的连续打卡状态
+func GetSummary(userID uint, now time.Time) (*Summary, error) {
+	loc := models.GetUserLocation(userID)
+	today := calendar.DateKey(now, loc)
+
+	checkIns, err := models.GetUserCheckIns(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取打卡记录失败: %v", err)
+	}
+	freezes, err := models.GetUserStreakFreezes(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取冻结记录失败: %v", err)
+	}
+
+	times := make([]time.Time, len(checkIns))
+	for i, checkIn := range checkIns {
+		times[i] = checkIn.CheckInAt
+	}
+	checkInDays := calendar.UniqueDays(times, loc)
+
+	summary := &Summary{
+		Today:              today,
+		FrozenDays:         make([]string, 0, len(freezes)),
+		FreezeMonthlyLimit: MaxFreezesPerMonth,
+	}
+	if len(checkInDays) > 0 {
+		summary.LastCheckInDate = checkInDays[len(checkInDays)-1]
+		summary.CheckedInToday = summary.LastCheckInDate == today
+	}
+
+	// 本月使用次数按使用时间统计，与 models.CreateStreakFreeze 检查上限的方式一致
+	monthStart, _ := calendar.MonthRange(now.In(loc).Year(), now.In(loc).Month(), loc)
+	for _, f := range freezes {
+		summary.FrozenDays = append(summary.FrozenDays, f.Date)
+		if !f.CreatedAt.Before(monthStart) {
+			summary.FreezesUsedThisMonth++
+		}
+	}
+
+	// 冻结的日期视为已打卡，参与连续天数计算
+	days := mergeDays(checkInDays, summary.FrozenDays)
+	summary.Current = calendar.CurrentStreak(days, today)
+	summary.Longest = calendar.LongestStreak(days)
+
+	if summary.FreezesAvailable, err = itemQuantity(userID, FreezeItemKey); err != nil {
+		return nil, err
+	}
+	if summary.MakeUpAvailable, err = itemQuantity(userID, MakeUpItemKey); err != nil {
+		return nil, err
+	}
+
+	yesterday := calendar.AddDays(today, -1)
+	summary.CanMakeUp = summary.MakeUpAvailable > 0 && !containsDay(days, yesterday)
+
+	return summary, nil
+}
+
+// Freeze 消耗一张冻结卡冻结指定日期，date 为空时冻结昨天
+func Freeze(userID uint, date string, now time.Time) (*models.StreakFreeze, error) {
+	item, err := streakItem(FreezeItemKey)
+	if err != nil {
+		return nil, err
+	}
+
+	freeze, dayStart, dayEnd, limit, err := prepareFreeze(userID, item.ID, date, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.UseStreakFreeze(freeze, dayStart, dayEnd, limit); err != nil {
+		return nil, freezeError(err)
+	}
+	return freeze, nil
+}
+
+// FreezeWithTx 在调用方的事务中冻结指定日期，由调用方负责扣减物品（用于物品使用效果）
+func FreezeWithTx(tx *gorm.DB, userID, itemID uint, date string, now time.Time) (*models.StreakFreeze, error) {
+	freeze, dayStart, dayEnd, limit, err := prepareFreeze(userID, itemID, date, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.CreateStreakFreeze(tx, freeze, dayStart, dayEnd, limit); err != nil {
+		return nil, freezeError(err)
+	}
+	return freeze, nil
+}
+
+// prepareFreeze 校验冻结日期，返回待创建的冻结记录、该日期的时间范围和本月的使用上限
+// 使用次数在创建冻结记录的事务中锁定用户后检查
+func prepareFreeze(userID, itemID uint, date string, now time.Time) (*models.StreakFreeze, time.Time, time.Time, models.StreakFreezeLimit, error) {
+	loc := models.GetUserLocation(userID)
+	today := calendar.DateKey(now, loc)
+	if date == "" {
+		date = calendar.AddDays(today, -1)
+	}
+
+	day, err := calendar.ParseDate(date, loc)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, models.StreakFreezeLimit{}, ErrInvalidFreezeDate
+	}
+	if diff := calendar.DaysBetween(date, today); diff < 1 || diff > FreezeLookbackDays {
+		return nil, time.Time{}, time.Time{}, models.StreakFreezeLimit{}, ErrInvalidFreezeDate
+	}
+
+	monthStart, _ := calendar.MonthRange(now.In(loc).Year(), now.In(loc).Month(), loc)
+	limit := models.StreakFreezeLimit{Since: monthStart, Max: MaxFreezesPerMonth}
+
+	dayStart, dayEnd := calendar.DayRange(day, loc)
+	freeze := &models.StreakFreeze{UserID: userID, Date: date, ItemID: itemID}
+	return freeze, dayStart, dayEnd, limit, nil
+}
+
+// freezeError 将模型层的上限错误转换为带具体次数的 ErrFreezeLimit
+func freezeError(err error) error {
+	if errors.Is(err, models.ErrStreakFreezeLimit) {
+		return ErrFreezeLimit
+	}
+	return err
+}
+
+// MakeUp 消耗一张补签卡为昨天补签
+// moderationStatus 为补签内容的审核状态
+func MakeUp(userID uint, content, moderationStatus string, now time.Time) (*models.CheckIn, error) {
+	loc := models.GetUserLocation(userID)
+	yesterday := calendar.AddDays(calendar.DateKey(now, loc), -1)
+	day, _ := calendar.ParseDate(yesterday, loc)
+	dayStart, dayEnd := calendar.DayRange(day, loc)
+
+	item, err := streakItem(MakeUpItemKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// 补签时间记为昨天的最后一秒
+	checkIn := &models.CheckIn{
+		UserID:    userID,
+		CheckInAt: dayEnd.Add(-time.Second).In(loc),
+		Content:   content,
+
+		ModerationStatus: moderationStatus,
+	}
+	if err := models.CreateMakeUpCheckIn(checkIn, item.ID, yesterday, dayStart, dayEnd); err != nil {
+		return nil, err
+	}
+	return checkIn, nil
+}
+
+func streakItem(key string) (*models.Item, error) {
+	item, err := models.GetItemByKey(key)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrItemUnavailable
+	}
+	return item, err
+}
+
+func itemQuantity(userID uint, key string) (int, error) {
+	item, err := streakItem(key)
+	if errors.Is(err, ErrItemUnavailable) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return models.GetUserItemQuantity(userID, item.ID)
+}
+
+// mergeDays 合并两个升序日期列表并去重
+func mergeDays(a, b []string) []string {
+	merged := make([]string, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		var next string
+		switch {
+		case j >= len(b) || (i < len(a) && a[i] <= b[j]):
+			next = a[i]
+			i++
+		default:
+			next = b[j]
+			j++
+		}
+		if len(merged) == 0 || merged[len(merged)-1] != next {
+			merged = append(merged, next)
+		}
+	}
+	return merged
+}
+
+func containsDay(days []string, day string) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,289 @@
+// Package trade 处理用户之间的物品赠送和交易，包括数量校验、防刷限制和通知
+package trade
+
+import (
+	"backend/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// OfferTTL 交易请求的有效期
+	OfferTTL = 7 * 24 * time.Hour
+	// MinAccountAge 注册满多长时间后才能发起交易
+	MinAccountAge = 24 * time.Hour
+	// MaxPendingOutgoing 每个用户同时等待处理的发出交易上限
+	MaxPendingOutgoing = 10
+	// MaxPendingIncoming 每个用户同时等待处理的收到交易上限
+	MaxPendingIncoming = 50
+	// MaxOffersPerDay 每个用户24小时内最多发起的交易数
+	MaxOffersPerDay = 20
+	// MaxLines 单个交易最多包含的物品种类
+	MaxLines = 10
+	// MaxLineQuantity 单种物品单次最多交易的数量
+	MaxLineQuantity = 99
+)
+
+var (
+	ErrSelfTrade        = errors.New("不能和自己交易")
+	ErrReceiverNotFound = errors.New("接收人不存在")
+	ErrEmptyOffer       = errors.New("请选择要赠送或交易的物品")
+	ErrTooManyLines     = fmt.Errorf("单次交易最多包含%d种物品", MaxLines)
+	ErrInvalidQuantity  = fmt.Errorf("物品数量应在1到%d之间", MaxLineQuantity)
+	ErrNotVerified      = errors.New("邮箱验证后才能赠送或交易物品")
+	ErrAccountTooNew    = errors.New("注册满24小时后才能赠送或交易物品")
+	ErrPendingLimit     = fmt.Errorf("最多同时发起%d个待处理的交易", MaxPendingOutgoing)
+	ErrReceiverBusy     = errors.New("对方待处理的交易过多，请稍后再试")
+	ErrDailyLimit       = fmt.Errorf("24小时内最多发起%d个交易", MaxOffersPerDay)
+	ErrBlocked          = errors.New("你与对方存在拉黑关系，无法交易")
+)
+
+// Line 交易中的一种物品
+type Line struct {
+	ItemID   uint `json:"item_id"`
+	Quantity int  `json:"quantity"`
+}
+
+// OfferRequest 发起交易请求，Requested 为空时即为赠送
+type OfferRequest struct {
+	SenderID   uint
+	ReceiverID uint
+	Offered    []Line
+	Requested  []Line
+	Message    string
+}
+
+// CreateOffer 校验并发起赠送或交易请求，通知接收人
+// 发起时只检查双方当前的持有数量，物品在对方接受时才转移
+func CreateOffer(req OfferRequest, now time.Time) (*models.TradeOffer, error) {
+	if req.SenderID == req.ReceiverID {
+		return nil, ErrSelfTrade
+	}
+	if err := checkSender(req.SenderID, now); err != nil {
+		return nil, err
+	}
+
+	var receiver models.User
+	if err := models.DB.First(&receiver, req.ReceiverID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReceiverNotFound
+		}
+		return nil, err
+	}
+	blocked, err := models.IsBlockedEither(req.SenderID, req.ReceiverID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, ErrBlocked
+	}
+
+	offered, err := mergeLines(req.Offered)
+	if err != nil {
+		return nil, err
+	}
+	requested, err := mergeLines(req.Requested)
+	if err != nil {
+		return nil, err
+	}
+	if len(offered) == 0 {
+		return nil, ErrEmptyOffer
+	}
+	if len(offered)+len(requested) > MaxLines {
+		return nil, ErrTooManyLines
+	}
+
+	if _, err := models.ExpireTradeOffers(now); err != nil {
+		return nil, err
+	}
+	if err := checkLimits(req.SenderID, req.ReceiverID, now); err != nil {
+		return nil, err
+	}
+
+	offer := &models.TradeOffer{
+		SenderID:   req.SenderID,
+		ReceiverID: req.ReceiverID,
+		Status:     models.TradeStatusPending,
+		Message:    req.Message,
+		ExpiresAt:  now.Add(OfferTTL),
+	}
+	sides := []struct {
+		side  string
+		owner uint
+		lines []Line
+	}{
+		{models.TradeSideOffer, req.SenderID, offered},
+		{models.TradeSideRequest, req.ReceiverID, requested},
+	}
+	for _, s := range sides {
+		for _, line := range s.lines {
+			if err := checkLine(s.owner, line); err != nil {
+				return nil, err
+			}
+			offer.Items = append(offer.Items, models.TradeOfferItem{ItemID: line.ItemID, Quantity: line.Quantity, Side: s.side})
+		}
+	}
+
+	if err := models.CreateTradeOffer(offer); err != nil {
+		return nil, err
+	}
+
+	offer, err = models.GetTradeOffer(offer.ID)
+	if err != nil {
+		return nil, err
+	}
+	notify(offer.ReceiverID, offerNotification(offer))
+	return offer, nil
+}
+
+// Accept 接收人接受交易，物品在同一事务中转移
+func Accept(offerID, userID uint, now time.Time) (*models.TradeOffer, error) {
+	offer, err := models.RespondTradeOffer(offerID, userID, true, now)
+	if err != nil {
+		return nil, err
+	}
+	notify(offer.SenderID, resultNotification(offer, "接受"))
+	return offer, nil
+}
+
+// Decline 接收人拒绝交易
+func Decline(offerID, userID uint, now time.Time) (*models.TradeOffer, error) {
+	offer, err := models.RespondTradeOffer(offerID, userID, false, now)
+	if err != nil {
+		return nil, err
+	}
+	notify(offer.SenderID, resultNotification(offer, "拒绝"))
+	return offer, nil
+}
+
+// Cancel 发起人取消交易
+func Cancel(offerID, userID uint, now time.Time) (*models.TradeOffer, error) {
+	return models.CancelTradeOffer(offerID, userID, now)
+}
+
+// checkSender 检查发起人是否满足交易条件
+func checkSender(senderID uint, now time.Time) error {
+	var sender models.User
+	if err := models.DB.First(&sender, senderID).Error; err != nil {
+		return err
+	}
+	if !sender.Verified {
+		return ErrNotVerified
+	}
+	if now.Sub(sender.CreatedAt) < MinAccountAge {
+		return ErrAccountTooNew
+	}
+	return nil
+}
+
+// checkLimits 检查发起人和接收人的待处理交易数以及发起人的每日交易数
+func checkLimits(senderID, receiverID uint, now time.Time) error {
+	pending, err := models.CountPendingTradeOffers(senderID, false)
+	if err != nil {
+		return err
+	}
+	if pending >= MaxPendingOutgoing {
+		return ErrPendingLimit
+	}
+
+	incoming, err := models.CountPendingTradeOffers(receiverID, true)
+	if err != nil {
+		return err
+	}
+	if incoming >= MaxPendingIncoming {
+		return ErrReceiverBusy
+	}
+
+	recent, err := models.CountTradeOffersSince(senderID, now.Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+	if recent >= MaxOffersPerDay {
+		return ErrDailyLimit
+	}
+	return nil
+}
+
+// checkLine 检查物品是否可交易以及持有人当前的数量是否足够
+func checkLine(ownerID uint, line Line) error {
+	item, err := models.GetItemByID(line.ItemID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w：物品 %d 不存在", models.ErrItemNotTradable, line.ItemID)
+	}
+	if err != nil {
+		return err
+	}
+	if !item.Tradable {
+		return fmt.Errorf("%w：%s", models.ErrItemNotTradable, item.Name)
+	}
+
+	quantity, err := models.GetUserItemQuantity(ownerID, line.ItemID)
+	if err != nil {
+		return err
+	}
+	if quantity < line.Quantity {
+		return fmt.Errorf("%w：%s", models.ErrInsufficientItems, item.Name)
+	}
+	return nil
+}
+
+// mergeLines 合并同一物品的多行并校验数量
+func mergeLines(lines []Line) ([]Line, error) {
+	var merged []Line
+	index := make(map[uint]int)
+	for _, line := range lines {
+		if line.Quantity < 1 || line.Quantity > MaxLineQuantity {
+			return nil, ErrInvalidQuantity
+		}
+		if i, ok := index[line.ItemID]; ok {
+			merged[i].Quantity += line.Quantity
+			if merged[i].Quantity > MaxLineQuantity {
+				return nil, ErrInvalidQuantity
+			}
+			continue
+		}
+		index[line.ItemID] = len(merged)
+		merged = append(merged, line)
+	}
+	return merged, nil
+}
+
+func notify(userID uint, notification models.Notification) {
+	notification.UserID = userID
+	if err := models.CreateNotifications([]models.Notification{notification}); err != nil {
+		fmt.Printf("保存用户 %d 的交易通知失败: %v\n", userID, err)
+	}
+}
+
+// offerNotification 收到交易请求的通知
+func offerNotification(offer *models.TradeOffer) models.Notification {
+	title := "收到一份交易请求"
+	if offer.IsGift() {
+		title = "收到一份礼物"
+	}
+	return models.Notification{
+		Type:    models.NotificationTypeTradeOffer,
+		Title:   title,
+		Content: offer.Message,
+		Data: map[string]interface{}{
+			"offer_id":  offer.ID,
+			"sender_id": offer.SenderID,
+		},
+	}
+}
+
+// resultNotification 交易被处理的通知
+func resultNotification(offer *models.TradeOffer, action string) models.Notification {
+	return models.Notification{
+		Type:  models.NotificationTypeTradeResult,
+		Title: fmt.Sprintf("对方%s了你的交易请求", action),
+		Data: map[string]interface{}{
+			"offer_id":    offer.ID,
+			"receiver_id": offer.ReceiverID,
+			"status":      offer.Status,
+		},
+	}
+}
@@ -0,0 +1,165 @@
+package trade
+
+import (
+	"backend/models"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var (
+	testDBOnce  sync.Once
+	testUserSeq int64
+)
+
+// setupTestDB 连接 TEST_DB_NAME 指定的 MySQL 测试库（连接参数与 DB_HOST 等配置相同），未设置时跳过测试
+// 测试会写入用户、物品和交易，不要指向正式数据库
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	name := os.Getenv("TEST_DB_NAME")
+	if name == "" {
+		t.Skip("未设置 TEST_DB_NAME，跳过需要数据库的测试")
+	}
+	testDBOnce.Do(func() {
+		os.Setenv("DB_NAME", name)
+		models.InitDB()
+	})
+}
+
+func createTestUser(t *testing.T) *models.User {
+	t.Helper()
+	email := fmt.Sprintf("trade_%d_%d@example.com", time.Now().UnixNano(), atomic.AddInt64(&testUserSeq, 1))
+	user := &models.User{Name: "测试用户", Email: email, Role: models.RoleUser, Verified: true}
+	if err := models.DB.Create(user).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	return user
+}
+
+func createTestItem(t *testing.T, tradable bool) *models.Item {
+	t.Helper()
+	item := &models.Item{Name: "测试物品", Source: "test", IconURL: "/icon.png", ImageURL: "/image.png", Tradable: tradable}
+	if err := models.CreateItem(item); err != nil {
+		t.Fatalf("创建物品失败: %v", err)
+	}
+	return item
+}
+
+func giveTestItem(t *testing.T, userID, itemID uint, quantity int) {
+	t.Helper()
+	if _, _, err := models.GrantItem(models.GrantRequest{UserID: userID, ItemID: itemID, Quantity: quantity, Source: "test"}); err != nil {
+		t.Fatalf("发放物品失败: %v", err)
+	}
+}
+
+func TestMergeLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []Line
+		want    []Line
+		wantErr error
+	}{
+		{"空", nil, nil, nil},
+		{"合并同一物品", []Line{{1, 2}, {2, 1}, {1, 3}}, []Line{{1, 5}, {2, 1}}, nil},
+		{"数量为0", []Line{{1, 0}}, nil, ErrInvalidQuantity},
+		{"数量为负数", []Line{{1, -1}}, nil, ErrInvalidQuantity},
+		{"单行超过上限", []Line{{1, MaxLineQuantity + 1}}, nil, ErrInvalidQuantity},
+		{"合并后超过上限", []Line{{1, MaxLineQuantity}, {1, 1}}, nil, ErrInvalidQuantity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeLines(tt.lines)
+			if err != tt.wantErr {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeLines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateOfferChecksLines(t *testing.T) {
+	setupTestDB(t)
+	// 新注册的账号需要满 MinAccountAge 才能发起交易
+	now := time.Now().Add(2 * MinAccountAge)
+
+	sender, receiver := createTestUser(t), createTestUser(t)
+	tradable := createTestItem(t, true)
+	untradable := createTestItem(t, false)
+	deleted := createTestItem(t, true)
+	if err := models.DeleteItem(deleted.ID); err != nil {
+		t.Fatalf("删除物品失败: %v", err)
+	}
+	giveTestItem(t, sender.ID, tradable.ID, 2)
+	giveTestItem(t, sender.ID, untradable.ID, 1)
+	giveTestItem(t, receiver.ID, tradable.ID, 1)
+
+	tests := []struct {
+		name      string
+		offered   []Line
+		requested []Line
+		wantErr   error
+	}{
+		{"赠送数量超过持有数量", []Line{{tradable.ID, 3}}, nil, models.ErrInsufficientItems},
+		{"索要数量超过对方持有数量", []Line{{tradable.ID, 1}}, []Line{{tradable.ID, 2}}, models.ErrInsufficientItems},
+		{"赠送不可交易的物品", []Line{{untradable.ID, 1}}, nil, models.ErrItemNotTradable},
+		{"索要已删除的物品", []Line{{tradable.ID, 1}}, []Line{{deleted.ID, 1}}, models.ErrItemNotTradable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CreateOffer(OfferRequest{SenderID: sender.ID, ReceiverID: receiver.ID, Offered: tt.offered, Requested: tt.requested}, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	pending, err := models.CountPendingTradeOffers(sender.ID, false)
+	if err != nil {
+		t.Fatalf("查询待处理交易失败: %v", err)
+	}
+	if pending != 0 {
+		t.Errorf("校验失败的交易不应保存，待处理交易数 = %d", pending)
+	}
+}
+
+func TestAcceptTwice(t *testing.T) {
+	setupTestDB(t)
+	now := time.Now().Add(2 * MinAccountAge)
+
+	sender, receiver := createTestUser(t), createTestUser(t)
+	item := createTestItem(t, true)
+	giveTestItem(t, sender.ID, item.ID, 2)
+
+	offer, err := CreateOffer(OfferRequest{SenderID: sender.ID, ReceiverID: receiver.ID, Offered: []Line{{item.ID, 2}}}, now)
+	if err != nil {
+		t.Fatalf("发起赠送失败: %v", err)
+	}
+	if _, err := Accept(offer.ID, receiver.ID, now); err != nil {
+		t.Fatalf("第一次接受失败: %v", err)
+	}
+	if _, err := Accept(offer.ID, receiver.ID, now); !errors.Is(err, models.ErrTradeNotPending) {
+		t.Errorf("第二次接受 err = %v, want %v", err, models.ErrTradeNotPending)
+	}
+
+	for _, c := range []struct {
+		userID uint
+		want   int
+	}{{sender.ID, 0}, {receiver.ID, 2}} {
+		got, err := models.GetUserItemQuantity(c.userID, item.ID)
+		if err != nil {
+			t.Fatalf("查询物品数量失败: %v", err)
+		}
+		if got != c.want {
+			t.Errorf("用户 %d 的物品数量 = %d, want %d", c.userID, got, c.want)
+		}
+	}
+}